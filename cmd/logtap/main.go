@@ -6,23 +6,44 @@ import (
 	"os"
 
 	"github.com/lichuan0620/logtap/cmd/logtap/option"
+	"github.com/lichuan0620/logtap/cmd/logtap/receive"
+	"github.com/lichuan0620/logtap/cmd/logtap/verify"
 	"github.com/lichuan0620/logtap/pkg/logtap"
 	"github.com/lichuan0620/logtap/pkg/logtap/handler"
 )
 
 func main() {
-	logTap, err := logtap.NewLogTap(option.Spec, option.Name)
-	if err != nil {
-		os.Exit(1)
+	if len(os.Args) > 1 && os.Args[1] == verify.Command {
+		os.Exit(verify.Run(os.Args[2:]))
 	}
-	go serveHTTP(logTap)
-	if err = logTap.Run(option.StopCh); err != nil {
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == receive.Command {
+		os.Exit(receive.Run(os.Args[2:]))
+	}
+	option.Parse()
+	manager := logtap.NewManager()
+	if len(option.Tasks) == 0 {
+		if _, err := manager.Create(option.Name, option.Spec); err != nil {
+			log.Fatalln(err.Error())
+		}
+	}
+	for _, task := range option.Tasks {
+		if _, err := manager.Create(task.Name, task.Spec); err != nil {
+			log.Fatalln(err.Error())
+		}
+	}
+	go serveHTTP(manager)
+	manager.Run(option.StopCh)
+	report := manager.Report()
+	printReport(os.Stderr, report)
+	if len(option.ReportPath) > 0 {
+		if err := writeReport(option.ReportPath, report); err != nil {
+			log.Fatalln(err.Error())
+		}
 	}
 }
 
-func serveHTTP(tap logtap.LogTap) {
-	if err := http.ListenAndServe(option.WebAddress, handler.NewLogTapHandler(tap)); err != nil {
+func serveHTTP(manager logtap.Manager) {
+	if err := http.ListenAndServe(option.WebAddress, handler.NewHandler(manager)); err != nil {
 		log.Fatalln(err.Error())
 	}
 }
@@ -2,6 +2,7 @@ package option
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	flag "github.com/spf13/pflag"
 
 	"github.com/lichuan0620/logtap/cmd/logtap/version"
+	"github.com/lichuan0620/logtap/pkg/config"
 	"github.com/lichuan0620/logtap/pkg/fieldpath"
 	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
 	"github.com/lichuan0620/logtap/pkg/signal"
@@ -20,6 +22,8 @@ const (
 	defaultMinSize    = 128
 	defaultInterval   = 0.5
 	defaultWebAddress = ":8080"
+	defaultMaxBackups = 5
+	defaultMaxRetries = 3
 
 	noDefault = ""
 )
@@ -34,6 +38,13 @@ var (
 	// Name is used to differentiate different deployments.
 	Name string
 
+	// ReportPath is the path to the file to which the run report is written in JSON upon exit, if specified.
+	ReportPath string
+
+	// Tasks are the LogTasks loaded from the configuration file unless a template is specified; they replace the
+	// LogTask defined by Spec and Name if not empty.
+	Tasks []model.LogTask
+
 	// StopCh closes when the program should be cleaned and terminated.
 	StopCh chan struct{}
 )
@@ -46,6 +57,11 @@ var (
 		"The name of the predefined template to run; override other options if specified",
 	)
 
+	configPath = commandLine.StringP(
+		"config", "c", getEnv("LOGTAP_CONFIG", noDefault),
+		"Path to a YAML or JSON file defining the tasks to run and the presets that --template can refer to",
+	)
+
 	duration = commandLine.String(
 		"duration", getEnv("LOGTAP_DURATION", noDefault),
 		"The duration, such as 1h or 30s, for which LogTap should run",
@@ -64,6 +80,107 @@ var (
 			"  %s\t\tThe log messages will be written to the specified file",
 			model.OutputKindFile,
 		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be sent to the syslog server given by --output.syslog.address",
+			model.OutputKindSyslog,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be sent to the TCP or UDP server given by --output.network.address",
+			model.OutputKindNetwork,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be sent in batches to the HTTP endpoint given by --output.http.url",
+			model.OutputKindHTTP,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be sent to the Fluentd Forward server given by --output.forward.address",
+			model.OutputKindForward,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be exported in batches to the OpenTelemetry receiver given by "+
+				"--output.otlp.endpoint",
+			model.OutputKindOTLP,
+		),
+	}
+
+	fileDistributionHelp = []string{
+		fmt.Sprintf(
+			"  %s\tThe log messages will be written to the files in turn",
+			model.FileDistributionRoundRobin,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be written to files picked according to --output.files.weights",
+			model.FileDistributionWeighted,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be written to files picked at random",
+			model.FileDistributionRandom,
+		),
+	}
+
+	forwardModeHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\tEvery log message is sent in its own event",
+			model.ForwardModeMessage,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages are sent in batches as arrays of entries",
+			model.ForwardModeForward,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages are sent in batches as binary streams of entries",
+			model.ForwardModePackedForward,
+		),
+	}
+
+	networkFramingHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\tEvery log message is terminated by a newline",
+			model.NetworkFramingNewline,
+		),
+		fmt.Sprintf(
+			"  %s\tEvery log message is prefixed by its length in decimal and a space",
+			model.NetworkFramingOctetCounting,
+		),
+		fmt.Sprintf(
+			"  %s\tEvery log message is prefixed by its length as a 4-byte big-endian integer",
+			model.NetworkFramingLengthPrefix,
+		),
+	}
+
+	syslogTransportHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\tEvery log message is sent in its own UDP datagram",
+			model.SyslogTransportUDP,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages are sent over TCP, each terminated by a newline",
+			model.SyslogTransportTCP,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages are sent over TCP, each prefixed by its length (RFC 6587)",
+			model.SyslogTransportTCPOctetCounting,
+		),
+		fmt.Sprintf(
+			"  %s\tEvery log message is sent in its own datagram to a Unix socket",
+			model.SyslogTransportUnixgram,
+		),
+	}
+
+	encodingHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be written as they are",
+			model.EncodingRaw,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be written as CRI log lines, as found under /var/log/pods",
+			model.EncodingCRI,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be written as Docker json-file records, as found under "+
+				"/var/lib/docker/containers",
+			model.EncodingDockerJSON,
+		),
 	}
 
 	contentTypeHelp = []string{
@@ -75,6 +192,85 @@ var (
 			"  %s\tThe log messages will be explicitly defined",
 			model.ContentTypeExplicit,
 		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be JSON objects with the fields given by --content.field",
+			model.ContentTypeJSON,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be rendered from --content.message as a Go text/template with "+
+				"fake data functions",
+			model.ContentTypeTemplate,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be explicitly defined, and some will be followed by stack traces "+
+				"spanning many lines",
+			model.ContentTypeMultiline,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be Apache access logs in the Common Log Format",
+			model.ContentTypeApacheCommon,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log messages will be Apache access logs in the Combined Log Format",
+			model.ContentTypeApacheCombined,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be Nginx access logs in the default combined format",
+			model.ContentTypeNginx,
+		),
+		fmt.Sprintf(
+			"  %s\t\tThe log messages will be Envoy access logs in the default format",
+			model.ContentTypeEnvoy,
+		),
+	}
+
+	fieldKindHelp = []string{
+		fmt.Sprintf(
+			"  %s\tNAME=%s:VALUE, the field always has the given value",
+			model.FieldKindConstant, model.FieldKindConstant,
+		),
+		fmt.Sprintf(
+			"  %s\t\tNAME=%s:A|B|C, the field has a value randomly picked from the list",
+			model.FieldKindPick, model.FieldKindPick,
+		),
+		fmt.Sprintf(
+			"  %s\tNAME=%s:SIZE, the field has a random string value of SIZE bytes",
+			model.FieldKindRandomString, model.FieldKindRandomString,
+		),
+		fmt.Sprintf(
+			"  %s\tNAME=%s:MIN:MAX, the field has a random integer value within the range",
+			model.FieldKindRandomInt, model.FieldKindRandomInt,
+		),
+	}
+
+	profileKindHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\t%s:FROM:TO:DURATION, the rate changes linearly from FROM to TO over DURATION seconds",
+			model.ProfileKindRamp, model.ProfileKindRamp,
+		),
+		fmt.Sprintf(
+			"  %s\t\t%s:RATE@DURATION,RATE@DURATION...[:Repeat], the rate changes through the stages",
+			model.ProfileKindStep, model.ProfileKindStep,
+		),
+		fmt.Sprintf(
+			"  %s\t\t%s:BASE:AMPLITUDE:PERIOD, the rate follows a sine wave",
+			model.ProfileKindSine, model.ProfileKindSine,
+		),
+		fmt.Sprintf(
+			"  %s\t\t%s:BASE:SIZE:PERIOD, SIZE logs are sent at once every PERIOD seconds on top of BASE",
+			model.ProfileKindBurst, model.ProfileKindBurst,
+		),
+	}
+
+	rotationStrategyHelp = []string{
+		fmt.Sprintf(
+			"  %s\t\tThe log file will be renamed to a backup and reopened",
+			model.RotationStrategyRename,
+		),
+		fmt.Sprintf(
+			"  %s\tThe log file will be copied to a backup and truncated in place",
+			model.RotationStrategyCopyTruncate,
+		),
 	}
 
 	presetHelp = []string{
@@ -100,13 +296,45 @@ var (
 Output Kinds:
 %s
 
+Output Encodings (only for the File, STDERR and STDOUT outputs):
+%s
+
 Content Types:
 %s
 
-Task Presets:
+JSON Field Kinds:
+%s
+
+Load Profiles (rates are in logs/s):
+%s
+
+File Distributions (for --output.files.count):
+%s
+
+Forward Modes:
+%s
+
+Network Framings:
+%s
+
+Rotation Strategies:
+%s
+
+Syslog Transports:
+%s
+
+Task Presets (more can be defined in the file given by --config):
 %s`,
 		strings.Join(outputKindHelp, "\n"),
+		strings.Join(encodingHelp, "\n"),
 		strings.Join(contentTypeHelp, "\n"),
+		strings.Join(fieldKindHelp, "\n"),
+		strings.Join(profileKindHelp, "\n"),
+		strings.Join(fileDistributionHelp, "\n"),
+		strings.Join(forwardModeHelp, "\n"),
+		strings.Join(networkFramingHelp, "\n"),
+		strings.Join(rotationStrategyHelp, "\n"),
+		strings.Join(syslogTransportHelp, "\n"),
 		strings.Join(presetHelp, "\n"),
 	)
 
@@ -114,10 +342,17 @@ Task Presets:
 
 Find more information at https://github.com/lichuan0620/logtap
 
+Usage:
+  logtap [options]
+  logtap verify [options]	Check collected log messages; see 'logtap verify -h'
+  logtap receive [options]	Receive log messages and measure their latency; see 'logtap receive -h'
+
 Options:`)
 )
 
-func init() {
+// Parse parses the command line options and populates the exported variables of this package; it exits the
+// program if the options are invalid.
+func Parse() {
 	flag.ErrHelp = fmt.Errorf("")
 	commandLine.Usage = printHelp
 	parse()
@@ -136,6 +371,12 @@ func parse() {
 		"The address to listen on for most HTTP requests",
 	)
 
+	commandLine.StringVar(
+		&ReportPath, "report.path", getEnv("LOGTAP_REPORT_PATH", noDefault),
+		"Path to the file to which a summary of the run is written in JSON upon exit; the summary is always "+
+			"printed to STDERR",
+	)
+
 	commandLine.StringVar(&Spec.OutputKind,
 		"output.kind", getEnv("LOGTAP_OUTPUT_KIND", model.OutputKindStdErr),
 		"The channel to which the log messages should be sent",
@@ -146,6 +387,354 @@ func parse() {
 		"Path to the log file to which the log messages would be appended",
 	)
 
+	commandLine.StringVar(&Spec.Encoding,
+		"output.encoding", getEnv("LOGTAP_OUTPUT_ENCODING", model.EncodingRaw),
+		"The way the log messages are written",
+	)
+
+	container := new(model.ContainerSpec)
+
+	commandLine.StringVar(&container.Stream,
+		"output.container.stream", getEnv("LOGTAP_OUTPUT_CONTAINER_STREAM", model.ContainerStreamStdout),
+		fmt.Sprintf("The stream of the container the log messages are printed to; either %s or %s",
+			model.ContainerStreamStdout, model.ContainerStreamStderr),
+	)
+
+	commandLine.StringVar(&container.LogRoot,
+		"output.container.logRoot", getEnv("LOGTAP_OUTPUT_CONTAINER_LOG_ROOT", noDefault),
+		"The directory, such as /var/log/pods or /var/lib/docker/containers, under which the log file is placed "+
+			"in the layout of the encoding; used in place of --output.filePath",
+	)
+
+	commandLine.StringVar(&container.Namespace,
+		"output.container.namespace", getEnv("LOGTAP_OUTPUT_CONTAINER_NAMESPACE", noDefault),
+		"The namespace of the pod of the container; default if not specified",
+	)
+
+	commandLine.StringVar(&container.Pod,
+		"output.container.pod", getEnv("LOGTAP_OUTPUT_CONTAINER_POD", noDefault),
+		"The name of the pod of the container; --name if not specified",
+	)
+
+	commandLine.StringVar(&container.PodUID,
+		"output.container.podUID", getEnv("LOGTAP_OUTPUT_CONTAINER_POD_UID", noDefault),
+		"The UID of the pod of the container; derived from the name of the pod if not specified",
+	)
+
+	commandLine.StringVar(&container.Name,
+		"output.container.name", getEnv("LOGTAP_OUTPUT_CONTAINER_NAME", noDefault),
+		"The name of the container; logtap if not specified",
+	)
+
+	commandLine.IntVar(&container.RestartCount,
+		"output.container.restartCount", getIntEnv("LOGTAP_OUTPUT_CONTAINER_RESTART_COUNT", 0),
+		"The number of times the container has been restarted",
+	)
+
+	commandLine.StringVar(&container.ID,
+		"output.container.id", getEnv("LOGTAP_OUTPUT_CONTAINER_ID", noDefault),
+		"The ID of the container; derived from the names of the pod and the container if not specified",
+	)
+
+	rotation := new(model.RotationSpec)
+
+	commandLine.StringVar(&rotation.Strategy,
+		"output.rotation.strategy", getEnv("LOGTAP_OUTPUT_ROTATION_STRATEGY", noDefault),
+		"The strategy used to rotate the log file; rotation is disabled if not specified",
+	)
+
+	commandLine.Int64Var(&rotation.MaxSize,
+		"output.rotation.maxSize", getInt64Env("LOGTAP_OUTPUT_ROTATION_MAX_SIZE", 0),
+		"The size in bytes above which the log file is rotated; 0 disables size-based rotation",
+	)
+
+	commandLine.Float64Var(&rotation.MaxAge,
+		"output.rotation.maxAge", getFloat64Env("LOGTAP_OUTPUT_ROTATION_MAX_AGE", 0),
+		"The amount of time, in seconds, after which the log file is rotated; 0 disables time-based rotation",
+	)
+
+	commandLine.IntVar(&rotation.MaxBackups,
+		"output.rotation.maxBackups", getIntEnv("LOGTAP_OUTPUT_ROTATION_MAX_BACKUPS", defaultMaxBackups),
+		"The number of rotated log files to keep",
+	)
+
+	commandLine.BoolVar(&rotation.Compress,
+		"output.rotation.compress", getBoolEnv("LOGTAP_OUTPUT_ROTATION_COMPRESS", false),
+		"Compress the rotated log files with gzip",
+	)
+
+	buffer := new(model.BufferSpec)
+
+	commandLine.IntVar(&buffer.Size,
+		"output.buffer.size", getIntEnv("LOGTAP_OUTPUT_BUFFER_SIZE", 0),
+		"The size in bytes of the buffer in which the log messages are gathered before they are written to the "+
+			"output; 0 disables buffering unless --output.buffer.fsync is set",
+	)
+
+	commandLine.Float64Var(&buffer.FlushInterval,
+		"output.buffer.flushInterval", getFloat64Env("LOGTAP_OUTPUT_BUFFER_FLUSH_INTERVAL", 1),
+		"The amount of time, in seconds, after which the buffered log messages are flushed; 0 only flushes the "+
+			"buffer when it is full",
+	)
+
+	commandLine.StringVar(&buffer.Fsync,
+		"output.buffer.fsync", getEnv("LOGTAP_OUTPUT_BUFFER_FSYNC", model.FsyncNever),
+		fmt.Sprintf("The policy to sync the log file to the disk; %s, %s to sync after every flush, or %s to sync "+
+			"after every --output.buffer.fsyncBytes bytes", model.FsyncNever, model.FsyncFlush, model.FsyncBytes),
+	)
+
+	commandLine.Int64Var(&buffer.FsyncBytes,
+		"output.buffer.fsyncBytes", getInt64Env("LOGTAP_OUTPUT_BUFFER_FSYNC_BYTES", 0),
+		fmt.Sprintf("The size in bytes of log messages flushed after which the log file is synced to the disk "+
+			"with the %s policy", model.FsyncBytes),
+	)
+
+	files := new(model.FilesSpec)
+
+	commandLine.IntVar(&files.Count,
+		"output.files.count", getIntEnv("LOGTAP_OUTPUT_FILES_COUNT", 0),
+		"The number of files written at once, in which case --output.filePath is a pattern such as "+
+			"/logs/app-%d.log; 0 writes a single file",
+	)
+
+	commandLine.StringVar(&files.Distribution,
+		"output.files.distribution", getEnv("LOGTAP_OUTPUT_FILES_DISTRIBUTION", model.FileDistributionRoundRobin),
+		"The way the log messages are spread over the files",
+	)
+
+	fileWeights := commandLine.StringSlice(
+		"output.files.weights", nil,
+		fmt.Sprintf("The comma-separated relative weights of the files, one for every file; only for %s",
+			model.FileDistributionWeighted),
+	)
+
+	commandLine.Float64Var(&files.ChurnInterval,
+		"output.files.churnInterval", getFloat64Env("LOGTAP_OUTPUT_FILES_CHURN_INTERVAL", 0),
+		"The amount of time, in seconds, after which the oldest files are removed and replaced by new ones; "+
+			"0 disables the replacement",
+	)
+
+	commandLine.IntVar(&files.ChurnCount,
+		"output.files.churnCount", getIntEnv("LOGTAP_OUTPUT_FILES_CHURN_COUNT", 0),
+		"The number of files replaced at a time; 1 if not specified",
+	)
+
+	syslog := new(model.SyslogSpec)
+
+	commandLine.StringVar(&syslog.Address,
+		"output.syslog.address", getEnv("LOGTAP_OUTPUT_SYSLOG_ADDRESS", noDefault),
+		"The address of the syslog server, or the path to its Unix socket",
+	)
+
+	commandLine.StringVar(&syslog.Transport,
+		"output.syslog.transport", getEnv("LOGTAP_OUTPUT_SYSLOG_TRANSPORT", model.SyslogTransportUDP),
+		"The way the log messages are sent to the syslog server",
+	)
+
+	commandLine.StringVar(&syslog.Format,
+		"output.syslog.format", getEnv("LOGTAP_OUTPUT_SYSLOG_FORMAT", model.SyslogFormatRFC5424),
+		fmt.Sprintf("The format of the syslog header; either %s or %s",
+			model.SyslogFormatRFC3164, model.SyslogFormatRFC5424),
+	)
+
+	commandLine.StringVar(&syslog.Facility,
+		"output.syslog.facility", getEnv("LOGTAP_OUTPUT_SYSLOG_FACILITY", noDefault),
+		"The syslog facility, such as local0; user if not specified",
+	)
+
+	commandLine.StringVar(&syslog.Severity,
+		"output.syslog.severity", getEnv("LOGTAP_OUTPUT_SYSLOG_SEVERITY", noDefault),
+		"The syslog severity of the log messages without a level, such as warning; info if not specified",
+	)
+
+	commandLine.StringVar(&syslog.AppName,
+		"output.syslog.appName", getEnv("LOGTAP_OUTPUT_SYSLOG_APP_NAME", noDefault),
+		"The application name in the syslog header; logtap if not specified",
+	)
+
+	network := new(model.NetworkSpec)
+
+	commandLine.StringVar(&network.Address,
+		"output.network.address", getEnv("LOGTAP_OUTPUT_NETWORK_ADDRESS", noDefault),
+		"The address of the TCP or UDP server to which the log messages are sent",
+	)
+
+	commandLine.StringVar(&network.Protocol,
+		"output.network.protocol", getEnv("LOGTAP_OUTPUT_NETWORK_PROTOCOL", model.NetworkProtocolTCP),
+		fmt.Sprintf("The network protocol; either %s or %s", model.NetworkProtocolTCP, model.NetworkProtocolUDP),
+	)
+
+	commandLine.StringVar(&network.Framing,
+		"output.network.framing", getEnv("LOGTAP_OUTPUT_NETWORK_FRAMING", model.NetworkFramingNewline),
+		"The way the log messages are delimited",
+	)
+
+	commandLine.Float64Var(&network.ConnectTimeout,
+		"output.network.connectTimeout", getFloat64Env("LOGTAP_OUTPUT_NETWORK_CONNECT_TIMEOUT", 0),
+		"The amount of time, in seconds, to wait for a connection; 5 if not specified",
+	)
+
+	commandLine.Float64Var(&network.MaxReconnectBackoff,
+		"output.network.maxReconnectBackoff", getFloat64Env("LOGTAP_OUTPUT_NETWORK_MAX_RECONNECT_BACKOFF", 0),
+		"The longest amount of time, in seconds, to wait before reconnecting; 10 if not specified",
+	)
+
+	httpSpec := new(model.HTTPSpec)
+
+	commandLine.StringVar(&httpSpec.URL,
+		"output.http.url", getEnv("LOGTAP_OUTPUT_HTTP_URL", noDefault),
+		"The URL of the HTTP endpoint to which the log messages are sent",
+	)
+
+	commandLine.StringVar(&httpSpec.Method,
+		"output.http.method", getEnv("LOGTAP_OUTPUT_HTTP_METHOD", http.MethodPost),
+		"The method of the HTTP requests",
+	)
+
+	httpHeaders := commandLine.StringArray(
+		"output.http.header", nil,
+		"An extra header field of the HTTP requests in the format of NAME:VALUE; can be repeated",
+	)
+
+	commandLine.StringVar(&httpSpec.Format,
+		"output.http.format", getEnv("LOGTAP_OUTPUT_HTTP_FORMAT", model.HTTPFormatNDJSON),
+		fmt.Sprintf("The format of the request body; %s sends the log messages one per line and %s renders "+
+			"--output.http.bodyTemplate", model.HTTPFormatNDJSON, model.HTTPFormatTemplate),
+	)
+
+	commandLine.StringVar(&httpSpec.BodyTemplate,
+		"output.http.bodyTemplate", getEnv("LOGTAP_OUTPUT_HTTP_BODY_TEMPLATE", noDefault),
+		"The Go template of the request body, executed with .Lines and .Timestamp; json quotes a string",
+	)
+
+	commandLine.BoolVar(&httpSpec.Compress,
+		"output.http.compress", getBoolEnv("LOGTAP_OUTPUT_HTTP_COMPRESS", false),
+		"Compress the request body with gzip",
+	)
+
+	commandLine.IntVar(&httpSpec.BatchSize,
+		"output.http.batchSize", getIntEnv("LOGTAP_OUTPUT_HTTP_BATCH_SIZE", 0),
+		"The largest number of log messages in a request; 1000 if not specified",
+	)
+
+	commandLine.IntVar(&httpSpec.BatchBytes,
+		"output.http.batchBytes", getIntEnv("LOGTAP_OUTPUT_HTTP_BATCH_BYTES", 0),
+		"The largest size in bytes of the log messages in a request; 1 MiB if not specified",
+	)
+
+	commandLine.Float64Var(&httpSpec.BatchInterval,
+		"output.http.batchInterval", getFloat64Env("LOGTAP_OUTPUT_HTTP_BATCH_INTERVAL", 0),
+		"The longest amount of time, in seconds, a log message waits to be sent; 1 if not specified",
+	)
+
+	commandLine.Float64Var(&httpSpec.Timeout,
+		"output.http.timeout", getFloat64Env("LOGTAP_OUTPUT_HTTP_TIMEOUT", 0),
+		"The amount of time, in seconds, to wait for a response; 10 if not specified",
+	)
+
+	commandLine.IntVar(&httpSpec.MaxRetries,
+		"output.http.maxRetries", getIntEnv("LOGTAP_OUTPUT_HTTP_MAX_RETRIES", defaultMaxRetries),
+		"The number of times a request failed with a 429 or 5xx response, or with no response, is retried",
+	)
+
+	forward := new(model.ForwardSpec)
+
+	commandLine.StringVar(&forward.Address,
+		"output.forward.address", getEnv("LOGTAP_OUTPUT_FORWARD_ADDRESS", noDefault),
+		"The address of the server speaking the Fluentd Forward protocol",
+	)
+
+	commandLine.StringVar(&forward.Mode,
+		"output.forward.mode", getEnv("LOGTAP_OUTPUT_FORWARD_MODE", model.ForwardModeForward),
+		"The mode in which the log messages are sent",
+	)
+
+	commandLine.StringVar(&forward.Tag,
+		"output.forward.tag", getEnv("LOGTAP_OUTPUT_FORWARD_TAG", noDefault),
+		fmt.Sprintf("The tag of the events, in which %s is replaced by --name; logtap.%s if not specified",
+			model.ForwardTagNamePlaceholder, model.ForwardTagNamePlaceholder),
+	)
+
+	commandLine.BoolVar(&forward.RequireAck,
+		"output.forward.requireAck", getBoolEnv("LOGTAP_OUTPUT_FORWARD_REQUIRE_ACK", false),
+		"Require the server to acknowledge every message",
+	)
+
+	commandLine.Float64Var(&forward.AckTimeout,
+		"output.forward.ackTimeout", getFloat64Env("LOGTAP_OUTPUT_FORWARD_ACK_TIMEOUT", 0),
+		"The amount of time, in seconds, to wait for an acknowledgement; 5 if not specified",
+	)
+
+	commandLine.IntVar(&forward.BatchSize,
+		"output.forward.batchSize", getIntEnv("LOGTAP_OUTPUT_FORWARD_BATCH_SIZE", 0),
+		"The largest number of log messages in a batch; 1000 if not specified",
+	)
+
+	commandLine.Float64Var(&forward.BatchInterval,
+		"output.forward.batchInterval", getFloat64Env("LOGTAP_OUTPUT_FORWARD_BATCH_INTERVAL", 0),
+		"The longest amount of time, in seconds, a log message waits in a batch; 1 if not specified",
+	)
+
+	otlp := new(model.OTLPSpec)
+
+	commandLine.StringVar(&otlp.Endpoint,
+		"output.otlp.endpoint", getEnv("LOGTAP_OUTPUT_OTLP_ENDPOINT", noDefault),
+		"The URL of the OpenTelemetry receiver, to which /v1/logs is appended over HTTP if it has no path",
+	)
+
+	commandLine.StringVar(&otlp.Protocol,
+		"output.otlp.protocol", getEnv("LOGTAP_OUTPUT_OTLP_PROTOCOL", model.OTLPProtocolHTTP),
+		fmt.Sprintf("The protocol of the requests; either %s or %s",
+			model.OTLPProtocolHTTP, model.OTLPProtocolGRPC),
+	)
+
+	otlpHeaders := commandLine.StringArray(
+		"output.otlp.header", nil,
+		"An extra header field, or gRPC metadata, of the requests in the format of NAME:VALUE; can be repeated",
+	)
+
+	commandLine.BoolVar(&otlp.Compress,
+		"output.otlp.compress", getBoolEnv("LOGTAP_OUTPUT_OTLP_COMPRESS", false),
+		"Compress the requests with gzip",
+	)
+
+	commandLine.StringVar(&otlp.Severity,
+		"output.otlp.severity", getEnv("LOGTAP_OUTPUT_OTLP_SEVERITY", noDefault),
+		fmt.Sprintf("The severity text of the log records without a level; one of %s; INFO if not specified",
+			strings.Join(model.LogLevels, ", ")),
+	)
+
+	otlpResourceAttributes := commandLine.StringArray(
+		"output.otlp.resourceAttribute", nil,
+		fmt.Sprintf("An extra resource attribute in the format of KEY=VALUE, besides %s which is --name "+
+			"by default; can be repeated", model.OTLPServiceNameAttribute),
+	)
+
+	otlpAttributes := commandLine.StringArray(
+		"output.otlp.attribute", nil,
+		"An attribute of every log record in the format of KEY=VALUE; can be repeated",
+	)
+
+	commandLine.IntVar(&otlp.BatchSize,
+		"output.otlp.batchSize", getIntEnv("LOGTAP_OUTPUT_OTLP_BATCH_SIZE", 0),
+		"The largest number of log records in a request; 1000 if not specified",
+	)
+
+	commandLine.Float64Var(&otlp.BatchInterval,
+		"output.otlp.batchInterval", getFloat64Env("LOGTAP_OUTPUT_OTLP_BATCH_INTERVAL", 0),
+		"The longest amount of time, in seconds, a log record waits to be exported; 1 if not specified",
+	)
+
+	commandLine.Float64Var(&otlp.Timeout,
+		"output.otlp.timeout", getFloat64Env("LOGTAP_OUTPUT_OTLP_TIMEOUT", 0),
+		"The amount of time, in seconds, to wait for a response; 10 if not specified",
+	)
+
+	commandLine.IntVar(&otlp.MaxRetries,
+		"output.otlp.maxRetries", getIntEnv("LOGTAP_OUTPUT_OTLP_MAX_RETRIES", defaultMaxRetries),
+		"The number of times a request failed in a retryable way is retried",
+	)
+
 	commandLine.StringVar(&Spec.TimestampFormat,
 		"timestamp.format", getEnv("LOGTAP_TIMESTAMP_FORMAT", defaultTimestamp),
 		"Format of the log timestamp",
@@ -161,6 +750,16 @@ func parse() {
 		"The type of content that the log messages would have",
 	)
 
+	commandLine.BoolVar(&Spec.Checksum,
+		"content.checksum", getBoolEnv("LOGTAP_CONTENT_CHECKSUM", false),
+		"Add a checksum of the payload to every log message so that corruption can be detected",
+	)
+
+	commandLine.BoolVar(&Spec.LatencyMarker,
+		"content.latencyMarker", getBoolEnv("LOGTAP_CONTENT_LATENCY_MARKER", false),
+		"Add the time at which every log message is created so that 'logtap receive' can measure the latency",
+	)
+
 	commandLine.StringVar(&Spec.Message,
 		"content.message", getEnv("LOGTAP_CONTENT_MESSAGE", noDefault),
 		"The log message to be be printed",
@@ -168,7 +767,94 @@ func parse() {
 
 	commandLine.IntVarP(&Spec.MinSize,
 		"content.minSize", "s", getIntEnv("LOGTAP_CONTENT_MIN_SIZE", defaultMinSize),
-		"The minimal size of a randomized log message in bytes",
+		"The minimal size of a randomized or JSON log message in bytes",
+	)
+
+	fields := commandLine.StringArray(
+		"content.field", nil,
+		"An extra field of the JSON log messages in the format of NAME=KIND:ARGUMENT; can be repeated",
+	)
+
+	levels := commandLine.StringSlice(
+		"content.levels", nil,
+		fmt.Sprintf("The comma-separated relative weights of the log levels in the format of LEVEL=WEIGHT, "+
+			"such as INFO=90,ERROR=10; the levels are %s", strings.Join(model.LogLevels, ", ")),
+	)
+
+	multiline := new(model.MultilineSpec)
+
+	commandLine.StringSliceVar(&multiline.Kinds,
+		"content.multiline.kinds", nil,
+		fmt.Sprintf("The comma-separated kinds of stack traces, out of %s, %s and %s; all of them if not specified",
+			model.MultilineKindJava, model.MultilineKindPython, model.MultilineKindGo),
+	)
+
+	commandLine.Float64Var(&multiline.Frequency,
+		"content.multiline.frequency", getFloat64Env("LOGTAP_CONTENT_MULTILINE_FREQUENCY", 0.1),
+		"The fraction of the log messages that are followed by stack traces",
+	)
+
+	commandLine.IntVar(&multiline.MinDepth,
+		"content.multiline.minDepth", getIntEnv("LOGTAP_CONTENT_MULTILINE_MIN_DEPTH", 5),
+		"The smallest number of frames in a stack trace",
+	)
+
+	commandLine.IntVar(&multiline.MaxDepth,
+		"content.multiline.maxDepth", getIntEnv("LOGTAP_CONTENT_MULTILINE_MAX_DEPTH", 20),
+		"The largest number of frames in a stack trace",
+	)
+
+	commandLine.BoolVar(&multiline.RepeatPrefix,
+		"content.multiline.repeatPrefix", getBoolEnv("LOGTAP_CONTENT_MULTILINE_REPEAT_PREFIX", false),
+		"Start every line of a stack trace with the timestamp, name and sequence number of its log message",
+	)
+
+	accessLog := new(model.AccessLogSpec)
+
+	commandLine.BoolVar(&accessLog.Prefix,
+		"content.accessLog.prefix", getBoolEnv("LOGTAP_CONTENT_ACCESS_LOG_PREFIX", false),
+		"Start every access log with the timestamp, level, name and sequence number, which is required by "+
+			"--content.checksum; otherwise, the name and the sequence number are carried in the query string",
+	)
+
+	accessLogMethods := commandLine.StringArray(
+		"content.accessLog.method", nil,
+		"A request method of the access logs with its relative weight, such as GET=80; can be repeated",
+	)
+
+	accessLogPaths := commandLine.StringArray(
+		"content.accessLog.path", nil,
+		"A request path of the access logs with its relative weight, such as /index.html=5; can be repeated",
+	)
+
+	accessLogStatuses := commandLine.StringArray(
+		"content.accessLog.status", nil,
+		"A response status code of the access logs with its relative weight, such as 503=0.5; can be repeated",
+	)
+
+	accessLogReferrers := commandLine.StringArray(
+		"content.accessLog.referrer", nil,
+		"A referrer of the access logs with its relative weight, such as -=60; can be repeated",
+	)
+
+	accessLogUserAgents := commandLine.StringArray(
+		"content.accessLog.userAgent", nil,
+		"A user agent of the access logs with its relative weight, such as curl/8.5.0=15; can be repeated",
+	)
+
+	commandLine.IntVar(&accessLog.MinBytes,
+		"content.accessLog.minBytes", getIntEnv("LOGTAP_CONTENT_ACCESS_LOG_MIN_BYTES", 0),
+		"The smallest size of a response body in bytes",
+	)
+
+	commandLine.IntVar(&accessLog.MaxBytes,
+		"content.accessLog.maxBytes", getIntEnv("LOGTAP_CONTENT_ACCESS_LOG_MAX_BYTES", 0),
+		"The largest size of a response body in bytes; 20000 if not specified",
+	)
+
+	commandLine.StringVar(&accessLog.ClientNetwork,
+		"content.accessLog.clientNetwork", getEnv("LOGTAP_CONTENT_ACCESS_LOG_CLIENT_NETWORK", noDefault),
+		"The network, in CIDR notation, of the client IP addresses; 10.0.0.0/16 if not specified",
 	)
 
 	commandLine.Float64VarP(&Spec.Interval,
@@ -176,6 +862,49 @@ func parse() {
 		"The amount of time, in seconds, to wait in-between log messages",
 	)
 
+	commandLine.Float64Var(&Spec.TargetLogsPerSecond,
+		"target.logsPerSecond", getFloat64Env("LOGTAP_TARGET_LOGS_PER_SECOND", 0),
+		"The number of log messages to send per second; replaces --interval if specified",
+	)
+
+	commandLine.Float64Var(&Spec.TargetBytesPerSecond,
+		"target.bytesPerSecond", getFloat64Env("LOGTAP_TARGET_BYTES_PER_SECOND", 0),
+		"The size in bytes of log messages to send per second; replaces --interval if specified",
+	)
+
+	commandLine.IntVar(&Spec.Workers,
+		"workers", getIntEnv("LOGTAP_WORKERS", 0),
+		"The number of workers sending log messages in parallel; the name in the log messages of every worker is "+
+			"followed by a slash and its index if there are more than one",
+	)
+
+	commandLine.StringVar(&Spec.WorkerWriter,
+		"workers.writer", getEnv("LOGTAP_WORKERS_WRITER", model.WorkerWriterShared),
+		fmt.Sprintf("The way the workers write to the output; either %s or %s, in which case the file path of the "+
+			"File output must contain a %%d verb replaced by the index of every worker",
+			model.WorkerWriterShared, model.WorkerWriterPerWorker),
+	)
+
+	commandLine.Int64Var(&Spec.MaxCount,
+		"stop.maxCount", getInt64Env("LOGTAP_STOP_MAX_COUNT", 0),
+		"The number of log messages after which the task stops; LogTap exits once every task has stopped",
+	)
+
+	commandLine.Int64Var(&Spec.MaxBytes,
+		"stop.maxBytes", getInt64Env("LOGTAP_STOP_MAX_BYTES", 0),
+		"The size in bytes of log messages after which the task stops",
+	)
+
+	commandLine.Float64Var(&Spec.Duration,
+		"stop.duration", getFloat64Env("LOGTAP_STOP_DURATION", 0),
+		"The amount of time, in seconds, after which the task stops",
+	)
+
+	profile := commandLine.String(
+		"profile", getEnv("LOGTAP_PROFILE", noDefault),
+		"The load profile in the format of KIND:ARGUMENTS; replaces --interval and --target.logsPerSecond",
+	)
+
 	showVersion := commandLine.BoolP(
 		"version", "v", false,
 		"Print the version information and quit",
@@ -202,10 +931,116 @@ func parse() {
 		Spec.TimestampFormat = ""
 	}
 
+	if len(*profile) > 0 {
+		var err error
+		Spec.Profile, err = parseProfileSpec(*profile)
+		failOnError(err)
+	}
+
+	if Spec.TargetLogsPerSecond > 0 || Spec.TargetBytesPerSecond > 0 || Spec.Profile != nil {
+		if !commandLine.Changed("interval") && os.Getenv("LOGTAP_INTERVAL") == "" {
+			Spec.Interval = 0
+		}
+	}
+
+	if Spec.ContentType != model.ContentTypeRandom && Spec.ContentType != model.ContentTypeJSON {
+		if !commandLine.Changed("content.minSize") && os.Getenv("LOGTAP_CONTENT_MIN_SIZE") == "" {
+			Spec.MinSize = 0
+		}
+	}
+
+	for _, field := range *fields {
+		fieldSpec, err := parseFieldSpec(field)
+		failOnError(err)
+		Spec.Fields = append(Spec.Fields, *fieldSpec)
+	}
+
+	for level, weight := range parsePairs(*levels, "=", "level", "LEVEL=WEIGHT") {
+		value, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			failOnError(fmt.Errorf("invalid weight of level '%s'", level))
+		}
+		if Spec.Levels == nil {
+			Spec.Levels = make(map[string]float64)
+		}
+		Spec.Levels[level] = value
+	}
+
+	if Spec.ContentType == model.ContentTypeMultiline {
+		Spec.Multiline = multiline
+	}
+
+	switch Spec.ContentType {
+	case model.ContentTypeApacheCommon, model.ContentTypeApacheCombined, model.ContentTypeNginx,
+		model.ContentTypeEnvoy:
+		accessLog.Methods = parseWeightedValues(*accessLogMethods, "method")
+		accessLog.Paths = parseWeightedValues(*accessLogPaths, "path")
+		accessLog.Statuses = parseWeightedValues(*accessLogStatuses, "status")
+		accessLog.Referrers = parseWeightedValues(*accessLogReferrers, "referrer")
+		accessLog.UserAgents = parseWeightedValues(*accessLogUserAgents, "user agent")
+		Spec.AccessLog = accessLog
+	}
+
+	if len(rotation.Strategy) > 0 {
+		Spec.Rotation = rotation
+	}
+
+	if buffer.Size > 0 || buffer.Fsync != model.FsyncNever {
+		Spec.Buffer = buffer
+	}
+
+	if files.Count > 0 {
+		for _, weight := range *fileWeights {
+			value, err := strconv.ParseFloat(weight, 64)
+			if err != nil {
+				failOnError(fmt.Errorf("invalid file weight '%s'", weight))
+			}
+			files.Weights = append(files.Weights, value)
+		}
+		Spec.Files = files
+	}
+
+	if Spec.Encoding == model.EncodingCRI || Spec.Encoding == model.EncodingDockerJSON {
+		Spec.Container = container
+	}
+
+	if Spec.OutputKind == model.OutputKindSyslog {
+		Spec.Syslog = syslog
+	}
+
+	if Spec.OutputKind == model.OutputKindNetwork {
+		Spec.Network = network
+	}
+
+	if Spec.OutputKind == model.OutputKindForward {
+		Spec.Forward = forward
+	}
+
+	if Spec.OutputKind == model.OutputKindHTTP {
+		httpSpec.Headers = parsePairs(*httpHeaders, ":", "header", "NAME:VALUE")
+		Spec.HTTP = httpSpec
+	}
+
+	if Spec.OutputKind == model.OutputKindOTLP {
+		otlp.Headers = parsePairs(*otlpHeaders, ":", "header", "NAME:VALUE")
+		otlp.ResourceAttributes = parsePairs(*otlpResourceAttributes, "=", "resource attribute", "KEY=VALUE")
+		otlp.Attributes = parsePairs(*otlpAttributes, "=", "attribute", "KEY=VALUE")
+		Spec.OTLP = otlp
+	}
+
+	cfg := new(config.Config)
+	if len(*configPath) > 0 {
+		var err error
+		cfg, err = config.Load(*configPath)
+		failOnError(err)
+	}
+
 	if len(*template) > 0 {
 		var err error
-		Spec, err = model.GetLogTaskSpecPreset(*template)
+		Spec, err = cfg.GetLogTaskSpecPreset(*template)
 		failOnError(err)
+	} else {
+		Tasks = cfg.Tasks
 	}
 
 	if len(*duration) > 0 {
@@ -218,6 +1053,91 @@ func parse() {
 	}
 }
 
+// parseFieldSpec parses a FieldSpec from a string in the format of NAME=KIND:ARGUMENT.
+func parseFieldSpec(value string) (*model.FieldSpec, error) {
+	invalid := fmt.Errorf("invalid field '%s': want NAME=KIND:ARGUMENT", value)
+	eq := strings.Index(value, "=")
+	if eq < 0 {
+		return nil, invalid
+	}
+	ret := &model.FieldSpec{Name: value[:eq]}
+	ret.Kind = value[eq+1:]
+	var argument string
+	if colon := strings.Index(ret.Kind, ":"); colon >= 0 {
+		ret.Kind, argument = ret.Kind[:colon], ret.Kind[colon+1:]
+	}
+	var err error
+	switch ret.Kind {
+	case model.FieldKindConstant:
+		ret.Value = argument
+	case model.FieldKindPick:
+		ret.Values = strings.Split(argument, "|")
+	case model.FieldKindRandomString:
+		ret.Size, err = strconv.Atoi(argument)
+	case model.FieldKindRandomInt:
+		bounds := strings.SplitN(argument, ":", 2)
+		if len(bounds) != 2 {
+			return nil, invalid
+		}
+		if ret.Min, err = strconv.ParseInt(bounds[0], 10, 64); err == nil {
+			ret.Max, err = strconv.ParseInt(bounds[1], 10, 64)
+		}
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return ret, nil
+}
+
+// parseProfileSpec parses a ProfileSpec from a string in the format of KIND:ARGUMENTS.
+func parseProfileSpec(value string) (*model.ProfileSpec, error) {
+	invalid := fmt.Errorf("invalid profile '%s': see the help for the format", value)
+	parts := strings.Split(value, ":")
+	ret := &model.ProfileSpec{Kind: parts[0]}
+	args := make([]float64, 0, len(parts)-1)
+	if ret.Kind != model.ProfileKindStep {
+		for _, part := range parts[1:] {
+			arg, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			args = append(args, arg)
+		}
+		if len(args) != 3 {
+			return nil, invalid
+		}
+	}
+	switch ret.Kind {
+	case model.ProfileKindRamp:
+		ret.From, ret.To, ret.Duration = args[0], args[1], args[2]
+	case model.ProfileKindStep:
+		if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "Repeat") {
+			return nil, invalid
+		}
+		ret.Repeat = len(parts) == 3
+		for _, stage := range strings.Split(parts[1], ",") {
+			at := strings.Index(stage, "@")
+			if at < 0 {
+				return nil, invalid
+			}
+			rate, err := strconv.ParseFloat(stage[:at], 64)
+			if err != nil {
+				return nil, invalid
+			}
+			duration, err := strconv.ParseFloat(stage[at+1:], 64)
+			if err != nil {
+				return nil, invalid
+			}
+			ret.Steps = append(ret.Steps, model.ProfileStep{Rate: rate, Duration: duration})
+		}
+	case model.ProfileKindSine:
+		ret.Base, ret.Amplitude, ret.Period = args[0], args[1], args[2]
+	case model.ProfileKindBurst:
+		ret.Base, ret.BurstSize, ret.Period = args[0], int(args[1]), args[2]
+	}
+	return ret, nil
+}
+
 func printVersion() {
 	fmt.Fprintln(os.Stderr, fmt.Sprintf("%s version %s", version.Name, version.Version))
 }
@@ -244,6 +1164,40 @@ func terminateAfter(duration time.Duration) {
 	close(StopCh)
 }
 
+// parsePairs parses the values of a repeatable flag, each being a name and a value joined by the separator, into
+// a map; it returns nil if there is no value.
+func parsePairs(values []string, separator, what, format string) map[string]string {
+	var ret map[string]string
+	for _, value := range values {
+		pos := strings.Index(value, separator)
+		if pos < 0 {
+			failOnError(fmt.Errorf("invalid %s '%s': want %s", what, value, format))
+		}
+		if ret == nil {
+			ret = make(map[string]string)
+		}
+		ret[value[:pos]] = strings.TrimSpace(value[pos+1:])
+	}
+	return ret
+}
+
+// parseWeightedValues parses values in the format of VALUE=WEIGHT, where VALUE can contain '=' but WEIGHT cannot.
+func parseWeightedValues(values []string, what string) []model.WeightedValue {
+	var ret []model.WeightedValue
+	for _, value := range values {
+		pos := strings.LastIndex(value, "=")
+		if pos < 0 {
+			failOnError(fmt.Errorf("invalid %s '%s': want VALUE=WEIGHT", what, value))
+		}
+		weight, err := strconv.ParseFloat(value[pos+1:], 64)
+		if err != nil {
+			failOnError(fmt.Errorf("invalid %s '%s': invalid weight", what, value))
+		}
+		ret = append(ret, model.WeightedValue{Value: value[:pos], Weight: weight})
+	}
+	return ret
+}
+
 func getEnv(name, def string) string {
 	if env := os.Getenv(name); env != "" {
 		return env
@@ -267,6 +1221,15 @@ func getIntEnv(name string, def int) int {
 	return def
 }
 
+func getInt64Env(name string, def int64) int64 {
+	if env := os.Getenv(name); env != "" {
+		if ret, err := strconv.ParseInt(env, 10, 64); err == nil {
+			return ret
+		}
+	}
+	return def
+}
+
 func getFloat64Env(name string, def float64) float64 {
 	if env := os.Getenv(name); env != "" {
 		if ret, err := strconv.ParseFloat(env, 64); err == nil {
@@ -0,0 +1,187 @@
+// Package receive implements the receive command of LogTap, which acts as a sink for the log messages carrying
+// latency markers and measures their end-to-end latency and throughput per task.
+package receive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/lichuan0620/logtap/pkg/receive"
+	"github.com/lichuan0620/logtap/pkg/signal"
+)
+
+// Command is the name of the receive command.
+const Command = "receive"
+
+const usage = `Receive the log messages sent by LogTap over TCP, UDP, HTTP or syslog, or appended to files, and measure the
+end-to-end latency of those carrying latency markers, as enabled by --content.latencyMarker. The latency histograms
+and the throughput per task are served in the Prometheus text format under /metrics and in JSON under /report; a
+summary is printed upon exit. The clocks of the hosts must be in sync.
+
+Usage:
+  logtap receive [options]
+
+Options:`
+
+// Run executes the receive command with the given arguments and returns the exit code.
+func Run(args []string) int {
+	commandLine := flag.NewFlagSet(Command, flag.ContinueOnError)
+	commandLine.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		commandLine.PrintDefaults()
+	}
+	tcpAddress := commandLine.String(
+		"tcp", "",
+		"Address on which to accept newline-delimited log messages over TCP, such as ':5140'",
+	)
+	udpAddress := commandLine.String(
+		"udp", "",
+		"Address on which to accept log messages over UDP, one or more lines per datagram",
+	)
+	httpAddress := commandLine.String(
+		"http", "",
+		"Address on which to accept newline-delimited log messages in the body of POST requests, which may be "+
+			"gzip-compressed",
+	)
+	syslogAddress := commandLine.String(
+		"syslog", "",
+		"Address on which to accept syslog messages over both UDP and TCP; the TCP frames can be newline-delimited "+
+			"or octet-counted",
+	)
+	files := commandLine.StringArray(
+		"file", nil,
+		"Path to a file to tail, following truncation and rotation; can be repeated",
+	)
+	webAddress := commandLine.String(
+		"web.address", ":8081",
+		"Address on which to serve the metrics and the report",
+	)
+	asJSON := commandLine.Bool(
+		"json", false,
+		"Print the summary in JSON instead of a table",
+	)
+	if err := commandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if len(*tcpAddress)+len(*udpAddress)+len(*httpAddress)+len(*syslogAddress)+len(*files) == 0 {
+		fmt.Fprintln(os.Stderr, "at least one of --tcp, --udp, --http, --syslog and --file is required")
+		return 2
+	}
+
+	receiver := receive.NewReceiver()
+	errCh := make(chan error, 1)
+	serve := func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+	var closers []io.Closer
+	defer func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}()
+	listenTCP := func(address string, octetCounting bool) error {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, listener)
+		go func() { serve(receive.ServeTCP(listener, receiver, octetCounting)) }()
+		return nil
+	}
+	listenUDP := func(address string) error {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, conn)
+		go func() { serve(receive.ServeUDP(conn, receiver)) }()
+		return nil
+	}
+	listenHTTP := func(address string, handler http.Handler) error {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, listener)
+		go func() { serve(http.Serve(listener, handler)) }()
+		return nil
+	}
+
+	var err error
+	if len(*tcpAddress) > 0 {
+		err = listenTCP(*tcpAddress, false)
+	}
+	if err == nil && len(*udpAddress) > 0 {
+		err = listenUDP(*udpAddress)
+	}
+	if err == nil && len(*httpAddress) > 0 {
+		err = listenHTTP(*httpAddress, receive.NewIngestHandler(receiver))
+	}
+	if err == nil && len(*syslogAddress) > 0 {
+		if err = listenUDP(*syslogAddress); err == nil {
+			err = listenTCP(*syslogAddress, true)
+		}
+	}
+	if err == nil {
+		err = listenHTTP(*webAddress, receive.NewHandler(receiver))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	stopCh := signal.SetupStopSignalHandler()
+	for _, path := range *files {
+		path := path
+		go func() { serve(receive.TailFile(path, receiver, stopCh)) }()
+	}
+
+	code := 0
+	select {
+	case <-stopCh:
+	case err = <-errCh:
+		fmt.Fprintln(os.Stderr, err.Error())
+		code = 1
+	}
+	report := receiver.Report(time.Now())
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "    ")
+		fmt.Println(string(data))
+	} else {
+		printTable(os.Stdout, report)
+	}
+	return code
+}
+
+func printTable(writer io.Writer, report *receive.Report) {
+	table := tabwriter.NewWriter(writer, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "TASK\tRECEIVED\tUNMARKED\tLOGS/S\tBYTES/S\tP50\tP90\tP99\tMAX\t")
+	for _, task := range report.Tasks {
+		fmt.Fprintf(
+			table, "%s\t%d\t%d\t%.1f\t%.0f\t%s\t%s\t%s\t%s\t\n",
+			task.Name, task.Received, task.Unmarked, task.LogsPerSecond, task.BytesPerSecond,
+			formatLatency(task.Latency.P50), formatLatency(task.Latency.P90),
+			formatLatency(task.Latency.P99), formatLatency(task.Latency.Max),
+		)
+	}
+	table.Flush()
+	fmt.Fprintf(writer, "\n%d line(s) without a sequence number\n", report.Unrecognized)
+}
+
+// formatLatency formats a latency in seconds as a duration rounded to the microsecond.
+func formatLatency(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond).String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/logtap"
+)
+
+// writeReport writes the report in JSON to the file at the given path.
+func writeReport(path string, report *logtap.Report) error {
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printReport prints the report as a table.
+func printReport(writer io.Writer, report *logtap.Report) {
+	table := tabwriter.NewWriter(writer, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "TASK\tPHASE\tDURATION\tSENT\tBYTES\tLOGS/S\tTARGET\tBYTES/S\tSHORTFALL\t"+
+		"P50\tP90\tP99\tMAX\tERRORS\tDROPPED\t")
+	for _, task := range report.Tasks {
+		target := "-"
+		if task.TargetLogsPerSecond > 0 {
+			target = fmt.Sprintf("%.1f", task.TargetLogsPerSecond)
+		}
+		fmt.Fprintf(
+			table, "%s\t%s\t%s\t%d\t%d\t%.1f\t%s\t%.0f\t%.1f%%\t%s\t%s\t%s\t%s\t%d\t%d\t\n",
+			task.Name, task.Phase, formatSeconds(task.Duration, time.Millisecond), task.SentCount, task.SentBytes,
+			task.LogsPerSecond, target, task.BytesPerSecond, task.Shortfall*100,
+			formatSeconds(task.WriteLatency.P50, time.Microsecond),
+			formatSeconds(task.WriteLatency.P90, time.Microsecond),
+			formatSeconds(task.WriteLatency.P99, time.Microsecond),
+			formatSeconds(task.WriteLatency.Max, time.Microsecond),
+			task.WriteErrors, task.DroppedWrites,
+		)
+	}
+	table.Flush()
+}
+
+// formatSeconds formats a number of seconds as a duration rounded to the given precision.
+func formatSeconds(seconds float64, precision time.Duration) string {
+	return time.Duration(seconds * float64(time.Second)).Round(precision).String()
+}
@@ -0,0 +1,179 @@
+// Package verify implements the verify command of LogTap, which reads the log messages collected from a sink and
+// reports the missing, duplicated, out-of-order and corrupted ones per task.
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	flag "github.com/spf13/pflag"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"github.com/lichuan0620/logtap/pkg/verify"
+)
+
+// Command is the name of the verify command.
+const Command = "verify"
+
+const usage = `Read the log messages collected from a sink, from the given files or STDIN if none is given, and report
+the missing, duplicated, out-of-order and corrupted ones per task. The exit code is 1 if any log is missing,
+duplicated, corrupted or unexpected.
+
+Usage:
+  logtap verify [options] [FILE...]
+
+Options:`
+
+// Run executes the verify command with the given arguments and returns the exit code.
+func Run(args []string) int {
+	commandLine := flag.NewFlagSet(Command, flag.ContinueOnError)
+	commandLine.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		commandLine.PrintDefaults()
+	}
+	tasksPath := commandLine.String(
+		"tasks", "",
+		"Path to a LogTask or LogTaskList JSON document, as returned by the API, providing the expected counts",
+	)
+	expectations := commandLine.StringArray(
+		"expect", nil,
+		"The expected number of logs of a task in the format of NAME=COUNT; can be repeated",
+	)
+	asJSON := commandLine.Bool(
+		"json", false,
+		"Print the report in JSON instead of a table",
+	)
+	if err := commandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	expected := make(map[string]int64)
+	if len(*tasksPath) > 0 {
+		if err := loadExpectedCounts(*tasksPath, expected); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+	}
+	for _, expectation := range *expectations {
+		eq := strings.LastIndex(expectation, "=")
+		if eq < 0 {
+			fmt.Fprintf(os.Stderr, "invalid expectation '%s': want NAME=COUNT\n", expectation)
+			return 2
+		}
+		count, err := strconv.ParseInt(expectation[eq+1:], 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid expectation '%s': %s\n", expectation, err.Error())
+			return 2
+		}
+		expected[expectation[:eq]] = count
+	}
+
+	verifier := verify.NewVerifier()
+	if commandLine.NArg() == 0 {
+		if err := readLines(os.Stdin, verifier); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+	}
+	for _, path := range commandLine.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		err = readLines(file, verifier)
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+	}
+
+	report := verifier.Report(expected)
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "    ")
+		fmt.Println(string(data))
+	} else {
+		printTable(os.Stdout, report)
+	}
+	for i := range report.Tasks {
+		if !report.Tasks[i].OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+func readLines(reader io.Reader, verifier verify.Verifier) error {
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if len(line) > 0 {
+			verifier.Add(bytes.TrimSuffix(line, []byte("\n")))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// loadExpectedCounts reads the final SentCount of the tasks, or of their workers if they have many, from a LogTask
+// or LogTaskList JSON document.
+func loadExpectedCounts(path string, expected map[string]int64) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	list := new(model.LogTaskList)
+	if err = json.Unmarshal(data, list); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", path, err.Error())
+	}
+	if len(list.LogTasks) == 0 {
+		task := new(model.LogTask)
+		if err = json.Unmarshal(data, task); err != nil {
+			return fmt.Errorf("failed to parse %s: %s", path, err.Error())
+		}
+		list.LogTasks = append(list.LogTasks, *task)
+	}
+	for _, task := range list.LogTasks {
+		if len(task.Name) == 0 || task.Status == nil {
+			return fmt.Errorf("%s does not contain the status of a LogTask", path)
+		}
+		if len(task.Status.Workers) > 0 {
+			for _, worker := range task.Status.Workers {
+				expected[worker.Name] = worker.SentCount
+			}
+			continue
+		}
+		expected[task.Name] = task.Status.SentCount
+	}
+	return nil
+}
+
+func printTable(writer io.Writer, report *verify.Report) {
+	table := tabwriter.NewWriter(writer, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "TASK\tEXPECTED\tRECEIVED\tMISSING\tDUPLICATED\tOUT OF ORDER\tCORRUPTED\tUNEXPECTED\t")
+	for _, task := range report.Tasks {
+		fmt.Fprintf(
+			table, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			task.Name, task.Expected, task.Received, task.Missing,
+			task.Duplicated, task.OutOfOrder, task.Corrupted, task.Unexpected,
+		)
+	}
+	table.Flush()
+	fmt.Fprintf(writer, "\n%d line(s) without a sequence number\n", report.Unrecognized)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/lichuan0620/logtap/pkg/fieldpath"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// Config is the content of a configuration file.
+type Config struct {
+	// Presets are the user-defined LogTaskSpec presets, keyed by their names.
+	Presets map[string]*model.LogTaskSpec `json:"presets,omitempty"`
+
+	// Tasks are the LogTasks to be created.
+	Tasks []model.LogTask `json:"tasks,omitempty"`
+}
+
+// documentSeparator matches the lines that separate the documents of a YAML stream.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?\r?$`)
+
+// Load reads a configuration file; see Parse for its format.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return ret, nil
+}
+
+// Parse parses a configuration in YAML or JSON, which may consist of multiple YAML documents. Each document is
+// either a single LogTask with metadata and spec, a list of such LogTasks, or a Config with tasks and presets.
+// Unknown fields are rejected. The version of the LogTasks defaults to model.Version; the LogTasks and the
+// presets are validated, and their names must be unique.
+func Parse(data []byte) (*Config, error) {
+	ret := new(Config)
+	for i, document := range documentSeparator.Split(string(data), -1) {
+		path := fieldpath.NewFieldPath(fmt.Sprintf("document[%d]", i))
+		if err := ret.parseDocument(path, []byte(document)); err != nil {
+			return nil, err
+		}
+	}
+	if err := ret.validate(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PresetNames returns the names of the presets in alphabetical order.
+func (c *Config) PresetNames() []string {
+	ret := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// GetLogTaskSpecPreset returns a copy of the named preset, looking up the user-defined presets before the
+// predefined ones.
+func (c *Config) GetLogTaskSpecPreset(name string) (*model.LogTaskSpec, error) {
+	if preset, exist := c.Presets[name]; exist {
+		return preset.DeepCopy(), nil
+	}
+	return model.GetLogTaskSpecPreset(name)
+}
+
+func (c *Config) parseDocument(path fieldpath.FieldPath, data []byte) error {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path.String(), err.Error())
+	}
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case bytes.HasPrefix(data, []byte("[")):
+		var tasks []model.LogTask
+		if err = decodeStrict(data, &tasks); err != nil {
+			return fmt.Errorf("%s: %s", path.String(), err.Error())
+		}
+		c.Tasks = append(c.Tasks, tasks...)
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%s: %s", path.String(), err.Error())
+	}
+	if _, exist := fields["spec"]; exist {
+		task := model.LogTask{}
+		if err = decodeStrict(data, &task); err != nil {
+			return fmt.Errorf("%s: %s", path.String(), err.Error())
+		}
+		c.Tasks = append(c.Tasks, task)
+		return nil
+	}
+	config := new(Config)
+	if err = decodeStrict(data, config); err != nil {
+		return fmt.Errorf("%s: %s", path.String(), err.Error())
+	}
+	c.Tasks = append(c.Tasks, config.Tasks...)
+	for name, preset := range config.Presets {
+		if _, exist := c.Presets[name]; exist {
+			return fmt.Errorf("%s: preset '%s' is defined more than once", path.String(), name)
+		}
+		if c.Presets == nil {
+			c.Presets = make(map[string]*model.LogTaskSpec)
+		}
+		c.Presets[name] = preset
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	for _, name := range c.PresetNames() {
+		path := fieldpath.NewFieldPath("presets", name)
+		if _, err := model.GetLogTaskSpecPreset(name); err == nil {
+			return fmt.Errorf("%s: preset name conflicts with a predefined preset", path.String())
+		}
+		if c.Presets[name] == nil {
+			return fmt.Errorf("%s: spec not specified", path.String())
+		}
+		if err := model.ValidateLogTaskSpec(path, c.Presets[name]); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]bool, len(c.Tasks))
+	for i := range c.Tasks {
+		task := &c.Tasks[i]
+		path := fieldpath.NewFieldPath(fmt.Sprintf("tasks[%d]", i))
+		if len(task.Version) == 0 {
+			task.Version = model.Version
+		}
+		if task.Spec == nil {
+			return fmt.Errorf("%s: spec not specified", path.String())
+		}
+		if task.Status == nil {
+			task.Status = &model.LogTaskStatus{Phase: model.PhaseIdle}
+		}
+		if err := model.ValidateLogTask(path, task); err != nil {
+			return err
+		}
+		if names[task.Name] {
+			return fmt.Errorf("%s: task '%s' is defined more than once", path.Add("metadata").String(), task.Name)
+		}
+		names[task.Name] = true
+	}
+	return nil
+}
+
+// decodeStrict decodes the JSON data into v, rejecting unknown fields and trailing data.
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the document")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+# a single task
+metadata:
+  name: nginx
+spec:
+  outputKind: STDOUT
+  contentType: Random
+  minSize: 256
+  targetLogsPerSecond: 100
+---
+presets:
+  Tiny:
+    outputKind: STDERR
+    contentType: Explicit
+    message: hello
+    interval: 1
+tasks:
+- metadata: {name: app}
+  spec: {outputKind: STDERR, contentType: Explicit, message: hi, interval: 0.1}
+---
+[{"metadata": {"name": "json"}, "spec": {"outputKind": "STDERR", "contentType": "Random", "interval": 1}}]
+`
+	config, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(config.Tasks) != 3 {
+		t.Fatalf("unexpected number of tasks: want 3; got %d", len(config.Tasks))
+	}
+	for i, name := range []string{"nginx", "app", "json"} {
+		task := config.Tasks[i]
+		if task.Name != name || task.Version != model.Version || task.Status.Phase != model.PhaseIdle {
+			t.Fatalf("unexpected task %d: %+v", i, task)
+		}
+	}
+	if config.Tasks[0].Spec.TargetLogsPerSecond != 100 {
+		t.Fatalf("unexpected spec: %+v", config.Tasks[0].Spec)
+	}
+	preset, err := config.GetLogTaskSpecPreset("Tiny")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if preset.Message != "hello" {
+		t.Fatalf("unexpected preset: %+v", preset)
+	}
+	if _, err = config.GetLogTaskSpecPreset(model.TaskPresetStandard); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			"UnknownField",
+			"metadata: {name: a}\nspec: {outputKind: STDERR, contentType: Random, interval: 1, unknown: 1}",
+			"unknown field",
+		},
+		{
+			"UnknownTopLevelField",
+			"task: []",
+			"unknown field",
+		},
+		{
+			"InvalidSpec",
+			"metadata: {name: a}\nspec: {outputKind: STDERR, contentType: Random, interval: -1}",
+			"spec.interval",
+		},
+		{
+			"MissingSpec",
+			"tasks: [{metadata: {name: a}}]",
+			"spec not specified",
+		},
+		{
+			"DuplicateTask",
+			"[{metadata: {name: a}, spec: {outputKind: STDERR, contentType: Random, interval: 1}}]\n---\n" +
+				"metadata: {name: a}\nspec: {outputKind: STDERR, contentType: Random, interval: 1}",
+			"more than once",
+		},
+		{
+			"PredefinedPreset",
+			"presets: {Standard: {outputKind: STDERR, contentType: Random, interval: 1}}",
+			"predefined preset",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected an error containing %q; got %v", tc.name, tc.err, err)
+		}
+	}
+}
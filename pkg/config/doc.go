@@ -0,0 +1,2 @@
+// Package config loads the LogTasks and the user-defined LogTaskSpec presets declared in YAML or JSON files.
+package config
@@ -40,6 +40,12 @@ func NewRequestError(message string) error {
 	return NewHTTPError(http.StatusBadRequest, reason, message)
 }
 
+// NewConflictError should be used when the request conflicts with the current state of the target.
+func NewConflictError(message string) error {
+	const reason = "conflict"
+	return NewHTTPError(http.StatusConflict, reason, message)
+}
+
 // NewValidationError should be used when a requested resource is found but not valid.
 func NewValidationError(message string) error {
 	const reason = "invalid data"
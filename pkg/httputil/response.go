@@ -15,6 +15,11 @@ func WritePostResponse(w http.ResponseWriter, body interface{}, err error, heade
 	writeResponse(w, http.StatusCreated, body, err, headers...)
 }
 
+// WriteDeleteResponse composes a response for a DELETE request.
+func WriteDeleteResponse(w http.ResponseWriter, body interface{}, err error, headers ...HeaderField) {
+	writeResponse(w, http.StatusOK, body, err, headers...)
+}
+
 func writeResponse(w http.ResponseWriter, successCode int, body interface{}, err error, headers ...HeaderField) {
 	for _, h := range headers {
 		w.Header().Add(h.Key(), h.Value())
@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultAccessLogMaxBytes      = 20000
+	defaultAccessLogClientNetwork = "10.0.0.0/16"
+	accessLogProtocol             = "HTTP/1.1"
+	apacheTimeFormat              = "02/Jan/2006:15:04:05 -0700"
+	envoyTimeFormat               = "2006-01-02T15:04:05.000Z"
+)
+
+var (
+	defaultAccessLogMethods = []model.WeightedValue{
+		{Value: "GET", Weight: 80},
+		{Value: "POST", Weight: 12},
+		{Value: "PUT", Weight: 4},
+		{Value: "DELETE", Weight: 2},
+		{Value: "HEAD", Weight: 2},
+	}
+	defaultAccessLogPaths = []model.WeightedValue{
+		{Value: "/", Weight: 10},
+		{Value: "/index.html", Weight: 5},
+		{Value: "/login", Weight: 5},
+		{Value: "/api/v1/orders", Weight: 25},
+		{Value: "/api/v1/orders?page=2&size=20", Weight: 5},
+		{Value: "/api/v1/users/me", Weight: 15},
+		{Value: "/static/js/app.js", Weight: 15},
+		{Value: "/static/css/style.css", Weight: 10},
+		{Value: "/healthz", Weight: 10},
+	}
+	defaultAccessLogStatuses = []model.WeightedValue{
+		{Value: "200", Weight: 80},
+		{Value: "201", Weight: 3},
+		{Value: "204", Weight: 2},
+		{Value: "301", Weight: 1},
+		{Value: "304", Weight: 5},
+		{Value: "400", Weight: 2},
+		{Value: "401", Weight: 1},
+		{Value: "404", Weight: 3},
+		{Value: "500", Weight: 1.5},
+		{Value: "502", Weight: 0.5},
+		{Value: "503", Weight: 0.5},
+		{Value: "504", Weight: 0.5},
+	}
+	defaultAccessLogReferrers = []model.WeightedValue{
+		{Value: "-", Weight: 60},
+		{Value: "https://www.google.com/", Weight: 20},
+		{Value: "https://example.com/", Weight: 20},
+	}
+	defaultAccessLogUserAgents = []model.WeightedValue{
+		{Value: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/120.0.0.0 Safari/537.36", Weight: 40},
+		{Value: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+			"Version/17.1 Mobile/15E148 Safari/604.1", Weight: 25},
+		{Value: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Weight: 5},
+		{Value: "curl/8.5.0", Weight: 15},
+		{Value: "python-requests/2.31.0", Weight: 15},
+	}
+)
+
+type accessLogger struct {
+	writer     io.Writer
+	header     Header
+	format     string
+	methods    *weightedPicker
+	paths      *weightedPicker
+	statuses   *weightedPicker
+	referrers  *weightedPicker
+	userAgents *weightedPicker
+	minBytes   int
+	maxBytes   int
+	network    *net.IPNet
+	prefix     bool
+	line       bytes.Buffer
+	buf        bytes.Buffer
+}
+
+// NewAccessLogger creates a Logger that prints the access logs of random HTTP requests in the format of the
+// given access log content type. The requests are drawn from the distributions in spec, which can be nil.
+// Unless spec.Prefix is true, the access logs are printed as they are, and the name, the sequence number and the
+// latency marker are carried in the query string of the request path, where ParseRecord finds them.
+func NewAccessLogger(writer io.Writer, format string, spec *model.AccessLogSpec, header Header) Logger {
+	if spec == nil {
+		spec = new(model.AccessLogSpec)
+	}
+	ret := &accessLogger{
+		writer:     writer,
+		header:     header.withDefaults(),
+		format:     format,
+		methods:    newWeightedPicker(spec.Methods, defaultAccessLogMethods),
+		paths:      newWeightedPicker(spec.Paths, defaultAccessLogPaths),
+		statuses:   newWeightedPicker(spec.Statuses, defaultAccessLogStatuses),
+		referrers:  newWeightedPicker(spec.Referrers, defaultAccessLogReferrers),
+		userAgents: newWeightedPicker(spec.UserAgents, defaultAccessLogUserAgents),
+		minBytes:   spec.MinBytes,
+		maxBytes:   spec.MaxBytes,
+		prefix:     spec.Prefix,
+	}
+	if ret.maxBytes == 0 {
+		ret.maxBytes = defaultAccessLogMaxBytes
+		if ret.maxBytes < ret.minBytes {
+			ret.maxBytes = ret.minBytes
+		}
+	}
+	network := spec.ClientNetwork
+	if len(network) == 0 {
+		network = defaultAccessLogClientNetwork
+	}
+	_, ret.network, _ = net.ParseCIDR(network)
+	return ret
+}
+
+func (al *accessLogger) Log() (time.Time, int, error) {
+	t, seq, level, prefix := al.header.sequencedPrefix()
+	method, status := al.methods.pick(), al.statuses.pick()
+	bytesSent := al.minBytes + rand.Intn(al.maxBytes-al.minBytes+1)
+	if method == "HEAD" || status == "204" || status == "304" {
+		bytesSent = 0
+	}
+	path := al.paths.pick()
+	if !al.prefix {
+		path = al.markPath(path, t, seq, level)
+	}
+	request := method + " " + path + " " + accessLogProtocol
+	al.line.Reset()
+	switch al.format {
+	case model.ContentTypeEnvoy:
+		al.writeEnvoy(t, request, method, status, bytesSent)
+	default:
+		size := strconv.Itoa(bytesSent)
+		if bytesSent == 0 && al.format != model.ContentTypeNginx {
+			size = "-"
+		}
+		fmt.Fprintf(&al.line, `%s - - [%s] "%s" %s %s`,
+			al.clientIP(), t.Format(apacheTimeFormat), request, status, size)
+		if al.format != model.ContentTypeApacheCommon {
+			fmt.Fprintf(&al.line, ` "%s" "%s"`, al.referrers.pick(), al.userAgents.pick())
+		}
+	}
+	al.buf.Reset()
+	if al.prefix {
+		al.buf.WriteString(prefix)
+		if al.header.Checksum {
+			al.buf.WriteString(checksumMarker(al.line.Bytes()))
+		}
+	}
+	al.buf.Write(al.line.Bytes())
+	al.buf.WriteByte('\n')
+	size, err := write(al.writer, al.buf.Bytes(), t, level)
+	return t, size, err
+}
+
+// markPath adds the name and the sequence number of the log, and the level and the latency marker if any, to the
+// query string of the request path.
+func (al *accessLogger) markPath(path string, t time.Time, seq int64, level string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	path += separator + queryTaskKey + "=" + url.QueryEscape(al.header.Name) +
+		"&" + querySequenceKey + "=" + strconv.FormatInt(seq, 10)
+	if len(level) > 0 {
+		path += "&" + queryLevelKey + "=" + level
+	}
+	if al.header.LatencyMarker {
+		path += "&" + querySentKey + "=" + strconv.FormatInt(t.UnixNano(), 10)
+	}
+	return path
+}
+
+// writeEnvoy writes an access log in the default format of Envoy, in which the response flags tell why the
+// upstream failed to respond and the upstream service time is missing if it did.
+func (al *accessLogger) writeEnvoy(t time.Time, request, method, status string, bytesSent int) {
+	flags, duration, upstreamTime := "-", 1+rand.Intn(500), "-"
+	switch status {
+	case "503":
+		flags = "UF"
+	case "504":
+		flags, duration = "UT", 15000
+	default:
+		upstreamTime = strconv.Itoa(duration - rand.Intn(duration))
+	}
+	bytesReceived := 0
+	if method == "POST" || method == "PUT" {
+		bytesReceived = rand.Intn(4096)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	fmt.Fprintf(&al.line,
+		`[%s] "%s" %s %s %d %d %d %s "%s" "%s" "%x-%x-%x-%x-%x" "%s" "%s"`,
+		t.UTC().Format(envoyTimeFormat), request, status, flags, bytesReceived, bytesSent, duration,
+		upstreamTime, al.clientIP(), al.userAgents.pick(), id[0:4], id[4:6], id[6:8], id[8:10], id[10:16],
+		al.header.Name, fmt.Sprintf("10.1.%d.%d:8080", rand.Intn(4), 2+rand.Intn(250)),
+	)
+}
+
+// clientIP returns a random address in the client network.
+func (al *accessLogger) clientIP() string {
+	ip := make(net.IP, len(al.network.IP))
+	for i := range ip {
+		ip[i] = al.network.IP[i] | byte(rand.Intn(256))&^al.network.Mask[i]
+	}
+	return ip.String()
+}
@@ -1,30 +1,32 @@
 package logger
 
 import (
-	"fmt"
 	"io"
 	"time"
 )
 
 type explicitLogger struct {
-	writer          io.Writer
-	name            string
-	msg             string
-	timestampFormat string
+	writer   io.Writer
+	header   Header
+	msg      string
+	checksum string
 }
 
 // NewExplicitLogger creates a Logger that prints a explicitly defined message.
-func NewExplicitLogger(writer io.Writer, msg, name string, timestampFormat string) Logger {
-	return &explicitLogger{
-		writer:          writer,
-		name:            name,
-		msg:             msg,
-		timestampFormat: timestampFormat,
+func NewExplicitLogger(writer io.Writer, msg string, header Header) Logger {
+	ret := &explicitLogger{
+		writer: writer,
+		header: header.withDefaults(),
+		msg:    msg,
 	}
+	if header.Checksum {
+		ret.checksum = checksumMarker([]byte(msg))
+	}
+	return ret
 }
 
 func (eg *explicitLogger) Log() (time.Time, int, error) {
-	t, prefix := getPrefix(eg.name, eg.timestampFormat)
-	size, err := eg.writer.Write([]byte(fmt.Sprintf("%s%s\n", prefix, eg.msg)))
+	t, level, prefix := eg.header.prefix()
+	size, err := write(eg.writer, []byte(prefix+eg.checksum+eg.msg+"\n"), t, level)
 	return t, size, err
 }
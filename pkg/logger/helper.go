@@ -2,18 +2,191 @@ package logger
 
 import (
 	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync/atomic"
 	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
 )
 
-func getPrefix(name string, timestampFormat string) (time.Time, string) {
-	t, timestamp := getTimestamp(timestampFormat)
+// checksumSize is the size of the checksum marker, "crc=" followed by 8 hexadecimal digits and a space.
+const checksumSize = 13
+
+// maxSequenceSize is the size of the largest sequence marker, "seq=" followed by 19 digits and a space.
+const maxSequenceSize = 24
+
+// maxLevelSize is the size of the longest level followed by a space.
+const maxLevelSize = 6
+
+// sentMarkerSize is the size of the latency marker, "sent=" followed by 19 digits and a space.
+const sentMarkerSize = 25
+
+// Header defines the part that all the log messages share, which identifies the task producing the log and the
+// position of the log in that task. The text Loggers put it in front of every log message in the format of
+// "<timestamp> <level> [<name>] seq=<sequence> sent=<nanoseconds> crc=<checksum> ", where the checksum is that of
+// the rest of the line; the level is omitted if Levels is nil, the latency marker is omitted if LatencyMarker is
+// false, and the checksum marker is omitted if Checksum is false.
+type Header struct {
+	// Name is the name of the task producing the logs.
+	Name string
+
+	// TimestampFormat is the format of the timestamp; the timestamp is omitted if TimestampFormat is empty.
+	TimestampFormat string
+
+	// Sequence generates the sequence numbers of the logs; the Logger creates its own if Sequence is nil.
+	Sequence *Sequence
+
+	// Checksum determines whether a checksum of the payload should be included in every log.
+	Checksum bool
+
+	// Levels picks the level of every log; the logs have no level if Levels is nil.
+	Levels *Levels
+
+	// LatencyMarker determines whether the time of the log, in nanoseconds since the Unix epoch, should be included
+	// in every log.
+	LatencyMarker bool
+}
+
+// Levels picks the levels of the logs at random according to their weights.
+type Levels struct {
+	picker *weightedPicker
+	record func(level string)
+}
+
+// NewLevels creates Levels with the given weights, keyed by model.LogLevels; record, if not nil, is called with
+// every level picked. It returns nil if there are no weights.
+func NewLevels(weights map[string]float64, record func(level string)) *Levels {
+	if len(weights) == 0 {
+		return nil
+	}
+	values := make([]model.WeightedValue, 0, len(weights))
+	for _, level := range model.LogLevels {
+		if weight, ok := weights[level]; ok {
+			values = append(values, model.WeightedValue{Value: level, Weight: weight})
+		}
+	}
+	return &Levels{
+		picker: newWeightedPicker(values, nil),
+		record: record,
+	}
+}
+
+func (l *Levels) pick() string {
+	ret := l.picker.pick()
+	if l.record != nil {
+		l.record(ret)
+	}
+	return ret
+}
+
+// Sequence generates monotonically increasing sequence numbers starting from 1. It is safe for concurrent use
+// and can be shared by Loggers so that the sequence continues when a Logger is replaced.
+type Sequence struct {
+	last int64
+}
+
+// Next returns the next sequence number.
+func (s *Sequence) Next() int64 {
+	return atomic.AddInt64(&s.last, 1)
+}
+
+// Last returns the sequence number last returned by Next, or 0 if Next has never been called.
+func (s *Sequence) Last() int64 {
+	return atomic.LoadInt64(&s.last)
+}
+
+// withDefaults returns a copy of the Header with a new Sequence if none was given.
+func (h Header) withDefaults() Header {
+	if h.Sequence == nil {
+		h.Sequence = new(Sequence)
+	}
+	return h
+}
+
+// prefix returns the time and the level of the log, and the prefix up to and including the sequence marker, or the
+// latency marker if there is one. The level is empty if there are no Levels.
+func (h *Header) prefix() (time.Time, string, string) {
+	t, _, level, prefix := h.sequencedPrefix()
+	return t, level, prefix
+}
+
+// sequencedPrefix is like prefix but also returns the sequence number of the log.
+func (h *Header) sequencedPrefix() (time.Time, int64, string, string) {
+	t, timestamp := getTimestamp(h.TimestampFormat)
+	seq := h.Sequence.Next()
+	var level, levelField string
+	if h.Levels != nil {
+		level = h.Levels.pick()
+		levelField = level + " "
+	}
+	var sent string
+	if h.LatencyMarker {
+		sent = "sent=" + strconv.FormatInt(t.UnixNano(), 10) + " "
+	}
 	if len(timestamp) > 0 {
-		return t, fmt.Sprintf("%s [%s] ", timestamp, name)
+		return t, seq, level, fmt.Sprintf("%s %s[%s] seq=%d %s", timestamp, levelField, h.Name, seq, sent)
+	}
+	return t, seq, level, fmt.Sprintf("%s[%s] seq=%d %s", levelField, h.Name, seq, sent)
+}
+
+// maxPrefixSize returns the largest possible size of the prefix, including the checksum marker.
+func (h *Header) maxPrefixSize() int {
+	ret := len(h.TimestampFormat) + len(" [] ") + len(h.Name) + maxSequenceSize
+	if h.Checksum {
+		ret += checksumSize
+	}
+	if h.Levels != nil {
+		ret += maxLevelSize
+	}
+	if h.LatencyMarker {
+		ret += sentMarkerSize
 	}
-	return t, fmt.Sprintf("[%s] ", name)
+	return ret
 }
 
 func getTimestamp(timestampFormat string) (time.Time, string) {
 	now := time.Now().UTC()
 	return now, now.Format(timestampFormat)
 }
+
+func checksum(payload []byte) uint32 {
+	return crc32.ChecksumIEEE(payload)
+}
+
+func checksumMarker(payload []byte) string {
+	return fmt.Sprintf("crc=%08x ", checksum(payload))
+}
+
+// weightedPicker picks values with probabilities proportional to their weights.
+type weightedPicker struct {
+	values     []string
+	cumulative []float64
+}
+
+// newWeightedPicker creates a weightedPicker of the given values, or of the defaults if there are none.
+func newWeightedPicker(values, defaults []model.WeightedValue) *weightedPicker {
+	if len(values) == 0 {
+		values = defaults
+	}
+	ret := &weightedPicker{
+		values:     make([]string, len(values)),
+		cumulative: make([]float64, len(values)),
+	}
+	var total float64
+	for i := range values {
+		total += values[i].Weight
+		ret.values[i] = values[i].Value
+		ret.cumulative[i] = total
+	}
+	return ret
+}
+
+func (wp *weightedPicker) pick() string {
+	target := rand.Float64() * wp.cumulative[len(wp.cumulative)-1]
+	return wp.values[sort.Search(len(wp.cumulative)-1, func(i int) bool {
+		return wp.cumulative[i] > target
+	})]
+}
@@ -1,6 +1,9 @@
 package logger
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // Logger generates log message in various ways one at a time.
 type Logger interface {
@@ -8,3 +11,18 @@ type Logger interface {
 	// any occurred.
 	Log() (time.Time, int, error)
 }
+
+// TimedWriter is implemented by the writers that need the time or the level of every log, such as the outputs that
+// send them in fields of their own. The Loggers call WriteTimed rather than Write on such a writer with the time
+// returned by Log and the level picked by the Levels of the Header, or an empty level if there are no Levels.
+type TimedWriter interface {
+	WriteTimed(p []byte, t time.Time, level string) (int, error)
+}
+
+// write writes a log created at the given time at the given level to w, passing them along if w is a TimedWriter.
+func write(w io.Writer, p []byte, t time.Time, level string) (int, error) {
+	if tw, ok := w.(TimedWriter); ok {
+		return tw.WriteTimed(p, t, level)
+	}
+	return w.Write(p)
+}
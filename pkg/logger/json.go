@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const jsonLevel = "INFO"
+
+// jsonChecksumSize is the size of the checksum field, `,"crc":"` followed by 8 hexadecimal digits and `"`.
+const jsonChecksumSize = 17
+
+type jsonLogger struct {
+	writer     io.Writer
+	header     Header
+	size       int
+	fields     []jsonField
+	rawMessage []byte
+	message    []byte
+	buffer     bytes.Buffer
+	randBuffer []byte
+}
+
+// jsonField is an extra field with its key already encoded.
+type jsonField struct {
+	key   []byte
+	spec  model.FieldSpec
+	value []byte
+}
+
+// NewJSONLogger creates a Logger that prints one JSON object per line. Besides the timestamp, level, task name,
+// sequence number and, if the Header asks for it, the time of the log, every object carries the given extra fields
+// and a message field that starts with msg and is padded with random characters so that the log is no smaller than
+// the minimal size. If the Header asks for a checksum, it is that of the message field and is put after it.
+func NewJSONLogger(writer io.Writer, msg string, size int, fields []model.FieldSpec, header Header) Logger {
+	ret := &jsonLogger{
+		writer:     writer,
+		header:     header.withDefaults(),
+		size:       size,
+		fields:     make([]jsonField, len(fields)),
+		rawMessage: []byte(msg),
+	}
+	for i := range fields {
+		ret.fields[i] = jsonField{
+			key:  append(encodeJSONString(fields[i].Name), ':'),
+			spec: fields[i],
+		}
+		if fields[i].Kind == model.FieldKindConstant {
+			ret.fields[i].value = encodeJSONString(fields[i].Value)
+		}
+	}
+	message := encodeJSONString(msg)
+	ret.message = message[:len(message)-1]
+	return ret
+}
+
+func (jl *jsonLogger) Log() (time.Time, int, error) {
+	t, timestamp := getTimestamp(jl.header.TimestampFormat)
+	buf := &jl.buffer
+	buf.Reset()
+	buf.WriteByte('{')
+	if len(timestamp) > 0 {
+		writeJSONKey(buf, model.JSONKeyTimestamp)
+		buf.Write(encodeJSONString(timestamp))
+		buf.WriteByte(',')
+	}
+	var level string
+	if jl.header.Levels != nil {
+		level = jl.header.Levels.pick()
+	}
+	writeJSONKey(buf, model.JSONKeyLevel)
+	if len(level) > 0 {
+		buf.Write(encodeJSONString(level))
+	} else {
+		buf.Write(encodeJSONString(jsonLevel))
+	}
+	buf.WriteByte(',')
+	writeJSONKey(buf, model.JSONKeyTask)
+	buf.Write(encodeJSONString(jl.header.Name))
+	buf.WriteByte(',')
+	writeJSONKey(buf, model.JSONKeySequence)
+	buf.WriteString(strconv.FormatInt(jl.header.Sequence.Next(), 10))
+	if jl.header.LatencyMarker {
+		buf.WriteByte(',')
+		writeJSONKey(buf, model.JSONKeySent)
+		buf.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+	}
+	for i := range jl.fields {
+		buf.WriteByte(',')
+		buf.Write(jl.fields[i].key)
+		jl.writeFieldValue(buf, &jl.fields[i])
+	}
+	buf.WriteByte(',')
+	writeJSONKey(buf, model.JSONKeyMessage)
+	buf.Write(jl.message)
+	var padding []byte
+	paddingSize := jl.size - buf.Len() - len("\"}\n")
+	if jl.header.Checksum {
+		paddingSize -= jsonChecksumSize
+	}
+	if paddingSize > 0 {
+		start := buf.Len()
+		jl.writeRandomHex(buf, paddingSize)
+		padding = buf.Bytes()[start:]
+	}
+	buf.WriteByte('"')
+	if jl.header.Checksum {
+		sum := crc32.Update(checksum(jl.rawMessage), crc32.IEEETable, padding)
+		buf.WriteByte(',')
+		writeJSONKey(buf, model.JSONKeyChecksum)
+		fmt.Fprintf(buf, "\"%08x\"", sum)
+	}
+	buf.WriteString("}\n")
+	size, err := write(jl.writer, buf.Bytes(), t, level)
+	return t, size, err
+}
+
+func (jl *jsonLogger) writeFieldValue(buf *bytes.Buffer, field *jsonField) {
+	switch field.spec.Kind {
+	case model.FieldKindConstant:
+		buf.Write(field.value)
+	case model.FieldKindPick:
+		buf.Write(encodeJSONString(field.spec.Values[rand.Intn(len(field.spec.Values))]))
+	case model.FieldKindRandomString:
+		buf.WriteByte('"')
+		jl.writeRandomHex(buf, field.spec.Size)
+		buf.WriteByte('"')
+	case model.FieldKindRandomInt:
+		value := field.spec.Min + rand.Int63n(field.spec.Max-field.spec.Min+1)
+		buf.WriteString(strconv.FormatInt(value, 10))
+	default:
+		buf.WriteString("null")
+	}
+}
+
+// writeRandomHex writes the given number of random hexadecimal characters to the buffer.
+func (jl *jsonLogger) writeRandomHex(buf *bytes.Buffer, size int) {
+	rawSize := (size + 1) / 2
+	if cap(jl.randBuffer) < rawSize*3 {
+		jl.randBuffer = make([]byte, rawSize*3)
+	}
+	raw, encoded := jl.randBuffer[:rawSize], jl.randBuffer[rawSize:rawSize*3]
+	rand.Read(raw)
+	hex.Encode(encoded, raw)
+	buf.Write(encoded[:size])
+}
+
+func writeJSONKey(buf *bytes.Buffer, key string) {
+	buf.Write(encodeJSONString(key))
+	buf.WriteByte(':')
+}
+
+// encodeJSONString returns the given string as a quoted JSON string.
+func encodeJSONString(s string) []byte {
+	ret, _ := json.Marshal(s)
+	return ret
+}
@@ -2,9 +2,17 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/lichuan0620/logtap/pkg/fieldpath"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
 )
 
 func TestExplicitLogger_Log(t *testing.T) {
@@ -41,7 +49,7 @@ func TestExplicitLogger_Log(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			writer := new(bytes.Buffer)
-			logger := NewExplicitLogger(writer, tc.msg, tc.name, tc.format)
+			logger := NewExplicitLogger(writer, tc.msg, Header{Name: tc.name, TimestampFormat: tc.format})
 			for i := 0; i < repeats; i++ {
 				writer.Reset()
 				ti, _, err := logger.Log()
@@ -52,7 +60,7 @@ func TestExplicitLogger_Log(t *testing.T) {
 				if len(timestamp) > 0 {
 					timestamp += " "
 				}
-				want := fmt.Sprintf("%s[%s] %s\n", timestamp, tc.name, tc.msg)
+				want := fmt.Sprintf("%s[%s] seq=%d %s\n", timestamp, tc.name, i+1, tc.msg)
 				if want != writer.String() {
 					t.Fatalf(`unexpected content: want "%s"; got "%s"`, want, writer.String())
 				}
@@ -92,7 +100,7 @@ func TestRandomLogger_Log(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			writer := new(bytes.Buffer)
-			logger := NewRandomLogger(writer, tc.size, tc.name, tc.format)
+			logger := NewRandomLogger(writer, tc.size, Header{Name: tc.name, TimestampFormat: tc.format})
 			randStrings := make([]string, 0, repeats)
 			for i := 0; i < repeats; i++ {
 				writer.Reset()
@@ -133,10 +141,378 @@ func BenchmarkRandomLogger_Log(b *testing.B) {
 	)
 	writer := new(bytes.Buffer)
 	writer.Grow(size)
-	logger := NewRandomLogger(writer, size, "Benchmark", time.RFC3339)
+	logger := NewRandomLogger(writer, size, Header{Name: "Benchmark", TimestampFormat: time.RFC3339})
 	b.ResetTimer()
 	for i := 0; i < count; i++ {
 		writer.Reset()
 		logger.Log()
 	}
 }
+
+func TestJSONLogger_Log(t *testing.T) {
+	const repeats = 3
+	fields := []model.FieldSpec{
+		{Name: "env", Kind: model.FieldKindConstant, Value: "prod"},
+		{Name: "status", Kind: model.FieldKindPick, Values: []string{"200", "404"}},
+		{Name: "id", Kind: model.FieldKindRandomString, Size: 7},
+		{Name: "latency", Kind: model.FieldKindRandomInt, Min: -5, Max: 5},
+	}
+	testCases := []struct {
+		name   string
+		msg    string
+		size   int
+		format string
+	}{
+		{"Basic", "hello", 512, time.RFC3339},
+		{"NoPadding", "hello", 0, time.RFC3339},
+		{"EscapedMessage", "\"quoted\"\n", 256, time.RFC3339},
+		{"EmptyTimestamp", "", 256, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := new(bytes.Buffer)
+			logger := NewJSONLogger(writer, tc.msg, tc.size, fields, Header{Name: tc.name, TimestampFormat: tc.format})
+			for i := 1; i <= repeats; i++ {
+				writer.Reset()
+				ti, size, err := logger.Log()
+				if err != nil {
+					t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+				}
+				if size < tc.size || size != writer.Len() {
+					t.Fatalf("unexpected size: want at least %d; got %d", tc.size, size)
+				}
+				if last := writer.Bytes()[writer.Len()-1]; last != '\n' {
+					t.Fatalf("log does not end with a new line")
+				}
+				var got map[string]interface{}
+				if err = json.Unmarshal(writer.Bytes(), &got); err != nil {
+					t.Fatalf("invalid JSON %q: %s", writer.String(), err.Error())
+				}
+				var timestamp interface{}
+				if len(tc.format) > 0 {
+					timestamp = ti.Format(tc.format)
+				}
+				if got[model.JSONKeyTimestamp] != timestamp {
+					t.Fatalf("unexpected timestamp: want %v; got %v", timestamp, got[model.JSONKeyTimestamp])
+				}
+				if got[model.JSONKeyTask] != tc.name || got[model.JSONKeySequence] != float64(i) {
+					t.Fatalf("unexpected task or sequence: %v", got)
+				}
+				if message, _ := got[model.JSONKeyMessage].(string); !strings.HasPrefix(message, tc.msg) {
+					t.Fatalf("unexpected message: want prefix %q; got %q", tc.msg, message)
+				}
+				if got["env"] != "prod" || (got["status"] != "200" && got["status"] != "404") {
+					t.Fatalf("unexpected field values: %v", got)
+				}
+				if id, _ := got["id"].(string); len(id) != 7 {
+					t.Fatalf("unexpected random string field: %v", got["id"])
+				}
+				if latency, _ := got["latency"].(float64); latency < -5 || latency > 5 {
+					t.Fatalf("unexpected random int field: %v", got["latency"])
+				}
+			}
+		})
+	}
+}
+
+func TestMultilineLogger_Log(t *testing.T) {
+	const repeats = 3
+	markers := map[string]string{
+		model.MultilineKindJava:   "\tat ",
+		model.MultilineKindPython: "Traceback (most recent call last):",
+		model.MultilineKindGo:     "goroutine 1 [running]:",
+	}
+	for kind, marker := range markers {
+		for _, repeatPrefix := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/RepeatPrefix=%t", kind, repeatPrefix), func(t *testing.T) {
+				writer := new(bytes.Buffer)
+				spec := &model.MultilineSpec{
+					Kinds:        []string{kind},
+					Frequency:    1,
+					MinDepth:     2,
+					MaxDepth:     4,
+					RepeatPrefix: repeatPrefix,
+				}
+				logger := NewMultilineLogger(writer, "", spec, Header{Name: "test", Checksum: true})
+				for i := 1; i <= repeats; i++ {
+					writer.Reset()
+					if _, _, err := logger.Log(); err != nil {
+						t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+					}
+					event := strings.TrimSuffix(writer.String(), "\n")
+					lines := strings.Split(event, "\n")
+					if len(lines) < 4 || !strings.Contains(event, marker) {
+						t.Fatalf("unexpected %s event: %q", kind, event)
+					}
+					if !strings.HasSuffix(lines[0], defaultMultilineMessage) {
+						t.Fatalf("unexpected first line: %q", lines[0])
+					}
+					record, ok := ParseRecord([]byte(event))
+					if !ok || record.Sequence != int64(i) || record.Corrupted {
+						t.Fatalf("unexpected record of the merged event: %+v", record)
+					}
+					if record, _ = ParseRecord([]byte(lines[0])); !record.Corrupted {
+						t.Fatal("unmerged event is not corrupted")
+					}
+					prefix := fmt.Sprintf("[test] seq=%d ", i)
+					for _, line := range lines[1:] {
+						if strings.HasPrefix(line, prefix) != repeatPrefix {
+							t.Fatalf("unexpected continuation line: %q", line)
+						}
+					}
+				}
+			})
+		}
+	}
+	t.Run("DefaultMinDepth", func(t *testing.T) {
+		spec := &model.MultilineSpec{Frequency: 1, MaxDepth: 3}
+		if err := model.ValidateMultilineSpec(fieldpath.NewFieldPath("multiline"), spec); err == nil {
+			t.Fatal("maxDepth below the default minDepth accepted")
+		}
+		writer := new(bytes.Buffer)
+		logger := NewMultilineLogger(writer, "", spec, Header{Name: "test"})
+		for i := 1; i <= repeats; i++ {
+			if _, _, err := logger.Log(); err != nil {
+				t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+			}
+		}
+	})
+}
+
+func TestAccessLogger_Log(t *testing.T) {
+	const repeats = 3
+	testCases := map[string]string{
+		model.ContentTypeApacheCommon: `^192\.168\.7\.\d+ - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+			`"POST %s HTTP/1\.1" 503 \d{2}$`,
+		model.ContentTypeApacheCombined: `^192\.168\.7\.\d+ - - \[[^]]+\] "POST %s HTTP/1\.1" 503 \d{2} ` +
+			`"[^"]+" "test-agent"$`,
+		model.ContentTypeNginx: `^192\.168\.7\.\d+ - - \[[^]]+\] "POST %s HTTP/1\.1" 503 \d{2} ` +
+			`"[^"]+" "test-agent"$`,
+		model.ContentTypeEnvoy: `^\[\d{4}-\d{2}-\d{2}T[\d:.]+Z\] "POST %s HTTP/1\.1" 503 UF \d+ \d{2} \d+ - ` +
+			`"192\.168\.7\.\d+" "test-agent" "[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}" "test/0" "[\d.:]+"$`,
+	}
+	for format, pattern := range testCases {
+		for _, prefixed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/Prefix=%t", format, prefixed), func(t *testing.T) {
+				spec := &model.AccessLogSpec{
+					Prefix:        prefixed,
+					Methods:       []model.WeightedValue{{Value: "POST", Weight: 1}},
+					Paths:         []model.WeightedValue{{Value: "/a", Weight: 0}, {Value: "/b", Weight: 1}},
+					Statuses:      []model.WeightedValue{{Value: "503", Weight: 1}},
+					UserAgents:    []model.WeightedValue{{Value: "test-agent", Weight: 1}},
+					MinBytes:      10,
+					MaxBytes:      99,
+					ClientNetwork: "192.168.7.0/24",
+				}
+				writer := new(bytes.Buffer)
+				header := Header{Name: "test/0", Checksum: prefixed, LatencyMarker: !prefixed}
+				logger := NewAccessLogger(writer, format, spec, header)
+				for i := 1; i <= repeats; i++ {
+					writer.Reset()
+					if _, _, err := logger.Log(); err != nil {
+						t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+					}
+					line := strings.TrimSuffix(writer.String(), "\n")
+					record, ok := ParseRecord([]byte(line))
+					if !ok || record.Name != "test/0" || record.Sequence != int64(i) || record.Corrupted ||
+						record.Sent.IsZero() == !prefixed {
+						t.Fatalf("unexpected record: %+v", record)
+					}
+					if prefixed {
+						prefix := fmt.Sprintf("[test/0] seq=%d crc=", i)
+						if !strings.HasPrefix(line, prefix) {
+							t.Fatalf("unexpected %s access log: %q", format, line)
+						}
+						line = line[len(prefix)+checksumSize-len("crc="):]
+					}
+					path := "/b"
+					if !prefixed {
+						path = fmt.Sprintf(`/b\?logtap_task=test%%2F0&logtap_seq=%d&logtap_sent=\d+`, i)
+					}
+					if !regexp.MustCompile(fmt.Sprintf(pattern, path)).MatchString(line) {
+						t.Fatalf("unexpected %s access log: %q", format, line)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTemplateLogger_Log(t *testing.T) {
+	const repeats = 3
+	text := `{{seq}} {{.Task}} {{now "2006"}} {{uuid}} {{randInt 1 3}} {{printf "%.1f" (randFloat 0 1)}} ` +
+		`{{pick "a" "b"}} {{words 2}} {{ipv4}} {{ipv6}} {{email}} {{username}} {{duration "1s" "2s"}}`
+	writer := new(bytes.Buffer)
+	logger, err := NewTemplateLogger(writer, text, Header{Name: "test", Checksum: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := 1; i <= repeats; i++ {
+		writer.Reset()
+		ti, _, err := logger.Log()
+		if err != nil {
+			t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+		}
+		line := strings.TrimSuffix(writer.String(), "\n")
+		if record, ok := ParseRecord([]byte(line)); !ok || record.Sequence != int64(i) || record.Corrupted {
+			t.Fatalf("unexpected record: %+v", record)
+		}
+		pattern := regexp.MustCompile(fmt.Sprintf(
+			`^\[test\] seq=%d crc=[0-9a-f]{8} %d test %d [0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-`+
+				`[0-9a-f]{12} [1-3] [01]\.\d [ab] [a-z]+ [a-z]+ (\d+\.){3}\d+ [0-9a-f:]+ [a-z.]+\d+@[a-z.]+ `+
+				`[a-z.]+\d+ 1(\.\d+)?s$`,
+			i, i, ti.Year(),
+		))
+		if !pattern.MatchString(line) {
+			t.Fatalf("unexpected content: %q", line)
+		}
+	}
+	if _, err = NewTemplateLogger(writer, "{{unknown}}", Header{}); err == nil {
+		t.Fatal("invalid template accepted")
+	}
+	spec := &model.LogTaskSpec{OutputKind: model.OutputKindStdOut, ContentType: model.ContentTypeTemplate,
+		Message: "{{unknown}}"}
+	if err = model.ValidateLogTaskSpec(fieldpath.NewFieldPath("spec"), spec); err == nil {
+		t.Fatal("invalid template passed validation")
+	}
+	logger, _ = NewTemplateLogger(writer, "{{randInt 3 1}}", Header{})
+	if _, _, err = logger.Log(); err == nil {
+		t.Fatal("invalid range accepted")
+	}
+	// the validation only knows the functions by their names
+	var names []string
+	for name := range messageTemplateFuncs(new(templateData)) {
+		names = append(names, name)
+	}
+	expected := append([]string(nil), model.MessageTemplateFunctions...)
+	sort.Strings(names)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("unexpected template functions: want %v; got %v", expected, names)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	const repeats = 100
+	counts := make(map[string]int)
+	levels := NewLevels(map[string]float64{"DEBUG": 0, "WARN": 1, "ERROR": 3}, func(level string) {
+		counts[level]++
+	})
+	writer := new(bytes.Buffer)
+	header := Header{Name: "test", TimestampFormat: time.RFC3339, Levels: levels}
+	logger := NewExplicitLogger(writer, "message", header)
+	jsonLogger := NewJSONLogger(writer, "message", 0, nil, header)
+	accessLogger := NewAccessLogger(writer, model.ContentTypeNginx, &model.AccessLogSpec{}, header)
+	for i := 1; i <= repeats; i++ {
+		writer.Reset()
+		logger.Log()
+		line := strings.TrimSuffix(writer.String(), "\n")
+		if !regexp.MustCompile(`^\S+ (WARN|ERROR) \[test\] `).MatchString(line) {
+			t.Fatalf("unexpected level: %q", line)
+		}
+		checkRecordLevel(t, line)
+		writer.Reset()
+		jsonLogger.Log()
+		var got map[string]interface{}
+		if err := json.Unmarshal(writer.Bytes(), &got); err != nil {
+			t.Fatal(err.Error())
+		}
+		if got[model.JSONKeyLevel] != "WARN" && got[model.JSONKeyLevel] != "ERROR" {
+			t.Fatalf("unexpected level: %v", got[model.JSONKeyLevel])
+		}
+		checkRecordLevel(t, strings.TrimSuffix(writer.String(), "\n"))
+		writer.Reset()
+		accessLogger.Log()
+		line = strings.TrimSuffix(writer.String(), "\n")
+		if !regexp.MustCompile(`&logtap_level=(WARN|ERROR) `).MatchString(line) {
+			t.Fatalf("unexpected level: %q", line)
+		}
+		checkRecordLevel(t, line)
+	}
+	if len(counts) != 2 || counts["WARN"]+counts["ERROR"] != 3*repeats || counts["ERROR"] < counts["WARN"] {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	if NewLevels(nil, nil) != nil {
+		t.Fatal("levels created without weights")
+	}
+}
+
+// checkRecordLevel checks that the level parsed from the line is either WARN or ERROR.
+func checkRecordLevel(t *testing.T, line string) {
+	record, ok := ParseRecord([]byte(line))
+	if !ok || (record.Level != "WARN" && record.Level != "ERROR") {
+		t.Fatalf("unexpected record %+v parsed from %q", record, line)
+	}
+}
+
+func TestLatencyMarker(t *testing.T) {
+	header := Header{Name: "test", Checksum: true, LatencyMarker: true}
+	newLoggers := map[string]func(w *bytes.Buffer) Logger{
+		"Explicit": func(w *bytes.Buffer) Logger { return NewExplicitLogger(w, "message", header) },
+		"JSON":     func(w *bytes.Buffer) Logger { return NewJSONLogger(w, "message", 64, nil, header) },
+		"Access": func(w *bytes.Buffer) Logger {
+			return NewAccessLogger(w, model.ContentTypeNginx, nil, header)
+		},
+	}
+	for name, newLogger := range newLoggers {
+		t.Run(name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			logged, _, err := newLogger(w).Log()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			record, ok := ParseRecord(bytes.TrimSuffix(w.Bytes(), []byte("\n")))
+			if !ok || record.Name != "test" || record.Sequence != 1 || record.Corrupted {
+				t.Fatalf("unexpected record %+v parsed from %q", record, w.String())
+			}
+			if !record.Sent.Equal(logged) {
+				t.Fatalf("unexpected sent time: want %s; got %s", logged, record.Sent)
+			}
+		})
+	}
+}
+
+// timedBuffer is a TimedWriter that records the times it is given.
+type timedBuffer struct {
+	bytes.Buffer
+	times []time.Time
+}
+
+func (b *timedBuffer) WriteTimed(p []byte, t time.Time, _ string) (int, error) {
+	b.times = append(b.times, t)
+	return b.Write(p)
+}
+
+func TestLogger_TimedWriter(t *testing.T) {
+	header := Header{Name: "test", TimestampFormat: time.RFC3339Nano}
+	newLoggers := map[string]func(w *timedBuffer) Logger{
+		"Explicit": func(w *timedBuffer) Logger { return NewExplicitLogger(w, "message", header) },
+		"Random":   func(w *timedBuffer) Logger { return NewRandomLogger(w, 64, header) },
+		"JSON":     func(w *timedBuffer) Logger { return NewJSONLogger(w, "message", 64, nil, header) },
+		"Template": func(w *timedBuffer) Logger {
+			logger, _ := NewTemplateLogger(w, "{{uuid}}", header)
+			return logger
+		},
+		"Access": func(w *timedBuffer) Logger {
+			return NewAccessLogger(w, model.ContentTypeApacheCombined, &model.AccessLogSpec{Prefix: true}, header)
+		},
+		"Multiline": func(w *timedBuffer) Logger {
+			return NewMultilineLogger(w, "message", &model.MultilineSpec{Frequency: 1}, header)
+		},
+	}
+	for name, newLogger := range newLoggers {
+		t.Run(name, func(t *testing.T) {
+			w := new(timedBuffer)
+			logged, _, err := newLogger(w).Log()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if len(w.times) != 1 || !w.times[0].Equal(logged) {
+				t.Fatalf("unexpected times: want [%s]; got %v", logged, w.times)
+			}
+			if !strings.Contains(w.String(), logged.Format(time.RFC3339Nano)) {
+				t.Fatalf("timestamp %s not in log: %s", logged.Format(time.RFC3339Nano), w.String())
+			}
+		})
+	}
+}
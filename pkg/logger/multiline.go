@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultMultilineMessage = "request failed"
+)
+
+var (
+	javaFrames = []string{
+		"com.example.orders.OrderService.process",
+		"com.example.orders.OrderController.create",
+		"com.example.payments.PaymentClient.charge",
+		"com.example.common.RetryTemplate.execute",
+		"org.springframework.web.servlet.FrameworkServlet.service",
+		"org.springframework.web.servlet.DispatcherServlet.doDispatch",
+		"org.apache.catalina.core.ApplicationFilterChain.doFilter",
+		"org.apache.catalina.core.StandardWrapperValve.invoke",
+		"java.util.concurrent.ThreadPoolExecutor.runWorker",
+		"java.lang.Thread.run",
+	}
+	javaExceptions = []string{
+		"java.lang.IllegalStateException: order is not in a payable state",
+		"java.lang.NullPointerException: Cannot invoke \"String.length()\" because \"name\" is null",
+		"java.util.concurrent.TimeoutException: request timed out after 30000 ms",
+		"java.lang.IllegalArgumentException: quantity must be positive",
+	}
+	javaCauses = []string{
+		"java.io.IOException: Connection reset by peer",
+		"java.net.SocketTimeoutException: Read timed out",
+		"java.sql.SQLTransientConnectionException: HikariPool-1 - Connection is not available",
+	}
+
+	pythonFrames = [][3]string{
+		{"/app/orders/service.py", "process", "result = self.handler(request)"},
+		{"/app/orders/views.py", "create_order", "order = service.process(payload)"},
+		{"/app/payments/client.py", "charge", "response = self.session.post(url, json=body)"},
+		{"/usr/lib/python3/site-packages/requests/sessions.py", "send", "r = adapter.send(request, **kwargs)"},
+		{"/usr/lib/python3/site-packages/flask/app.py", "dispatch_request", "return view(**req.view_args)"},
+		{"/usr/lib/python3/site-packages/flask/app.py", "full_dispatch_request", "rv = self.dispatch_request()"},
+	}
+	pythonErrors = []string{
+		"ValueError: invalid literal for int() with base 10: 'abc'",
+		"KeyError: 'user_id'",
+		"ConnectionError: HTTPConnectionPool(host='payments', port=80): Max retries exceeded",
+		"TypeError: 'NoneType' object is not subscriptable",
+	}
+
+	goFrames = [][2]string{
+		{"main.(*Server).handle", "/app/server.go"},
+		{"main.(*OrderService).Process", "/app/orders.go"},
+		{"main.(*PaymentClient).Charge", "/app/payments.go"},
+		{"net/http.HandlerFunc.ServeHTTP", "/usr/local/go/src/net/http/server.go"},
+		{"net/http.serverHandler.ServeHTTP", "/usr/local/go/src/net/http/server.go"},
+		{"net/http.(*conn).serve", "/usr/local/go/src/net/http/server.go"},
+		{"encoding/json.(*decodeState).object", "/usr/local/go/src/encoding/json/decode.go"},
+	}
+	goPanics = []string{
+		"runtime error: index out of range [5] with length 3",
+		"assignment to entry in nil map",
+		"runtime error: slice bounds out of range [:12] with capacity 8",
+	}
+	goNilPanic    = "runtime error: invalid memory address or nil pointer dereference"
+	goWaitReasons = []string{"chan receive", "IO wait", "select", "semacquire"}
+)
+
+type multilineLogger struct {
+	writer   io.Writer
+	header   Header
+	msg      string
+	kinds    []string
+	spec     *model.MultilineSpec
+	minDepth int
+	maxDepth int
+	lines    []string
+	body     bytes.Buffer
+	buf      bytes.Buffer
+}
+
+// NewMultilineLogger creates a Logger that prints the given message, and sometimes follows it with a stack trace
+// that spans many lines. Every line of the trace after the first one starts with the prefix of the log if
+// spec.RepeatPrefix is true; the checksum, if any, covers all the lines.
+func NewMultilineLogger(writer io.Writer, msg string, spec *model.MultilineSpec, header Header) Logger {
+	ret := &multilineLogger{
+		writer:   writer,
+		header:   header.withDefaults(),
+		msg:      msg,
+		kinds:    spec.Kinds,
+		spec:     spec,
+		minDepth: spec.MinDepth,
+		maxDepth: spec.MaxDepth,
+	}
+	if len(ret.msg) == 0 {
+		ret.msg = defaultMultilineMessage
+	}
+	if len(ret.kinds) == 0 {
+		ret.kinds = []string{model.MultilineKindJava, model.MultilineKindPython, model.MultilineKindGo}
+	}
+	if ret.minDepth == 0 {
+		ret.minDepth = model.MultilineDefaultMinDepth
+	}
+	if ret.maxDepth == 0 {
+		ret.maxDepth = model.MultilineDefaultMaxDepth
+	}
+	if ret.maxDepth < ret.minDepth {
+		ret.maxDepth = ret.minDepth
+	}
+	return ret
+}
+
+func (ml *multilineLogger) Log() (time.Time, int, error) {
+	t, level, prefix := ml.header.prefix()
+	ml.lines = append(ml.lines[:0], ml.msg)
+	if rand.Float64() < ml.spec.Frequency {
+		switch ml.kinds[rand.Intn(len(ml.kinds))] {
+		case model.MultilineKindJava:
+			ml.appendJavaTrace()
+		case model.MultilineKindPython:
+			ml.appendPythonTrace()
+		default:
+			ml.appendGoTrace()
+		}
+	}
+	ml.body.Reset()
+	for i, line := range ml.lines {
+		if i > 0 {
+			ml.body.WriteByte('\n')
+			if ml.spec.RepeatPrefix {
+				ml.body.WriteString(prefix)
+			}
+		}
+		ml.body.WriteString(line)
+	}
+	ml.buf.Reset()
+	ml.buf.WriteString(prefix)
+	if ml.header.Checksum {
+		ml.buf.WriteString(checksumMarker(ml.body.Bytes()))
+	}
+	ml.buf.Write(ml.body.Bytes())
+	ml.buf.WriteByte('\n')
+	size, err := write(ml.writer, ml.buf.Bytes(), t, level)
+	return t, size, err
+}
+
+// depth returns a random number of frames within the configured range.
+func (ml *multilineLogger) depth() int {
+	return ml.minDepth + rand.Intn(ml.maxDepth-ml.minDepth+1)
+}
+
+// appendJavaTrace appends an exception with its frames, and sometimes the cause of the exception, whose frames
+// end with the number of frames in common with the enclosing trace.
+func (ml *multilineLogger) appendJavaTrace() {
+	ml.lines = append(ml.lines, pick(javaExceptions))
+	ml.appendJavaFrames(ml.depth())
+	if rand.Intn(2) == 0 {
+		return
+	}
+	ml.lines = append(ml.lines, "Caused by: "+pick(javaCauses))
+	depth := ml.depth()
+	common := rand.Intn(depth) + 1
+	ml.appendJavaFrames(depth - common + 1)
+	ml.lines = append(ml.lines, fmt.Sprintf("\t... %d more", common))
+}
+
+func (ml *multilineLogger) appendJavaFrames(depth int) {
+	for i := 0; i < depth; i++ {
+		frame := pick(javaFrames)
+		method := strings.LastIndexByte(frame, '.')
+		class := frame[strings.LastIndexByte(frame[:method], '.')+1 : method]
+		ml.lines = append(ml.lines, fmt.Sprintf("\tat %s(%s.java:%d)", frame, class, 10+rand.Intn(990)))
+	}
+}
+
+// appendPythonTrace appends a traceback, whose frames are listed from the outermost to the innermost.
+func (ml *multilineLogger) appendPythonTrace() {
+	ml.lines = append(ml.lines, "Traceback (most recent call last):")
+	for i, depth := 0, ml.depth(); i < depth; i++ {
+		frame := pythonFrames[rand.Intn(len(pythonFrames))]
+		ml.lines = append(ml.lines,
+			fmt.Sprintf(`  File "%s", line %d, in %s`, frame[0], 10+rand.Intn(990), frame[1]),
+			"    "+frame[2],
+		)
+	}
+	ml.lines = append(ml.lines, pick(pythonErrors))
+}
+
+// appendGoTrace appends a panic followed by the stack of the panicking goroutine and sometimes those of a few
+// waiting goroutines, separated by blank lines.
+func (ml *multilineLogger) appendGoTrace() {
+	if rand.Intn(2) == 0 {
+		ml.lines = append(ml.lines,
+			"panic: "+goNilPanic,
+			fmt.Sprintf(
+				"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x%x]", 0x400000+rand.Intn(0x100000),
+			),
+		)
+	} else {
+		ml.lines = append(ml.lines, "panic: "+pick(goPanics))
+	}
+	ml.appendGoroutine(1, "running")
+	for i, extra := 0, rand.Intn(3); i < extra; i++ {
+		ml.appendGoroutine(2+rand.Intn(100), pick(goWaitReasons))
+	}
+}
+
+func (ml *multilineLogger) appendGoroutine(id int, state string) {
+	ml.lines = append(ml.lines, "", fmt.Sprintf("goroutine %d [%s]:", id, state))
+	for i, depth := 0, ml.depth(); i < depth; i++ {
+		frame := goFrames[rand.Intn(len(goFrames))]
+		ml.lines = append(ml.lines,
+			fmt.Sprintf("%s(0xc%09x, 0x%x)", frame[0], rand.Int63n(1<<36), rand.Intn(0x100)),
+			fmt.Sprintf("\t%s:%d +0x%x", frame[1], 10+rand.Intn(990), rand.Intn(0x200)),
+		)
+	}
+}
+
+func pick(values []string) string {
+	return values[rand.Intn(len(values))]
+}
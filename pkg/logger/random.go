@@ -2,7 +2,6 @@ package logger
 
 import (
 	"encoding/hex"
-	"fmt"
 	"io"
 	"math/rand"
 	"sync"
@@ -10,23 +9,21 @@ import (
 )
 
 type randomLogger struct {
-	output          io.Writer
-	name            string
-	timestampFormat string
-	logBuffer       []byte
-	hexBuffer       []byte
-	newLinePos      int
-	mutex           sync.Mutex
+	output     io.Writer
+	header     Header
+	logBuffer  []byte
+	hexBuffer  []byte
+	newLinePos int
+	mutex      sync.Mutex
 }
 
 // NewRandomLogger creates a Logger that prints random strings no smaller than the minimal size.
-func NewRandomLogger(writer io.Writer, size int, name string, timestampFormat string) Logger {
+func NewRandomLogger(writer io.Writer, size int, header Header) Logger {
 	ret := &randomLogger{
-		output:          writer,
-		name:            name,
-		timestampFormat: timestampFormat,
+		output: writer,
+		header: header.withDefaults(),
 	}
-	maxPrefixSize := len(fmt.Sprintf("%s [%s]\n", timestampFormat, name))
+	maxPrefixSize := ret.header.maxPrefixSize() + 1
 	if maxPrefixSize >= size {
 		size = maxPrefixSize
 	}
@@ -39,18 +36,21 @@ func NewRandomLogger(writer io.Writer, size int, name string, timestampFormat st
 }
 
 func (rg *randomLogger) Log() (time.Time, int, error) {
-	t, prefix := getPrefix(rg.name, rg.timestampFormat)
-	size, err := rg.doLog(prefix)
+	t, level, prefix := rg.header.prefix()
+	size, err := rg.doLog(t, level, prefix)
 	rg.mutex.Lock()
 	go rg.refresh()
 	return t, size, err
 }
 
-func (rg *randomLogger) doLog(prefix string) (int, error) {
+func (rg *randomLogger) doLog(t time.Time, level, prefix string) (int, error) {
 	rg.mutex.Lock()
 	defer rg.mutex.Unlock()
+	if rg.header.Checksum {
+		prefix += checksumMarker(rg.logBuffer[len(prefix)+checksumSize : rg.newLinePos])
+	}
 	copy(rg.logBuffer, prefix)
-	return rg.output.Write(rg.logBuffer)
+	return write(rg.output, rg.logBuffer, t, level)
 }
 
 func (rg *randomLogger) refresh() {
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"net/url"
+	"strconv"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// The keys of the query parameters that carry the name, the sequence number, the level and the latency marker of an
+// access log without the prefix.
+const (
+	queryTaskKey     = "logtap_task"
+	querySequenceKey = "logtap_seq"
+	queryLevelKey    = "logtap_level"
+	querySentKey     = "logtap_sent"
+)
+
+var sequenceMarker = []byte("] seq=")
+
+// Record identifies a log message produced by a Logger; it is parsed back from the text of the log.
+type Record struct {
+	// Name is the name of the task that produced the log.
+	Name string
+
+	// Sequence is the sequence number of the log in the task.
+	Sequence int64
+
+	// Level is the level of the log, or an empty string if the log carries none.
+	Level string
+
+	// Corrupted is true if the log carries a checksum that does not match its payload.
+	Corrupted bool
+
+	// Sent is the time at which the log was created, or the zero time if the log carries no latency marker.
+	Sent time.Time
+}
+
+// ParseRecord parses a line, without the trailing new line, produced by any of the Loggers in this package. It
+// returns false if the line does not carry a sequence number.
+func ParseRecord(line []byte) (*Record, bool) {
+	if len(line) > 0 && line[0] == '{' {
+		if ret, ok := parseJSONRecord(line); ok {
+			return ret, true
+		}
+	}
+	return parseTextRecord(line)
+}
+
+func parseTextRecord(line []byte) (*Record, bool) {
+	markerPos := bytes.Index(line, sequenceMarker)
+	if markerPos < 0 {
+		return parseQueryRecord(line)
+	}
+	namePos := bytes.LastIndexByte(line[:markerPos], '[')
+	if namePos < 0 {
+		return nil, false
+	}
+	rest := line[markerPos+len(sequenceMarker):]
+	end := bytes.IndexByte(rest, ' ')
+	if end < 0 {
+		end = len(rest)
+	}
+	seq, err := strconv.ParseInt(string(rest[:end]), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	ret := &Record{
+		Name:     string(line[namePos+1 : markerPos]),
+		Sequence: seq,
+		Level:    parseTextLevel(line[:namePos]),
+	}
+	if end == len(rest) {
+		return ret, true
+	}
+	rest = rest[end+1:]
+	if bytes.HasPrefix(rest, []byte("sent=")) {
+		end = bytes.IndexByte(rest, ' ')
+		if end < 0 {
+			end = len(rest)
+		}
+		if nanos, err := strconv.ParseInt(string(rest[len("sent="):end]), 10, 64); err == nil {
+			ret.Sent = time.Unix(0, nanos)
+		}
+		if end == len(rest) {
+			return ret, true
+		}
+		rest = rest[end+1:]
+	}
+	if len(rest) >= checksumSize && bytes.HasPrefix(rest, []byte("crc=")) && rest[checksumSize-1] == ' ' {
+		sum, err := strconv.ParseUint(string(rest[4:checksumSize-1]), 16, 32)
+		ret.Corrupted = err != nil || uint32(sum) != checksum(rest[checksumSize:])
+	}
+	return ret, true
+}
+
+// parseTextLevel returns the level at the end of the part of a prefix before the name, or an empty string if there
+// is none.
+func parseTextLevel(prefix []byte) string {
+	if !bytes.HasSuffix(prefix, []byte(" ")) {
+		return ""
+	}
+	prefix = prefix[:len(prefix)-1]
+	level := string(prefix[bytes.LastIndexByte(prefix, ' ')+1:])
+	for i := range model.LogLevels {
+		if model.LogLevels[i] == level {
+			return level
+		}
+	}
+	return ""
+}
+
+// parseQueryRecord parses an access log without the prefix, which carries its name, sequence number, level and
+// latency marker in the query string of the request path.
+func parseQueryRecord(line []byte) (*Record, bool) {
+	seq, err := strconv.ParseInt(queryValue(line, querySequenceKey), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	name, err := url.QueryUnescape(queryValue(line, queryTaskKey))
+	if err != nil {
+		return nil, false
+	}
+	ret := &Record{
+		Name:     name,
+		Sequence: seq,
+		Level:    queryValue(line, queryLevelKey),
+	}
+	if nanos, err := strconv.ParseInt(queryValue(line, querySentKey), 10, 64); err == nil {
+		ret.Sent = time.Unix(0, nanos)
+	}
+	return ret, true
+}
+
+// queryValue returns the raw value of the query parameter of the given key in the line, or an empty string if
+// there is none.
+func queryValue(line []byte, key string) string {
+	for _, separator := range []string{"?", "&"} {
+		marker := []byte(separator + key + "=")
+		if pos := bytes.Index(line, marker); pos >= 0 {
+			rest := line[pos+len(marker):]
+			if end := bytes.IndexAny(rest, "& \""); end >= 0 {
+				rest = rest[:end]
+			}
+			return string(rest)
+		}
+	}
+	return ""
+}
+
+func parseJSONRecord(line []byte) (*Record, bool) {
+	var record struct {
+		Task     string  `json:"task"`
+		Sequence *int64  `json:"seq"`
+		Level    string  `json:"level"`
+		Message  *string `json:"message"`
+		Checksum *string `json:"crc"`
+		Sent     *int64  `json:"sent"`
+	}
+	if err := json.Unmarshal(line, &record); err != nil || record.Sequence == nil {
+		return nil, false
+	}
+	ret := &Record{
+		Name:     record.Task,
+		Sequence: *record.Sequence,
+		Level:    record.Level,
+	}
+	if record.Sent != nil {
+		ret.Sent = time.Unix(0, *record.Sent)
+	}
+	if record.Checksum != nil {
+		sum, err := strconv.ParseUint(*record.Checksum, 16, 32)
+		ret.Corrupted = err != nil || record.Message == nil ||
+			uint32(sum) != crc32.ChecksumIEEE([]byte(*record.Message))
+	}
+	return ret, true
+}
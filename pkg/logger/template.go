@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type templateLogger struct {
+	writer   io.Writer
+	header   Header
+	template *template.Template
+	data     *templateData
+	msg      bytes.Buffer
+	buf      bytes.Buffer
+}
+
+// NewTemplateLogger creates a Logger that prints messages rendered from the given template; see parseMessageTemplate
+// for the functions it can use. It returns an error if the template cannot be parsed.
+func NewTemplateLogger(writer io.Writer, text string, header Header) (Logger, error) {
+	ret := &templateLogger{
+		writer: writer,
+		header: header.withDefaults(),
+		data:   &templateData{Task: header.Name},
+	}
+	var err error
+	if ret.template, err = parseMessageTemplate(text, ret.data); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (tl *templateLogger) Log() (time.Time, int, error) {
+	t, seq, level, prefix := tl.header.sequencedPrefix()
+	tl.data.Sequence, tl.data.Time = seq, t
+	tl.msg.Reset()
+	if err := tl.template.Execute(&tl.msg, tl.data); err != nil {
+		return t, 0, err
+	}
+	tl.buf.Reset()
+	tl.buf.WriteString(prefix)
+	if tl.header.Checksum {
+		tl.buf.WriteString(checksumMarker(tl.msg.Bytes()))
+	}
+	tl.buf.Write(tl.msg.Bytes())
+	tl.buf.WriteByte('\n')
+	size, err := write(tl.writer, tl.buf.Bytes(), t, level)
+	return t, size, err
+}
+
+var templateWords = []string{
+	"alpha", "amber", "anchor", "apple", "arrow", "autumn", "breeze", "bridge", "canyon", "cedar",
+	"cloud", "comet", "coral", "crystal", "dawn", "delta", "dune", "ember", "falcon", "forest",
+	"frost", "garden", "glacier", "harbor", "hollow", "island", "jade", "lagoon", "lantern", "maple",
+	"meadow", "nebula", "ocean", "orbit", "pebble", "pine", "prairie", "quartz", "river", "shadow",
+	"spark", "summit", "thunder", "tide", "valley", "velvet", "willow", "winter", "zenith", "zephyr",
+}
+
+var templateDomains = []string{"example.com", "example.org", "example.net", "mail.test"}
+
+// templateData is the value with which the message template is executed; the seq and now functions read it too.
+type templateData struct {
+	// Task is the name of the task.
+	Task string
+
+	// Sequence is the sequence number of the log.
+	Sequence int64
+
+	// Time is the time at which the log is created.
+	Time time.Time
+}
+
+// parseMessageTemplate parses the Message of a LogTaskSpec whose ContentType is ContentTypeTemplate. The template
+// is meant to be executed with data, whose fields are read by the seq and now functions when it is executed; the
+// other functions generate random fake data. The functions, named by model.MessageTemplateFunctions, are:
+//
+//	seq                  the sequence number of the log
+//	now LAYOUT           the time of the log in the given Go time layout
+//	uuid                 a random version 4 UUID
+//	randInt MIN MAX      a random integer within [MIN, MAX]
+//	randFloat MIN MAX    a random float within [MIN, MAX)
+//	pick A B ...         one of the arguments
+//	words N              N random words separated by spaces
+//	ipv4, ipv6           a random IP address
+//	email                a random email address
+//	username             a random user name
+//	duration MIN MAX     a random duration within the range, such as duration "10ms" "2s"
+func parseMessageTemplate(text string, data *templateData) (*template.Template, error) {
+	return template.New("message").Funcs(messageTemplateFuncs(data)).Parse(text)
+}
+
+// messageTemplateFuncs returns the functions of a message template executed with data.
+func messageTemplateFuncs(data *templateData) template.FuncMap {
+	return template.FuncMap{
+		"seq": func() int64 {
+			return data.Sequence
+		},
+		"now": func(layout string) string {
+			return data.Time.Format(layout)
+		},
+		"uuid": func() string {
+			b := make([]byte, 16)
+			rand.Read(b)
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max < min {
+				return 0, fmt.Errorf("max %d smaller than min %d", max, min)
+			}
+			return min + rand.Intn(max-min+1), nil
+		},
+		"randFloat": func(min, max float64) float64 {
+			return min + rand.Float64()*(max-min)
+		},
+		"pick": func(values ...string) (string, error) {
+			if len(values) == 0 {
+				return "", fmt.Errorf("nothing to pick from")
+			}
+			return values[rand.Intn(len(values))], nil
+		},
+		"words": func(n int) string {
+			words := make([]string, n)
+			for i := range words {
+				words[i] = templateWords[rand.Intn(len(templateWords))]
+			}
+			return strings.Join(words, " ")
+		},
+		"ipv4": func() string {
+			return net.IPv4(byte(1+rand.Intn(223)), byte(rand.Intn(256)), byte(rand.Intn(256)),
+				byte(1+rand.Intn(254))).String()
+		},
+		"ipv6": func() string {
+			ip := make(net.IP, net.IPv6len)
+			rand.Read(ip)
+			ip[0], ip[1] = 0x20, 0x01
+			return ip.String()
+		},
+		"email": func() string {
+			return templateUsername() + "@" + templateDomains[rand.Intn(len(templateDomains))]
+		},
+		"username": templateUsername,
+		"duration": func(min, max string) (time.Duration, error) {
+			from, err := time.ParseDuration(min)
+			if err != nil {
+				return 0, err
+			}
+			to, err := time.ParseDuration(max)
+			if err != nil {
+				return 0, err
+			}
+			if to < from {
+				return 0, fmt.Errorf("max %s smaller than min %s", max, min)
+			}
+			return from + time.Duration(rand.Int63n(int64(to-from)+1)), nil
+		},
+	}
+}
+
+func templateUsername() string {
+	ret := templateWords[rand.Intn(len(templateWords))]
+	if rand.Intn(2) == 0 {
+		ret += "." + templateWords[rand.Intn(len(templateWords))]
+	}
+	return ret + strconv.Itoa(rand.Intn(100))
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/lichuan0620/logtap/pkg/fieldpath"
 	"github.com/lichuan0620/logtap/pkg/httputil"
@@ -9,40 +13,189 @@ import (
 	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
 )
 
-const notFoundMessage = "Cannot find the requested LogTask object."
+const (
+	// TasksPath is the path under which the LogTask resources are served.
+	TasksPath = "/tasks"
 
-type logTapHandler struct {
-	tap logtap.LogTap
+	notFoundMessage = "Cannot find the requested LogTask object."
+	conflictMessage = "A LogTask object with the same name already exists."
+	stoppedMessage  = "LogTap is shutting down and no longer accepts new LogTask objects."
+	runningMessage  = "The requested LogTask object is not running."
+)
+
+// taskUpdate is the body of PUT and PATCH requests; the fields are kept raw so that PATCH requests can be merged
+// into the current LogTaskSpec.
+type taskUpdate struct {
+	Metadata *model.Metadata `json:"metadata,omitempty"`
+	Spec     json.RawMessage `json:"spec,omitempty"`
+	Status   *struct {
+		Phase string `json:"phase"`
+	} `json:"status,omitempty"`
+}
+
+type taskHandler struct {
+	manager logtap.Manager
 }
 
-// NewLogTapHandler returns a http.Handler that handles one LogTap instance.
-func NewLogTapHandler(tap logtap.LogTap) http.Handler {
-	return &logTapHandler{
-		tap: tap,
+// NewHandler returns a http.Handler that serves the LogTasks hosted by the given Manager under TasksPath and their
+// metrics under MetricsPath.
+func NewHandler(manager logtap.Manager) http.Handler {
+	h := &taskHandler{
+		manager: manager,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(TasksPath, h.serveCollection)
+	mux.HandleFunc(TasksPath+"/", h.serveResource)
+	mux.HandleFunc(MetricsPath, h.serveMetrics)
+	return mux
+}
+
+// serveCollection serves requests targeting the collection of all LogTasks.
+func (h *taskHandler) serveCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		httputil.WriteGetResponse(w, h.manager.List(), nil)
+	case http.MethodPost:
+		task, err := h.createLogTask(r)
+		if err != nil {
+			httputil.WritePostResponse(w, nil, err)
+			return
+		}
+		httputil.WritePostResponse(w, task, nil, httputil.NewHeaderField("Location", TasksPath+"/"+task.Name))
+	default:
+		httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
 	}
 }
 
-// ServeHTTP implements the http.Handler interface.
-func (h *logTapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// serveResource serves requests targeting a single LogTask.
+func (h *taskHandler) serveResource(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, TasksPath+"/")
+	if len(name) == 0 {
+		h.serveCollection(w, r)
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
-		task, err := h.getLogTask()
+		task, err := h.getLogTask(name)
 		httputil.WriteGetResponse(w, task, err)
+	case http.MethodPut:
+		task, err := h.updateLogTask(name, r, false)
+		httputil.WriteGetResponse(w, task, err)
+	case http.MethodPatch:
+		task, err := h.updateLogTask(name, r, true)
+		httputil.WriteGetResponse(w, task, err)
+	case http.MethodDelete:
+		task, err := h.manager.Delete(name)
+		httputil.WriteDeleteResponse(w, task, translateError(err))
 	default:
 		httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
 	}
 }
 
-func (h *logTapHandler) getLogTask() (*model.LogTask, error) {
-	if h == nil || h.tap == nil {
-		return nil, httputil.NewNotFoundError(notFoundMessage)
+func (h *taskHandler) createLogTask(r *http.Request) (*model.LogTask, error) {
+	task := new(model.LogTask)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(task); err != nil {
+		return nil, httputil.NewRequestError(err.Error())
+	}
+	if task.Spec == nil {
+		return nil, httputil.NewRequestError("spec not specified")
+	}
+	if len(task.Version) > 0 {
+		if err := model.ValidateMetadata(fieldpath.NewFieldPath("logTask", "metadata"), &task.Metadata); err != nil {
+			return nil, httputil.NewRequestError(err.Error())
+		}
+	}
+	if err := model.ValidateLogTaskSpec(fieldpath.NewFieldPath("logTask", "spec"), task.Spec); err != nil {
+		return nil, httputil.NewRequestError(err.Error())
+	}
+	created, err := h.manager.Create(task.Name, task.Spec)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return created, nil
+}
+
+// updateLogTask applies the spec and the phase found in the request body to the named LogTask. If merge is true,
+// the given spec fields are merged into the current spec; otherwise, they replace the current spec as a whole.
+// A phase of PhasePaused pauses the LogTask and a phase of PhaseRunning resumes it.
+func (h *taskHandler) updateLogTask(name string, r *http.Request, merge bool) (*model.LogTask, error) {
+	current, err := h.manager.Get(name)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	update := new(taskUpdate)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(update); err != nil {
+		return nil, httputil.NewRequestError(err.Error())
+	}
+	if update.Metadata != nil && len(update.Metadata.Name) > 0 && update.Metadata.Name != name {
+		return nil, httputil.NewRequestError(fmt.Sprintf("name '%s' does not match the path", update.Metadata.Name))
+	}
+	if !merge && len(update.Spec) == 0 {
+		return nil, httputil.NewRequestError("spec not specified")
 	}
-	task := h.tap.GetTask()
-	if task == nil {
-		return nil, httputil.NewNotFoundError(notFoundMessage)
+	if len(update.Spec) > 0 {
+		spec := new(model.LogTaskSpec)
+		if merge {
+			spec = current.Spec
+		}
+		decoder = json.NewDecoder(bytes.NewReader(update.Spec))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(spec); err != nil {
+			return nil, httputil.NewRequestError(err.Error())
+		}
+		if err = model.ValidateLogTaskSpec(fieldpath.NewFieldPath("logTask", "spec"), spec); err != nil {
+			return nil, httputil.NewRequestError(err.Error())
+		}
+		if current, err = h.manager.UpdateSpec(name, spec); err != nil {
+			return nil, translateError(err)
+		}
+	}
+	if update.Status != nil {
+		switch update.Status.Phase {
+		case current.Status.Phase:
+		case model.PhasePaused:
+			current, err = h.manager.Pause(name)
+		case model.PhaseRunning:
+			current, err = h.manager.Resume(name)
+		default:
+			return nil, httputil.NewRequestError(fmt.Sprintf("cannot move task to phase '%s'", update.Status.Phase))
+		}
+		if err != nil {
+			return nil, translateError(err)
+		}
+	}
+	return current, nil
+}
+
+func (h *taskHandler) getLogTask(name string) (*model.LogTask, error) {
+	task, err := h.manager.Get(name)
+	if err != nil {
+		return nil, translateError(err)
 	}
 	if err := model.ValidateLogTask(fieldpath.NewFieldPath("logTask"), task); err != nil {
 		return nil, httputil.NewValidationError(err.Error())
 	}
 	return task, nil
 }
+
+// translateError converts the errors returned by a logtap.Manager into HTTP errors.
+func translateError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case logtap.ErrTaskNotFound:
+		return httputil.NewNotFoundError(notFoundMessage)
+	case logtap.ErrTaskExists:
+		return httputil.NewConflictError(conflictMessage)
+	case logtap.ErrManagerStopped:
+		return httputil.NewConflictError(stoppedMessage)
+	case logtap.ErrTaskNotRunning:
+		return httputil.NewConflictError(runningMessage)
+	default:
+		return httputil.NewRequestError(err.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lichuan0620/logtap/pkg/logtap"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestHandler_CRUD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	manager := logtap.NewManager()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go manager.Run(stopCh)
+	server := httptest.NewServer(NewHandler(manager))
+	defer server.Close()
+
+	body := func(name string) string {
+		return fmt.Sprintf(`{
+			"metadata": {"name": "%s"},
+			"spec": {
+				"outputKind": "File",
+				"filepath": "%s",
+				"contentType": "Explicit",
+				"message": "hello",
+				"interval": 0.01
+			}
+		}`, name, filepath.Join(dir, name+".log"))
+	}
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"CreateFirst", http.MethodPost, TasksPath, body("first"), http.StatusCreated},
+		{"CreateSecond", http.MethodPost, TasksPath, body("second"), http.StatusCreated},
+		{"CreateDuplicate", http.MethodPost, TasksPath, body("first"), http.StatusConflict},
+		{"CreateInvalid", http.MethodPost, TasksPath, `{"metadata": {"name": "bad"}, "spec": {}}`, http.StatusBadRequest},
+		{"CreateUnknownField", http.MethodPost, TasksPath, `{"unknown": true}`, http.StatusBadRequest},
+		{"Get", http.MethodGet, TasksPath + "/first", "", http.StatusOK},
+		{"GetMissing", http.MethodGet, TasksPath + "/missing", "", http.StatusNotFound},
+		{"Pause", http.MethodPatch, TasksPath + "/first", `{"status": {"phase": "Paused"}}`, http.StatusOK},
+		{"PatchSpec", http.MethodPatch, TasksPath + "/first", `{"spec": {"interval": 0.02}}`, http.StatusOK},
+		{"PatchInvalidSpec", http.MethodPatch, TasksPath + "/first", `{"spec": {"interval": -1}}`, http.StatusBadRequest},
+		{"PatchInvalidPhase", http.MethodPatch, TasksPath + "/first", `{"status": {"phase": "Stopped"}}`, http.StatusBadRequest},
+		{"PutWithoutSpec", http.MethodPut, TasksPath + "/first", `{"status": {"phase": "Running"}}`, http.StatusBadRequest},
+		{"PatchMissing", http.MethodPatch, TasksPath + "/missing", `{}`, http.StatusNotFound},
+		{"Delete", http.MethodDelete, TasksPath + "/second", "", http.StatusOK},
+		{"DeleteMissing", http.MethodDelete, TasksPath + "/second", "", http.StatusNotFound},
+		{"MethodNotAllowed", http.MethodPut, TasksPath, "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err.Error())
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.code {
+			t.Fatalf("%s: unexpected status code: want %d; got %d", tc.name, tc.code, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + TasksPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	list := new(model.LogTaskList)
+	if err = json.NewDecoder(resp.Body).Decode(list); err != nil {
+		t.Fatal(err.Error())
+	}
+	if list.Total != 1 || list.LogTasks[0].Name != "first" {
+		t.Fatalf("unexpected task list: %+v", list)
+	}
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	manager := logtap.NewManager()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go manager.Run(stopCh)
+	spec := &model.LogTaskSpec{
+		OutputKind:  model.OutputKindStdErr,
+		ContentType: model.ContentTypeExplicit,
+		Message:     "hello",
+		Interval:    1,
+	}
+	if _, err := manager.Create("metrics", spec); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := manager.Pause("metrics"); err != nil {
+		t.Fatal(err.Error())
+	}
+	server := httptest.NewServer(NewHandler(manager))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + MetricsPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body := string(data)
+	for _, expected := range []string{
+		"# TYPE logtap_sent_logs_total counter\n",
+		`logtap_sent_bytes_total{task="metrics",output="STDERR"} `,
+		`logtap_task_phase{task="metrics",output="STDERR",phase="Paused"} 1` + "\n",
+		`logtap_task_phase{task="metrics",output="STDERR",phase="Running"} 0` + "\n",
+		`logtap_write_duration_seconds_bucket{task="metrics",output="STDERR",le="+Inf"} `,
+		`logtap_write_errors_total{task="metrics",output="STDERR"} 0` + "\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("expected %q in metrics:\n%s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/lichuan0620/logtap/pkg/httputil"
+	"github.com/lichuan0620/logtap/pkg/logtap"
+	"github.com/lichuan0620/logtap/pkg/metrics"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// MetricsPath is the path under which the metrics of all LogTasks are served in the Prometheus text format.
+const MetricsPath = "/metrics"
+
+// phases are all the phases reported by the phase metric, one sample each.
+var phases = []string{
+	model.PhaseIdle,
+	model.PhaseRunning,
+	model.PhasePaused,
+	model.PhaseUpdating,
+	model.PhaseStopped,
+	model.PhaseFailed,
+}
+
+func (h *taskHandler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := writeMetrics(w, h.manager.Metrics()); err != nil {
+		log.Printf("failed to write metrics: %s", err.Error())
+	}
+}
+
+// writeMetrics writes the metric families of the given LogTaps; every sample is labelled by the task name and the
+// output kind.
+func writeMetrics(w io.Writer, taps []*logtap.Metrics) error {
+	labels := make([][]metrics.Label, len(taps))
+	for i, tap := range taps {
+		labels[i] = []metrics.Label{
+			{Name: "task", Value: tap.Task.Name},
+			{Name: "output", Value: tap.Task.Spec.OutputKind},
+		}
+	}
+	e := metrics.NewEncoder(w)
+
+	e.Family("logtap_sent_logs_total", metrics.TypeCounter, "Number of log messages sent.")
+	for i, tap := range taps {
+		e.Sample("logtap_sent_logs_total", labels[i], float64(tap.Task.Status.SentCount))
+	}
+	e.Family("logtap_sent_bytes_total", metrics.TypeCounter, "Size in bytes of the log messages sent.")
+	for i, tap := range taps {
+		e.Sample("logtap_sent_bytes_total", labels[i], float64(tap.Task.Status.SentBytes))
+	}
+	e.Family("logtap_task_phase", metrics.TypeGauge, "Phase of the task; 1 for the current phase and 0 for the others.")
+	for i, tap := range taps {
+		phaseLabels := withLabel(labels[i], "phase")
+		for _, phase := range phases {
+			phaseLabels[len(labels[i])].Value = phase
+			value := 0.0
+			if tap.Task.Status.Phase == phase {
+				value = 1
+			}
+			e.Sample("logtap_task_phase", phaseLabels, value)
+		}
+	}
+	e.Family("logtap_write_duration_seconds", metrics.TypeHistogram, "Time taken to write a single log message.")
+	for i, tap := range taps {
+		e.Histogram("logtap_write_duration_seconds", labels[i], tap.WriteLatency)
+	}
+	e.Family("logtap_write_errors_total", metrics.TypeCounter, "Number of log messages that failed to be written.")
+	for i, tap := range taps {
+		e.Sample("logtap_write_errors_total", labels[i], float64(tap.WriteErrors))
+	}
+	e.Family("logtap_dropped_writes_total", metrics.TypeCounter,
+		"Number of log messages dropped by the output; they are also counted as sent.")
+	for i, tap := range taps {
+		e.Sample("logtap_dropped_writes_total", labels[i], float64(tap.Task.Status.DroppedWrites))
+	}
+	e.Family("logtap_reconnects_total", metrics.TypeCounter,
+		"Number of times the connection to the server has been redialed after it was lost or could not be made.")
+	for i, tap := range taps {
+		e.Sample("logtap_reconnects_total", labels[i], float64(tap.Task.Status.Reconnects))
+	}
+	e.Family("logtap_buffered_bytes", metrics.TypeGauge,
+		"Size in bytes of the log messages written to the buffer but not flushed to the output yet.")
+	for i, tap := range taps {
+		e.Sample("logtap_buffered_bytes", labels[i], float64(tap.Task.Status.BufferedBytes))
+	}
+	e.Family("logtap_flushes_total", metrics.TypeCounter, "Number of times the buffer has been flushed.")
+	for i, tap := range taps {
+		e.Sample("logtap_flushes_total", labels[i], float64(tap.Task.Status.Flushes))
+	}
+	e.Family("logtap_fsyncs_total", metrics.TypeCounter, "Number of times the log file has been synced to the disk.")
+	for i, tap := range taps {
+		e.Sample("logtap_fsyncs_total", labels[i], float64(tap.Task.Status.Fsyncs))
+	}
+	e.Family("logtap_http_responses_total", metrics.TypeCounter,
+		"Number of HTTP requests by the status code of the response; requests without a response have code error.")
+	for i, tap := range taps {
+		codes := make([]string, 0, len(tap.Task.Status.HTTPResponses))
+		for code := range tap.Task.Status.HTTPResponses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		codeLabels := withLabel(labels[i], "code")
+		for _, code := range codes {
+			codeLabels[len(labels[i])].Value = code
+			e.Sample("logtap_http_responses_total", codeLabels, float64(tap.Task.Status.HTTPResponses[code]))
+		}
+	}
+	e.Family("logtap_logs_by_level_total", metrics.TypeCounter, "Number of log messages produced at every level.")
+	for i, tap := range taps {
+		levelLabels := withLabel(labels[i], "level")
+		for _, level := range model.LogLevels {
+			if count, ok := tap.Task.Status.LevelCounts[level]; ok {
+				levelLabels[len(labels[i])].Value = level
+				e.Sample("logtap_logs_by_level_total", levelLabels, float64(count))
+			}
+		}
+	}
+	e.Family("logtap_http_request_duration_seconds", metrics.TypeHistogram, "Time taken by a HTTP request.")
+	for i, tap := range taps {
+		if kind := tap.Task.Spec.OutputKind; kind == model.OutputKindHTTP || kind == model.OutputKindOTLP {
+			e.Histogram("logtap_http_request_duration_seconds", labels[i], tap.HTTPRequestLatency)
+		}
+	}
+	return e.Flush()
+}
+
+// withLabel returns a copy of the labels with an extra label of the given name, whose value is left to be set.
+func withLabel(labels []metrics.Label, name string) []metrics.Label {
+	return append(append(make([]metrics.Label, 0, len(labels)+1), labels...), metrics.Label{Name: name})
+}
@@ -1,31 +1,114 @@
 package logtap
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/lichuan0620/logtap/pkg/fieldpath"
 	"github.com/lichuan0620/logtap/pkg/logger"
+	"github.com/lichuan0620/logtap/pkg/metrics"
 	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"github.com/lichuan0620/logtap/pkg/output"
 )
 
+// ErrTaskNotRunning is returned when a LogTap is asked to pause or resume after it has stopped.
+var ErrTaskNotRunning = errors.New("task is not running")
+
+// httpErrorKey is the key under which the HTTP requests that got no response are counted in the status.
+const httpErrorKey = "error"
+
 // A LogTap is a runnable worker that keep generating log messages in a predefined way.
 type LogTap interface {
 	// GetTask is used to inspect the underlying task; it return a copy of the LogTask.
 	GetTask() *model.LogTask
 
+	// GetMetrics returns a copy of the LogTask along with the metrics that are not part of its status.
+	GetMetrics() *Metrics
+
 	// Run prompts the LogTap to start generating log messages and blocks until it stops. The LogTap would stop
-	// when either the stopCh was closed or an error occurred. Run can only be called once per LogTap instance.
+	// when the stopCh was closed, a limit of its LogTaskSpec was reached, or an error occurred. Run can only be
+	// called once per LogTap instance.
 	Run(stopCh <-chan struct{}) error
+
+	// Pause stops the LogTap from generating log messages without ending Run; pausing a paused LogTap does
+	// nothing. If Run has not been called yet, the LogTap would start in the paused state. ErrTaskNotRunning is
+	// returned if Run has returned.
+	Pause() error
+
+	// Resume prompts a paused LogTap to continue generating log messages; resuming a running LogTap does
+	// nothing. ErrTaskNotRunning is returned if Run has returned.
+	Resume() error
+
+	// UpdateSpec validates the given LogTaskSpec and replaces the current one with it. If Run is in progress,
+	// the output and the content generator are rebuilt while the status, including the counters, is kept.
+	UpdateSpec(spec *model.LogTaskSpec) error
+
+	// Report summarizes how the LogTap went so far; it is meant to be called after Run has returned.
+	Report() *TaskReport
+}
+
+const (
+	commandPause = iota
+	commandResume
+	commandUpdate
+)
+
+// Metrics is a snapshot of a LogTap taken for the metrics endpoint.
+type Metrics struct {
+	// Task is a copy of the LogTask.
+	Task *model.LogTask
+
+	// WriteLatency is the histogram of the time in seconds taken to write a single log message.
+	WriteLatency *metrics.Histogram
+
+	// WriteErrors is the number of log messages that failed to be written.
+	WriteErrors int64
+
+	// HTTPRequestLatency is the histogram of the time in seconds taken by a HTTP request.
+	HTTPRequestLatency *metrics.Histogram
+}
+
+// command is a request sent to the goroutine executing Run.
+type command struct {
+	kind   int
+	spec   *model.LogTaskSpec
+	result chan error
 }
 
 type logTapImpl struct {
-	task  *model.LogTask
-	mutex sync.Mutex
-	once  chan struct{}
+	task     *model.LogTask
+	mutex    sync.Mutex
+	once     chan struct{}
+	done     chan struct{}
+	commands chan command
+
+	writeLatency    *metrics.Histogram
+	maxWriteLatency float64
+	writeErrors     int64
+	requestLatency  *metrics.Histogram
+
+	// startTime and stopTime are the times at which Run was called and returned.
+	startTime time.Time
+	stopTime  time.Time
+
+	// activeTime is the time spent generating log messages, and targetedLogs and targetedBytes are the numbers of
+	// logs and bytes that should have been sent meanwhile; they are accumulated by the runner.
+	activeTime    time.Duration
+	targetedLogs  float64
+	targetedBytes float64
+
+	// sequences are the Sequences of the log messages, keyed by the name in them.
+	sequences map[string]*logger.Sequence
+
+	// fileIndexes are the indexes of the files in the Files of the status, keyed by their paths.
+	fileIndexes map[string]int
+
+	// startPaused is set by Pause and Resume before Run is called.
+	startPaused bool
 }
 
 // NewLogTap creates a LogTap with the given name; its behavior is defined by the given LogTaskSpec object.
@@ -40,7 +123,14 @@ func NewLogTap(taskTemplate *model.LogTaskSpec, name string) (LogTap, error) {
 			Spec:   taskTemplate.DeepCopy(),
 			Status: new(model.LogTaskStatus),
 		},
-		once: make(chan struct{}),
+		once:     make(chan struct{}),
+		done:     make(chan struct{}),
+		commands: make(chan command),
+
+		sequences: make(map[string]*logger.Sequence),
+
+		writeLatency:   metrics.NewHistogram(metrics.LatencyBuckets),
+		requestLatency: metrics.NewHistogram(metrics.LatencyBuckets),
 	}
 	ret.setPhase(model.PhaseIdle, "")
 	if err := model.ValidateLogTask(fieldpath.NewFieldPath(), ret.task); err != nil {
@@ -49,69 +139,437 @@ func NewLogTap(taskTemplate *model.LogTaskSpec, name string) (LogTap, error) {
 	return ret, nil
 }
 
-func (lm logTapImpl) GetTask() *model.LogTask {
+func (lm *logTapImpl) GetTask() *model.LogTask {
 	lm.mutex.Lock()
 	defer lm.mutex.Unlock()
 	return lm.task.DeepCopy()
 }
 
-func (lm *logTapImpl) Run(stopCh <-chan struct{}) error {
-	close(lm.once)
-	var output io.Writer
-	switch lm.task.Spec.OutputKind {
-	case model.OutputKindStdErr:
-		output = os.Stderr
-	case model.OutputKindStdOut:
-		output = os.Stdout
-	case model.OutputKindFile:
-		file, err := os.OpenFile(lm.task.Spec.Filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			return fmt.Errorf("[%s] failed to open log file: %s", lm.task.Name, err.Error())
+func (lm *logTapImpl) GetMetrics() *Metrics {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	return &Metrics{
+		Task:         lm.task.DeepCopy(),
+		WriteLatency: lm.writeLatency.DeepCopy(),
+		WriteErrors:  lm.writeErrors,
+
+		HTTPRequestLatency: lm.requestLatency.DeepCopy(),
+	}
+}
+
+func (lm *logTapImpl) Report() *TaskReport {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	status := lm.task.Status
+	ret := &TaskReport{
+		Name:           lm.task.Name,
+		Phase:          status.Phase,
+		Reason:         status.Reason,
+		StartTime:      lm.startTime,
+		StopTime:       lm.stopTime,
+		ActiveDuration: lm.activeTime.Seconds(),
+		SentCount:      status.SentCount,
+		SentBytes:      status.SentBytes,
+		WriteLatency: LatencyReport{
+			P50: lm.writeLatency.Quantile(0.5),
+			P90: lm.writeLatency.Quantile(0.9),
+			P99: lm.writeLatency.Quantile(0.99),
+			Max: lm.maxWriteLatency,
+		},
+		WriteErrors:   lm.writeErrors,
+		DroppedWrites: status.DroppedWrites,
+		Reconnects:    status.Reconnects,
+		Flushes:       status.Flushes,
+		Fsyncs:        status.Fsyncs,
+		Spec:          lm.task.Spec.DeepCopy(),
+	}
+	if !lm.startTime.IsZero() {
+		stopTime := lm.stopTime
+		if stopTime.IsZero() {
+			stopTime = time.Now()
+		}
+		ret.Duration = stopTime.Sub(lm.startTime).Seconds()
+	}
+	if lm.writeLatency.Count > 0 {
+		ret.WriteLatency.Average = lm.writeLatency.Sum / float64(lm.writeLatency.Count)
+	}
+	for key, count := range status.HTTPResponses {
+		if code, err := strconv.Atoi(key); err != nil || code >= 400 {
+			ret.HTTPErrors += count
 		}
-		defer file.Close()
+	}
+	if ret.ActiveDuration > 0 {
+		ret.LogsPerSecond = float64(ret.SentCount) / ret.ActiveDuration
+		ret.BytesPerSecond = float64(ret.SentBytes) / ret.ActiveDuration
+		ret.TargetLogsPerSecond = lm.targetedLogs / ret.ActiveDuration
+		ret.TargetBytesPerSecond = lm.targetedBytes / ret.ActiveDuration
+		ret.Shortfall = getShortfall(
+			ret.TargetLogsPerSecond, ret.TargetBytesPerSecond, ret.LogsPerSecond, ret.BytesPerSecond,
+		)
+	}
+	return ret
+}
+
+func (lm *logTapImpl) Pause() error {
+	if lm.setStartPaused(true) {
+		return nil
+	}
+	return lm.send(command{kind: commandPause})
+}
+
+func (lm *logTapImpl) Resume() error {
+	if lm.setStartPaused(false) {
+		return nil
+	}
+	return lm.send(command{kind: commandResume})
+}
+
+// setStartPaused sets whether Run should start in the paused state and returns true if Run has not been called;
+// otherwise, it does nothing and returns false.
+func (lm *logTapImpl) setStartPaused(paused bool) bool {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	select {
+	case <-lm.once:
+		return false
 	default:
-		reason := fmt.Sprintf("[%s] unsupported output kind: %s", lm.task.Name, lm.task.Spec.OutputKind)
-		lm.setPhase(model.PhaseFailed, reason)
-		return fmt.Errorf(reason)
+		lm.startPaused = paused
+		return true
 	}
-	var worker logger.Logger
-	switch lm.task.Spec.ContentType {
-	case model.ContentTypeExplicit:
-		worker = logger.NewExplicitLogger(output, lm.task.Spec.Message, lm.task.Name, lm.task.Spec.TimestampFormat)
-	case model.ContentTypeRandom:
-		worker = logger.NewRandomLogger(output, lm.task.Spec.MinSize, lm.task.Name, lm.task.Spec.TimestampFormat)
+}
+
+func (lm *logTapImpl) UpdateSpec(spec *model.LogTaskSpec) error {
+	if err := model.ValidateLogTaskSpec(fieldpath.NewFieldPath("spec"), spec); err != nil {
+		return err
+	}
+	lm.mutex.Lock()
+	select {
+	case <-lm.once:
+		lm.mutex.Unlock()
 	default:
-		reason := fmt.Sprintf("[%s] unsupported content type: %s", lm.task.Name, lm.task.Spec.ContentType)
-		lm.setPhase(model.PhaseFailed, reason)
-		return fmt.Errorf(reason)
-	}
-	lm.setPhase(model.PhaseRunning, "")
-	interval := time.Duration(float64(time.Second) * lm.task.Spec.Interval)
-	timer := time.NewTimer(0)
-	defer timer.Stop()
+		lm.task.Spec = spec.DeepCopy()
+		lm.mutex.Unlock()
+		return nil
+	}
+	return lm.send(command{kind: commandUpdate, spec: spec.DeepCopy()})
+}
+
+// send passes the command to the goroutine executing Run and waits for the result; Run must have been called.
+func (lm *logTapImpl) send(cmd command) error {
+	cmd.result = make(chan error, 1)
+	select {
+	case lm.commands <- cmd:
+		return <-cmd.result
+	case <-lm.done:
+		return ErrTaskNotRunning
+	}
+}
+
+func (lm *logTapImpl) Run(stopCh <-chan struct{}) error {
+	lm.mutex.Lock()
+	close(lm.once)
+	lm.startTime = time.Now().UTC()
+	spec := lm.task.Spec.DeepCopy()
+	paused := lm.startPaused
+	lm.mutex.Unlock()
+	defer close(lm.done)
+	defer func() {
+		lm.mutex.Lock()
+		lm.stopTime = time.Now().UTC()
+		lm.mutex.Unlock()
+	}()
+	r, err := newRunner(lm, spec, paused)
+	if err != nil {
+		return lm.fail(err.Error())
+	}
+	defer r.close()
 	for {
 		select {
 		case <-stopCh:
 			lm.setPhase(model.PhaseStopped, "")
 			return nil
-		case <-timer.C:
-			timer.Reset(interval)
-			_, size, err := worker.Log()
+		case cmd := <-lm.commands:
+			cmd.result <- r.handle(cmd)
+		case <-r.expired():
+			lm.setPhase(model.PhaseStopped, fmt.Sprintf("duration of %gs reached", r.spec.Duration))
+			return nil
+		case <-r.timer.C:
+			if err = r.tick(); err != nil {
+				return lm.fail(err.Error())
+			}
+			if len(r.stopReason) > 0 {
+				lm.setPhase(model.PhaseStopped, r.stopReason)
+				return nil
+			}
+		}
+	}
+}
+
+// worker is one of the content generators of a LogTap.
+type worker struct {
+	logger logger.Logger
+
+	// status is the index of the worker in the Workers of the status, or -1 if the LogTap has a single worker.
+	status int
+}
+
+// open creates the outputs and the workers defined by the given LogTaskSpec; there is either a single output shared
+// by all the workers or one output per worker.
+func (lm *logTapImpl) open(spec *model.LogTaskSpec) ([]io.WriteCloser, []*worker, error) {
+	count := spec.Workers
+	if count < 1 {
+		count = 1
+	}
+	outs := make([]io.WriteCloser, 0, count)
+	workers := make([]*worker, 0, count)
+	perWorker := count > 1 && spec.WorkerWriter == model.WorkerWriterPerWorker
+	for i := 0; i < count; i++ {
+		if i == 0 || perWorker {
+			outSpec := spec
+			if perWorker && spec.OutputKind == model.OutputKindFile {
+				outSpec = spec.DeepCopy()
+				outSpec.Filepath = fmt.Sprintf(spec.Filepath, i)
+			}
+			out, err := output.New(lm.task.Name, outSpec, lm)
 			if err != nil {
-				reason := fmt.Sprintf("[%s] failed to write log: %s", lm.task.Name, err.Error())
-				lm.setPhase(model.PhaseFailed, reason)
-				return fmt.Errorf(reason)
+				closeAll(outs)
+				return nil, nil, fmt.Errorf("[%s] %s", lm.task.Name, err.Error())
+			}
+			if count > 1 && !perWorker {
+				out = output.NewLocked(out)
 			}
-			lm.recordLogStatus(size)
+			outs = append(outs, out)
+		}
+		name, status := lm.task.Name, -1
+		if count > 1 {
+			name = fmt.Sprintf("%s/%d", lm.task.Name, i)
+			status = lm.workerStatus(name)
+		}
+		l, err := lm.newLogger(outs[len(outs)-1], spec, name)
+		if err != nil {
+			closeAll(outs)
+			return nil, nil, err
+		}
+		workers = append(workers, &worker{logger: l, status: status})
+	}
+	return outs, workers, nil
+}
+
+// newLogger creates the content generator defined by the given LogTaskSpec, which writes to out the log messages
+// of the given name.
+func (lm *logTapImpl) newLogger(out io.Writer, spec *model.LogTaskSpec, name string) (logger.Logger, error) {
+	header := logger.Header{
+		Name:            name,
+		TimestampFormat: spec.TimestampFormat,
+		Sequence:        lm.sequence(name),
+		Checksum:        spec.Checksum,
+		LatencyMarker:   spec.LatencyMarker,
+		Levels:          logger.NewLevels(spec.Levels, lm.recordLevel),
+	}
+	switch spec.ContentType {
+	case model.ContentTypeExplicit:
+		return logger.NewExplicitLogger(out, spec.Message, header), nil
+	case model.ContentTypeRandom:
+		return logger.NewRandomLogger(out, spec.MinSize, header), nil
+	case model.ContentTypeJSON:
+		return logger.NewJSONLogger(out, spec.Message, spec.MinSize, spec.Fields, header), nil
+	case model.ContentTypeTemplate:
+		ret, err := logger.NewTemplateLogger(out, spec.Message, header)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] failed to parse message template: %s", lm.task.Name, err.Error())
+		}
+		return ret, nil
+	case model.ContentTypeMultiline:
+		return logger.NewMultilineLogger(out, spec.Message, spec.Multiline, header), nil
+	case model.ContentTypeApacheCommon, model.ContentTypeApacheCombined, model.ContentTypeNginx,
+		model.ContentTypeEnvoy:
+		return logger.NewAccessLogger(out, spec.ContentType, spec.AccessLog, header), nil
+	default:
+		return nil, fmt.Errorf("[%s] unsupported content type: %s", lm.task.Name, spec.ContentType)
+	}
+}
+
+// sequence returns the Sequence of the log messages of the given name, which is kept for the lifetime of the
+// LogTap so that the sequence numbers carry on after an update.
+func (lm *logTapImpl) sequence(name string) *logger.Sequence {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	ret, ok := lm.sequences[name]
+	if !ok {
+		ret = new(logger.Sequence)
+		lm.sequences[name] = ret
+	}
+	return ret
+}
+
+// workerStatus returns the index of the worker of the given name in the Workers of the status, adding it if needed.
+func (lm *logTapImpl) workerStatus(name string) int {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	for i := range lm.task.Status.Workers {
+		if lm.task.Status.Workers[i].Name == name {
+			return i
 		}
 	}
+	lm.task.Status.Workers = append(lm.task.Status.Workers, model.WorkerStatus{Name: name})
+	return len(lm.task.Status.Workers) - 1
 }
 
-func (lm *logTapImpl) recordLogStatus(size int) {
+// recordWrite records the result of writing a single log message by the worker with the given status index, or -1,
+// that took the given latency.
+func (lm *logTapImpl) recordWrite(status, size int, latency time.Duration, err error) {
 	lm.mutex.Lock()
 	defer lm.mutex.Unlock()
+	lm.writeLatency.Observe(latency.Seconds())
+	if latency.Seconds() > lm.maxWriteLatency {
+		lm.maxWriteLatency = latency.Seconds()
+	}
+	if err == output.ErrDropped {
+		lm.task.Status.DroppedWrites++
+	} else if err != nil {
+		lm.writeErrors++
+		return
+	}
 	lm.task.Status.SentCount++
 	lm.task.Status.SentBytes += int64(size)
+	if status >= 0 {
+		lm.task.Status.Workers[status].SentCount++
+		lm.task.Status.Workers[status].SentBytes += int64(size)
+	}
+}
+
+// sent returns the number and the size in bytes of the log messages sent.
+func (lm *logTapImpl) sent() (int64, int64) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	return lm.task.Status.SentCount, lm.task.Status.SentBytes
+}
+
+// recordActiveTime records the given time spent generating log messages at the given target rates.
+func (lm *logTapImpl) recordActiveTime(d time.Duration, targetLogsPerSecond, targetBytesPerSecond float64) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.activeTime += d
+	lm.targetedLogs += targetLogsPerSecond * d.Seconds()
+	lm.targetedBytes += targetBytesPerSecond * d.Seconds()
+}
+
+// recordLevel records a log message produced at the given level.
+func (lm *logTapImpl) recordLevel(level string) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	if lm.task.Status.LevelCounts == nil {
+		lm.task.Status.LevelCounts = make(map[string]int64)
+	}
+	lm.task.Status.LevelCounts[level]++
+}
+
+// RecordRotation implements the output.Recorder interface.
+func (lm *logTapImpl) RecordRotation() {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.Rotations++
+}
+
+// RecordReconnect implements the output.Recorder interface.
+func (lm *logTapImpl) RecordReconnect() {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.Reconnects++
+}
+
+// RecordRequest implements the output.Recorder interface.
+func (lm *logTapImpl) RecordRequest(code int, latency time.Duration) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.requestLatency.Observe(latency.Seconds())
+	key := strconv.Itoa(code)
+	if code == 0 {
+		key = httpErrorKey
+	}
+	if lm.task.Status.HTTPResponses == nil {
+		lm.task.Status.HTTPResponses = make(map[string]int64)
+	}
+	lm.task.Status.HTTPResponses[key]++
+	lm.task.Status.HTTPRequestLatency = lm.requestLatency.Sum / float64(lm.requestLatency.Count)
+}
+
+// RecordDrop implements the output.Recorder interface.
+func (lm *logTapImpl) RecordDrop(count int) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.DroppedWrites += int64(count)
+}
+
+// RecordBuffer implements the output.Recorder interface.
+func (lm *logTapImpl) RecordBuffer(size int) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.BufferedBytes += int64(size)
+}
+
+// RecordFlush implements the output.Recorder interface.
+func (lm *logTapImpl) RecordFlush(size int) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.BufferedBytes -= int64(size)
+	lm.task.Status.Flushes++
+}
+
+// RecordSync implements the output.Recorder interface.
+func (lm *logTapImpl) RecordSync() {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.Fsyncs++
+}
+
+// RecordFileWrite implements the output.Recorder interface.
+func (lm *logTapImpl) RecordFileWrite(path string, size int) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	i, ok := lm.fileIndexes[path]
+	if !ok {
+		if lm.fileIndexes == nil {
+			lm.fileIndexes = make(map[string]int)
+		}
+		i = len(lm.task.Status.Files)
+		lm.fileIndexes[path] = i
+		lm.task.Status.Files = append(lm.task.Status.Files, model.FileStatus{Path: path})
+	}
+	lm.task.Status.Files[i].SentCount++
+	lm.task.Status.Files[i].SentBytes += int64(size)
+}
+
+// RecordFileRemoval implements the output.Recorder interface.
+func (lm *logTapImpl) RecordFileRemoval(path string) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.RemovedFiles++
+	i, ok := lm.fileIndexes[path]
+	if !ok {
+		return
+	}
+	files := lm.task.Status.Files
+	lm.task.Status.Files = append(files[:i:i], files[i+1:]...)
+	delete(lm.fileIndexes, path)
+	for j := i; j < len(lm.task.Status.Files); j++ {
+		lm.fileIndexes[lm.task.Status.Files[j].Path] = j
+	}
+}
+
+func (lm *logTapImpl) setRates(logsPerSecond, bytesPerSecond, targetLogsPerSecond, shortfall float64) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Status.LogsPerSecond = logsPerSecond
+	lm.task.Status.BytesPerSecond = bytesPerSecond
+	lm.task.Status.TargetLogsPerSecond = targetLogsPerSecond
+	lm.task.Status.Shortfall = shortfall
+}
+
+func (lm *logTapImpl) setSpec(spec *model.LogTaskSpec) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.task.Spec = spec
 }
 
 func (lm *logTapImpl) setPhase(phase string, reason string) {
@@ -121,3 +579,9 @@ func (lm *logTapImpl) setPhase(phase string, reason string) {
 	lm.task.Status.Phase = phase
 	lm.task.Status.Reason = reason
 }
+
+// fail moves the task into the failed phase and returns the reason as an error.
+func (lm *logTapImpl) fail(reason string) error {
+	lm.setPhase(model.PhaseFailed, reason)
+	return errors.New(reason)
+}
@@ -0,0 +1,321 @@
+package logtap
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/logger"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"github.com/lichuan0620/logtap/pkg/output"
+)
+
+func TestLogTap_PauseResumeUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	spec := &model.LogTaskSpec{
+		OutputKind:  model.OutputKindFile,
+		Filepath:    filepath.Join(dir, "test.log"),
+		ContentType: model.ContentTypeExplicit,
+		Message:     "before",
+		Interval:    0.001,
+	}
+	tap, err := NewLogTap(spec, "test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = tap.Pause(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = tap.Resume(); err != nil {
+		t.Fatal(err.Error())
+	}
+	stopCh := make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- tap.Run(stopCh)
+	}()
+	waitForCount(t, tap, 1)
+
+	if err = tap.Pause(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if phase := tap.GetTask().Status.Phase; phase != model.PhasePaused {
+		t.Fatalf("unexpected phase: want %s; got %s", model.PhasePaused, phase)
+	}
+	paused := tap.GetTask().Status.SentCount
+	time.Sleep(20 * time.Millisecond)
+	if count := tap.GetTask().Status.SentCount; count != paused {
+		t.Fatalf("paused task kept sending: %d -> %d", paused, count)
+	}
+
+	invalid := spec.DeepCopy()
+	invalid.Interval = -1
+	if err = tap.UpdateSpec(invalid); err == nil {
+		t.Fatal("invalid spec accepted")
+	}
+	updated := spec.DeepCopy()
+	updated.Message = "after"
+	if err = tap.UpdateSpec(updated); err != nil {
+		t.Fatal(err.Error())
+	}
+	if phase := tap.GetTask().Status.Phase; phase != model.PhasePaused {
+		t.Fatalf("unexpected phase after update: want %s; got %s", model.PhasePaused, phase)
+	}
+	if err = tap.Resume(); err != nil {
+		t.Fatal(err.Error())
+	}
+	waitForCount(t, tap, paused+1)
+
+	close(stopCh)
+	if err = <-errCh; err != nil {
+		t.Fatal(err.Error())
+	}
+	task := tap.GetTask()
+	if task.Status.Phase != model.PhaseStopped || task.Spec.Message != "after" {
+		t.Fatalf("unexpected task after stop: %+v %+v", task.Spec, task.Status)
+	}
+	data, err := ioutil.ReadFile(spec.Filepath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	content := string(data)
+	if !strings.Contains(content, " before\n") || !strings.Contains(content, " after\n") {
+		t.Fatalf("unexpected file content: %q", content)
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if int64(len(lines)) != task.Status.SentCount {
+		t.Fatalf("counters were reset: %d lines in file; sentCount %d", len(lines), task.Status.SentCount)
+	}
+	for i, line := range lines {
+		if want := fmt.Sprintf("[test] seq=%d ", i+1); !strings.HasPrefix(line, want) {
+			t.Fatalf("sequence was reset: want prefix %q; got %q", want, line)
+		}
+	}
+	report := tap.Report()
+	if report.SentCount != task.Status.SentCount || report.Spec.Message != "after" ||
+		report.StopTime.Before(report.StartTime) || report.ActiveDuration >= report.Duration {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if math.Abs(report.TargetLogsPerSecond-1000) > 1e-6 || report.LogsPerSecond <= 0 {
+		t.Fatalf("unexpected rates in report: %+v", report)
+	}
+	if latency := report.WriteLatency; latency.P50 <= 0 || latency.P50 > latency.P99 || latency.Max <= 0 {
+		t.Fatalf("unexpected write latency in report: %+v", latency)
+	}
+	if err = tap.Resume(); err != ErrTaskNotRunning {
+		t.Fatalf("unexpected error resuming a stopped task: %v", err)
+	}
+}
+
+func TestManager_Limits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	newSpec := func(name string) *model.LogTaskSpec {
+		return &model.LogTaskSpec{
+			OutputKind:          model.OutputKindFile,
+			Filepath:            filepath.Join(dir, name+".log"),
+			ContentType:         model.ContentTypeExplicit,
+			Message:             "hello",
+			TargetLogsPerSecond: 100000,
+		}
+	}
+	count := newSpec("count")
+	count.MaxCount = 1234
+	size := newSpec("size")
+	size.MaxBytes = 10000
+	workers := newSpec("workers")
+	workers.MaxBytes, workers.Workers = 10000, 4
+	duration := newSpec("duration")
+	duration.TargetLogsPerSecond, duration.Interval, duration.Duration = 0, 0.01, 0.1
+	manager := NewManager()
+	specs := map[string]*model.LogTaskSpec{"count": count, "size": size, "workers": workers, "duration": duration}
+	for name, spec := range specs {
+		if _, err = manager.Create(name, spec); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	returned := make(chan struct{})
+	go func() {
+		manager.Run(make(chan struct{}))
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager kept running after every task had reached its limit")
+	}
+	reasons := map[string]string{
+		"count": "maxCount of ", "size": "maxBytes of ", "workers": "maxBytes of ", "duration": "duration of ",
+	}
+	for _, task := range manager.List().LogTasks {
+		status := task.Status
+		if status.Phase != model.PhaseStopped || !strings.HasPrefix(status.Reason, reasons[task.Name]) {
+			t.Fatalf("unexpected status of %s: %+v", task.Name, status)
+		}
+		data, err := ioutil.ReadFile(task.Spec.Filepath)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		lines := int64(strings.Count(string(data), "\n"))
+		switch task.Name {
+		case "count":
+			if lines != count.MaxCount || status.SentCount != count.MaxCount {
+				t.Fatalf("unexpected count: %d lines; sentCount %d", lines, status.SentCount)
+			}
+		case "size", "workers":
+			// the workers stop at the same size as a single writer
+			overshoot := status.SentBytes - size.MaxBytes
+			if overshoot < 0 || overshoot > 2*status.SentBytes/status.SentCount {
+				t.Fatalf("unexpected size: sentBytes %d", status.SentBytes)
+			}
+		}
+	}
+}
+
+func TestLogTap_Workers(t *testing.T) {
+	const workers, maxCount = 4, 4000
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	for _, writer := range []string{model.WorkerWriterShared, model.WorkerWriterPerWorker} {
+		t.Run(writer, func(t *testing.T) {
+			spec := &model.LogTaskSpec{
+				OutputKind:          model.OutputKindFile,
+				Filepath:            filepath.Join(dir, writer+".log"),
+				ContentType:         model.ContentTypeExplicit,
+				Message:             "hello",
+				TargetLogsPerSecond: 100000,
+				MaxCount:            maxCount,
+				Workers:             workers,
+				WorkerWriter:        writer,
+			}
+			if writer == model.WorkerWriterPerWorker {
+				spec.Filepath = filepath.Join(dir, writer+"-%d.log")
+			}
+			tap, err := NewLogTap(spec, "test")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if err = tap.Run(make(chan struct{})); err != nil {
+				t.Fatal(err.Error())
+			}
+			status := tap.GetTask().Status
+			if status.SentCount != maxCount || len(status.Workers) != workers {
+				t.Fatalf("unexpected status: %+v", status)
+			}
+			paths := []string{spec.Filepath}
+			if writer == model.WorkerWriterPerWorker {
+				paths = paths[:0]
+				for i := 0; i < workers; i++ {
+					paths = append(paths, fmt.Sprintf(spec.Filepath, i))
+				}
+			}
+			last := make(map[string]int64)
+			for i, path := range paths {
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				for _, line := range strings.SplitAfter(string(data), "\n") {
+					if len(line) == 0 {
+						continue
+					}
+					record, ok := logger.ParseRecord([]byte(line))
+					if !ok {
+						t.Fatalf("unrecognized log: %q", line)
+					}
+					if record.Sequence != last[record.Name]+1 {
+						t.Fatalf("unexpected log after seq=%d: %q", last[record.Name], line)
+					}
+					if want := fmt.Sprintf("test/%d", i); len(paths) > 1 && record.Name != want {
+						t.Fatalf("unexpected log of %s in the file of %s", record.Name, want)
+					}
+					last[record.Name] = record.Sequence
+				}
+			}
+			for _, worker := range status.Workers {
+				if last[worker.Name] != worker.SentCount {
+					t.Fatalf("unexpected count of %s: %d logs; sentCount %d", worker.Name, last[worker.Name],
+						worker.SentCount)
+				}
+			}
+		})
+	}
+}
+
+func TestLogTap_WorkersInterval(t *testing.T) {
+	const workers, interval = 2, 0.01
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	tap, err := NewLogTap(&model.LogTaskSpec{
+		OutputKind:  model.OutputKindFile,
+		Filepath:    filepath.Join(dir, "writer.log"),
+		ContentType: model.ContentTypeExplicit,
+		Message:     "hello",
+		Interval:    interval,
+		MaxCount:    20,
+		Workers:     workers,
+	}, "test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = tap.Run(make(chan struct{})); err != nil {
+		t.Fatal(err.Error())
+	}
+	// every worker sends a log per interval
+	if report := tap.Report(); math.Abs(report.TargetLogsPerSecond-workers/interval) > 1e-6 {
+		t.Fatalf("unexpected target rate: want %v; got %v", workers/interval, report.TargetLogsPerSecond)
+	}
+}
+
+func TestLogTap_RecordWrite(t *testing.T) {
+	tap, err := NewLogTap(&model.LogTaskSpec{
+		OutputKind:  model.OutputKindStdOut,
+		ContentType: model.ContentTypeExplicit,
+		Message:     "hello",
+		Interval:    1,
+	}, "test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	impl := tap.(*logTapImpl)
+	impl.recordWrite(-1, 10, time.Millisecond, nil)
+	impl.recordWrite(-1, 10, time.Millisecond, output.ErrDropped)
+	impl.recordWrite(-1, 0, time.Millisecond, errors.New("broken"))
+	status := tap.GetTask().Status
+	// a dropped log message has a sequence number like the sent ones, so it is counted as sent for verify
+	if status.SentCount != 2 || status.SentBytes != 20 || status.DroppedWrites != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if report := tap.Report(); report.WriteErrors != 1 || report.DroppedWrites != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func waitForCount(t *testing.T, tap LogTap, count int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for tap.GetTask().Status.SentCount < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d logs", count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
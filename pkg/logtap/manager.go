@@ -0,0 +1,271 @@
+package logtap
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+var (
+	// ErrTaskExists is returned when creating a LogTask whose name is already taken.
+	ErrTaskExists = errors.New("task already exists")
+
+	// ErrTaskNotFound is returned when the requested LogTask does not exist.
+	ErrTaskNotFound = errors.New("task not found")
+
+	// ErrManagerStopped is returned when creating a LogTask after the Manager has stopped.
+	ErrManagerStopped = errors.New("manager stopped")
+)
+
+// A Manager hosts multiple named LogTaps, each running in its own goroutine.
+type Manager interface {
+	// Create creates a LogTap with the given name and LogTaskSpec and starts it right away. It returns a copy of
+	// the created LogTask.
+	Create(name string, spec *model.LogTaskSpec) (*model.LogTask, error)
+
+	// Get returns a copy of the LogTask with the given name.
+	Get(name string) (*model.LogTask, error)
+
+	// List returns copies of all the LogTasks, sorted by name.
+	List() *model.LogTaskList
+
+	// Metrics returns the Metrics of all the LogTaps, sorted by name.
+	Metrics() []*Metrics
+
+	// Report returns the reports of all the LogTaps; it is meant to be called after Run has returned.
+	Report() *Report
+
+	// Pause pauses the LogTap with the given name and returns a copy of its LogTask.
+	Pause(name string) (*model.LogTask, error)
+
+	// Resume resumes the LogTap with the given name and returns a copy of its LogTask.
+	Resume(name string) (*model.LogTask, error)
+
+	// UpdateSpec replaces the LogTaskSpec of the LogTap with the given name and returns a copy of its LogTask.
+	UpdateSpec(name string, spec *model.LogTaskSpec) (*model.LogTask, error)
+
+	// Delete stops the LogTap with the given name, waits for it to return, and removes it. It returns a copy of
+	// the LogTask as it was when the LogTap stopped.
+	Delete(name string) (*model.LogTask, error)
+
+	// Run blocks until the stopCh is closed, after which it stops all LogTaps and waits for them to return. It
+	// also returns once every LogTap, if there is any, has returned on its own, such as after reaching a limit of
+	// its LogTaskSpec.
+	Run(stopCh <-chan struct{})
+}
+
+type managedLogTap struct {
+	tap      LogTap
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// returned is notified after the LogTap has returned.
+	returned chan<- struct{}
+}
+
+type managerImpl struct {
+	taps     map[string]*managedLogTap
+	stopped  bool
+	mutex    sync.RWMutex
+	returned chan struct{}
+}
+
+// NewManager creates an empty Manager.
+func NewManager() Manager {
+	return &managerImpl{
+		taps:     make(map[string]*managedLogTap),
+		returned: make(chan struct{}, 1),
+	}
+}
+
+func (m *managerImpl) Create(name string, spec *model.LogTaskSpec) (*model.LogTask, error) {
+	tap, err := NewLogTap(spec, name)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped {
+		return nil, ErrManagerStopped
+	}
+	if _, exist := m.taps[name]; exist {
+		return nil, ErrTaskExists
+	}
+	managed := &managedLogTap{
+		tap:    tap,
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+
+		returned: m.returned,
+	}
+	m.taps[name] = managed
+	go managed.run()
+	return tap.GetTask(), nil
+}
+
+func (m *managerImpl) Get(name string) (*model.LogTask, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	managed, exist := m.taps[name]
+	if !exist {
+		return nil, ErrTaskNotFound
+	}
+	return managed.tap.GetTask(), nil
+}
+
+func (m *managerImpl) Pause(name string) (*model.LogTask, error) {
+	return m.do(name, func(tap LogTap) error {
+		return tap.Pause()
+	})
+}
+
+func (m *managerImpl) Resume(name string) (*model.LogTask, error) {
+	return m.do(name, func(tap LogTap) error {
+		return tap.Resume()
+	})
+}
+
+func (m *managerImpl) UpdateSpec(name string, spec *model.LogTaskSpec) (*model.LogTask, error) {
+	return m.do(name, func(tap LogTap) error {
+		return tap.UpdateSpec(spec)
+	})
+}
+
+// do calls the given function with the LogTap of the given name and returns a copy of its LogTask afterwards.
+func (m *managerImpl) do(name string, f func(LogTap) error) (*model.LogTask, error) {
+	m.mutex.RLock()
+	managed, exist := m.taps[name]
+	m.mutex.RUnlock()
+	if !exist {
+		return nil, ErrTaskNotFound
+	}
+	if err := f(managed.tap); err != nil {
+		return nil, err
+	}
+	return managed.tap.GetTask(), nil
+}
+
+func (m *managerImpl) List() *model.LogTaskList {
+	m.mutex.RLock()
+	ret := &model.LogTaskList{
+		LogTasks: make([]model.LogTask, 0, len(m.taps)),
+	}
+	for _, managed := range m.taps {
+		ret.LogTasks = append(ret.LogTasks, *managed.tap.GetTask())
+	}
+	m.mutex.RUnlock()
+	sort.Slice(ret.LogTasks, func(i, j int) bool {
+		return ret.LogTasks[i].Name < ret.LogTasks[j].Name
+	})
+	ret.Total = len(ret.LogTasks)
+	return ret
+}
+
+func (m *managerImpl) Metrics() []*Metrics {
+	m.mutex.RLock()
+	ret := make([]*Metrics, 0, len(m.taps))
+	for _, managed := range m.taps {
+		ret = append(ret, managed.tap.GetMetrics())
+	}
+	m.mutex.RUnlock()
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Task.Name < ret[j].Task.Name
+	})
+	return ret
+}
+
+func (m *managerImpl) Report() *Report {
+	m.mutex.RLock()
+	ret := &Report{
+		Tasks: make([]TaskReport, 0, len(m.taps)),
+	}
+	for _, managed := range m.taps {
+		ret.Tasks = append(ret.Tasks, *managed.tap.Report())
+	}
+	m.mutex.RUnlock()
+	sort.Slice(ret.Tasks, func(i, j int) bool {
+		return ret.Tasks[i].Name < ret.Tasks[j].Name
+	})
+	return ret
+}
+
+func (m *managerImpl) Delete(name string) (*model.LogTask, error) {
+	m.mutex.Lock()
+	managed, exist := m.taps[name]
+	if !exist {
+		m.mutex.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	delete(m.taps, name)
+	m.mutex.Unlock()
+	managed.stop()
+	return managed.tap.GetTask(), nil
+}
+
+func (m *managerImpl) Run(stopCh <-chan struct{}) {
+	for running := true; running; {
+		select {
+		case <-stopCh:
+			running = false
+		case <-m.returned:
+			running = !m.allReturned()
+		}
+	}
+	m.mutex.Lock()
+	m.stopped = true
+	taps := make([]*managedLogTap, 0, len(m.taps))
+	for _, managed := range m.taps {
+		taps = append(taps, managed)
+	}
+	m.mutex.Unlock()
+	for _, managed := range taps {
+		managed.signal()
+	}
+	for _, managed := range taps {
+		<-managed.done
+	}
+}
+
+// allReturned returns true if there is any LogTap and all of them have returned.
+func (m *managerImpl) allReturned() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, managed := range m.taps {
+		select {
+		case <-managed.done:
+		default:
+			return false
+		}
+	}
+	return len(m.taps) > 0
+}
+
+func (mt *managedLogTap) run() {
+	defer func() {
+		close(mt.done)
+		select {
+		case mt.returned <- struct{}{}:
+		default:
+		}
+	}()
+	if err := mt.tap.Run(mt.stopCh); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// signal closes the stop channel of the LogTap; it is safe to be called more than once.
+func (mt *managedLogTap) signal() {
+	mt.stopOnce.Do(func() {
+		close(mt.stopCh)
+	})
+}
+
+// stop signals the LogTap to stop and waits for it to return.
+func (mt *managedLogTap) stop() {
+	mt.signal()
+	<-mt.done
+}
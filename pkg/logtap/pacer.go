@@ -0,0 +1,209 @@
+package logtap
+
+import (
+	"math"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	// maxBurst is the longest period of time for which the unused budget of a pacer is kept.
+	maxBurst = time.Second
+
+	// minWait is the shortest wait in-between two batches of logs; higher rates are reached by sending batches.
+	minWait = time.Millisecond
+
+	// rateWindow is the period of time over which the achieved rates are measured.
+	rateWindow = time.Second
+)
+
+// pacer is a token bucket that decides how many logs can be sent to meet the target rates. The budget grows with
+// the time elapsed, including the time spent writing, so slow writes are made up for by larger batches. If a
+// profile is given, it decides the number of logs to be sent per second.
+type pacer struct {
+	limitLogs      bool
+	limitBytes     bool
+	logsPerSecond  float64
+	bytesPerSecond float64
+	logTokens      float64
+	byteTokens     float64
+	last           time.Time
+	profile        *profile
+}
+
+// newPacer creates a pacer for the target rates or profile of the given LogTaskSpec, or returns nil if there is
+// none.
+func newPacer(spec *model.LogTaskSpec, now time.Time) *pacer {
+	if spec.TargetLogsPerSecond <= 0 && spec.TargetBytesPerSecond <= 0 && spec.Profile == nil {
+		return nil
+	}
+	ret := &pacer{
+		limitLogs:      spec.TargetLogsPerSecond > 0 || spec.Profile != nil,
+		limitBytes:     spec.TargetBytesPerSecond > 0,
+		logsPerSecond:  spec.TargetLogsPerSecond,
+		bytesPerSecond: spec.TargetBytesPerSecond,
+	}
+	if spec.Profile != nil {
+		ret.profile = newProfile(spec.Profile, now)
+	}
+	ret.reset(now)
+	return ret
+}
+
+// reset discards the budget, usually after the pacer has not been used for a while.
+func (p *pacer) reset(now time.Time) {
+	p.logTokens, p.byteTokens, p.last = 1, 1, now
+	if p.profile != nil {
+		p.logTokens = 0
+	}
+}
+
+// pause stops the time of the profile, if any.
+func (p *pacer) pause(now time.Time) {
+	if p.profile != nil {
+		p.profile.pause(now)
+	}
+}
+
+// resume restarts the time of the profile, if any, and discards the budget.
+func (p *pacer) resume(now time.Time) {
+	if p.profile != nil {
+		p.profile.resume(now)
+	}
+	p.reset(now)
+}
+
+// refill grows the budget according to the time elapsed since the last refill.
+func (p *pacer) refill(now time.Time) {
+	elapsed := now.Sub(p.last).Seconds()
+	p.last = now
+	if p.profile != nil {
+		p.logsPerSecond = p.profile.rate(now)
+		p.logTokens += p.profile.dueBursts(now)
+	}
+	if p.limitLogs {
+		p.logTokens = grow(p.logTokens, elapsed*p.logsPerSecond, maxFloat(1, p.logsPerSecond*maxBurst.Seconds()))
+	}
+	if p.limitBytes {
+		p.byteTokens = grow(p.byteTokens, elapsed*p.bytesPerSecond, p.bytesPerSecond*maxBurst.Seconds())
+	}
+}
+
+// allow returns true if the budget allows another log to be sent. The size of a log is not known until it is
+// written, so a log is allowed as long as there is any byte budget left, and the overdraft is paid back later.
+func (p *pacer) allow() bool {
+	return (!p.limitLogs || p.logTokens >= 1) && (!p.limitBytes || p.byteTokens > 0)
+}
+
+// consume takes a log of the given size out of the budget.
+func (p *pacer) consume(size int) {
+	p.logTokens--
+	p.consumeBytes(size)
+}
+
+// consumeBytes takes the size of a log out of the budget once it is known, after the log was taken out by consume
+// with a size of zero.
+func (p *pacer) consumeBytes(size int) {
+	p.byteTokens -= float64(size)
+}
+
+// wait returns how long it takes for the budget to allow the next log.
+func (p *pacer) wait(now time.Time) time.Duration {
+	maxWait := time.Duration(math.MaxInt64)
+	if p.profile != nil {
+		maxWait = p.profile.maxWait(now)
+	}
+	var seconds float64
+	if p.limitLogs && p.logTokens < 1 {
+		if p.logsPerSecond <= 0 {
+			seconds = maxWait.Seconds()
+		} else {
+			seconds = (1 - p.logTokens) / p.logsPerSecond
+		}
+	}
+	if p.limitBytes && p.byteTokens <= 0 {
+		seconds = maxFloat(seconds, (1-p.byteTokens)/p.bytesPerSecond)
+	}
+	wait := time.Duration(seconds * float64(time.Second))
+	if wait > maxWait {
+		wait = maxWait
+	}
+	if wait < minWait {
+		return minWait
+	}
+	return wait
+}
+
+// targetLogsPerSecond returns the number of logs that should currently be sent per second, or zero if the
+// number of logs is not limited.
+func (p *pacer) targetLogsPerSecond(now time.Time) float64 {
+	if p.profile != nil {
+		return p.profile.averageRate(now)
+	}
+	return p.logsPerSecond
+}
+
+// rateMeter measures the rates at which logs are sent over consecutive windows.
+type rateMeter struct {
+	start time.Time
+	count int64
+	bytes int64
+}
+
+func (m *rateMeter) reset(now time.Time) {
+	m.start, m.count, m.bytes = now, 0, 0
+}
+
+// record adds the sent logs to the current window. If the window has ended, it returns the rates measured over
+// the window along with true, and a new window is started.
+func (m *rateMeter) record(now time.Time, count, bytes int64) (float64, float64, bool) {
+	m.count += count
+	m.bytes += bytes
+	elapsed := now.Sub(m.start)
+	if elapsed < rateWindow {
+		return 0, 0, false
+	}
+	logsPerSecond, bytesPerSecond := float64(m.count)/elapsed.Seconds(), float64(m.bytes)/elapsed.Seconds()
+	m.reset(now)
+	return logsPerSecond, bytesPerSecond, true
+}
+
+// getShortfall returns the fraction by which the achieved rates fall short of the target rates; a target rate of
+// zero means there is no target. If both target rates are set, the smaller shortfall is returned because only the
+// stricter target can be met.
+func getShortfall(targetLogs, targetBytes, logsPerSecond, bytesPerSecond float64) float64 {
+	ret := -1.
+	if targetLogs > 0 {
+		ret = maxFloat(0, 1-logsPerSecond/targetLogs)
+	}
+	if targetBytes > 0 {
+		shortfall := maxFloat(0, 1-bytesPerSecond/targetBytes)
+		if ret < 0 || shortfall < ret {
+			ret = shortfall
+		}
+	}
+	return maxFloat(0, ret)
+}
+
+// grow adds the given amount to the budget without letting it exceed the limit through growth alone.
+func grow(budget, amount, limit float64) float64 {
+	if budget >= limit {
+		return budget
+	}
+	return minFloat(budget+amount, limit)
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,153 @@
+package logtap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestPacer(t *testing.T) {
+	const (
+		size    = 100
+		seconds = 3
+		step    = 7 * time.Millisecond
+	)
+	testCases := []struct {
+		name  string
+		spec  *model.LogTaskSpec
+		count int
+	}{
+		{
+			name:  "LogsPerSecond",
+			spec:  &model.LogTaskSpec{TargetLogsPerSecond: 50000},
+			count: 50000 * seconds,
+		},
+		{
+			name:  "BytesPerSecond",
+			spec:  &model.LogTaskSpec{TargetBytesPerSecond: 20000},
+			count: 20000 / size * seconds,
+		},
+		{
+			name:  "StricterTarget",
+			spec:  &model.LogTaskSpec{TargetLogsPerSecond: 1000, TargetBytesPerSecond: 20000},
+			count: 20000 / size * seconds,
+		},
+		{
+			name:  "SlowLogsPerSecond",
+			spec:  &model.LogTaskSpec{TargetLogsPerSecond: 0.5},
+			count: seconds/2 + 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			p := newPacer(tc.spec, now)
+			sent := 0
+			for end := now.Add(seconds * time.Second); !now.After(end); now = now.Add(step) {
+				p.refill(now)
+				for p.allow() {
+					p.consume(size)
+					sent++
+				}
+			}
+			if diff := sent - tc.count; diff < -tc.count/100-1 || diff > tc.count/100+1 {
+				t.Fatalf("unexpected number of logs: want about %d; got %d", tc.count, sent)
+			}
+		})
+	}
+}
+
+func TestGetShortfall(t *testing.T) {
+	testCases := []struct {
+		name        string
+		targetLogs  float64
+		targetBytes float64
+		logs        float64
+		want        float64
+	}{
+		{"TargetMet", 10, 0, 12, 0},
+		{"TargetMissed", 10, 0, 7.5, 0.25},
+		{"StricterTarget", 100, 1000, 10, 0},
+		{"BytesTarget", 0, 2000, 10, 0.5},
+		{"NoTarget", 0, 0, 10, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getShortfall(tc.targetLogs, tc.targetBytes, tc.logs, tc.logs*100); got != tc.want {
+				t.Fatalf("unexpected shortfall: want %f; got %f", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestProfile_Rate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		spec  *model.ProfileSpec
+		rates map[float64]float64
+	}{
+		{
+			name:  "Ramp",
+			spec:  &model.ProfileSpec{Kind: model.ProfileKindRamp, From: 10, To: 110, Duration: 10},
+			rates: map[float64]float64{0: 10, 5: 60, 10: 110, 20: 110},
+		},
+		{
+			name: "Step",
+			spec: &model.ProfileSpec{Kind: model.ProfileKindStep, Steps: []model.ProfileStep{
+				{Rate: 1, Duration: 10}, {Rate: 2, Duration: 5},
+			}},
+			rates: map[float64]float64{0: 1, 9: 1, 10: 2, 14: 2, 16: 2},
+		},
+		{
+			name: "StepRepeat",
+			spec: &model.ProfileSpec{Kind: model.ProfileKindStep, Repeat: true, Steps: []model.ProfileStep{
+				{Rate: 1, Duration: 10}, {Rate: 2, Duration: 5},
+			}},
+			rates: map[float64]float64{0: 1, 10: 2, 16: 1, 26: 2},
+		},
+		{
+			name:  "Sine",
+			spec:  &model.ProfileSpec{Kind: model.ProfileKindSine, Base: 100, Amplitude: 50, Period: 4},
+			rates: map[float64]float64{0: 100, 1: 150, 3: 50},
+		},
+		{
+			name:  "Burst",
+			spec:  &model.ProfileSpec{Kind: model.ProfileKindBurst, Base: 5, BurstSize: 100, Period: 10},
+			rates: map[float64]float64{0: 5, 15: 5},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			p := newProfile(tc.spec, start)
+			for second, want := range tc.rates {
+				now := start.Add(time.Duration(second * float64(time.Second)))
+				if got := p.rate(now); math.Abs(got-want) > 1e-9 {
+					t.Fatalf("unexpected rate at %fs: want %f; got %f", second, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPacer_Burst(t *testing.T) {
+	const step = 10 * time.Millisecond
+	spec := &model.LogTaskSpec{
+		Profile: &model.ProfileSpec{Kind: model.ProfileKindBurst, Base: 10, BurstSize: 100, Period: 1},
+	}
+	now := time.Now()
+	p := newPacer(spec, now)
+	sent := 0
+	for end := now.Add(2500 * time.Millisecond); now.Before(end); now = now.Add(step) {
+		p.refill(now)
+		for p.allow() {
+			p.consume(1)
+			sent++
+		}
+	}
+	if want := 3*100 + 25; sent < want-2 || sent > want+2 {
+		t.Fatalf("unexpected number of logs: want about %d; got %d", want, sent)
+	}
+}
@@ -0,0 +1,113 @@
+package logtap
+
+import (
+	"math"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// maxProfileWait is the longest wait in-between two batches of logs when following a profile, so that the changes
+// of the rate are picked up in time.
+const maxProfileWait = 100 * time.Millisecond
+
+// profile computes the target rate of a ProfileSpec over time; the time of the profile stops while it is paused.
+type profile struct {
+	spec     *model.ProfileSpec
+	start    time.Time
+	pausedAt time.Time
+	paused   bool
+	bursts   int64
+}
+
+func newProfile(spec *model.ProfileSpec, now time.Time) *profile {
+	return &profile{
+		spec:  spec,
+		start: now,
+	}
+}
+
+func (p *profile) pause(now time.Time) {
+	if !p.paused {
+		p.paused, p.pausedAt = true, now
+	}
+}
+
+func (p *profile) resume(now time.Time) {
+	if p.paused {
+		p.paused = false
+		p.start = p.start.Add(now.Sub(p.pausedAt))
+	}
+}
+
+// elapsed returns the time of the profile in seconds.
+func (p *profile) elapsed(now time.Time) float64 {
+	if p.paused {
+		now = p.pausedAt
+	}
+	return now.Sub(p.start).Seconds()
+}
+
+// rate returns the number of logs to be sent per second at the given time, excluding the bursts.
+func (p *profile) rate(now time.Time) float64 {
+	t := p.elapsed(now)
+	switch p.spec.Kind {
+	case model.ProfileKindRamp:
+		if t >= p.spec.Duration {
+			return p.spec.To
+		}
+		return p.spec.From + (p.spec.To-p.spec.From)*t/p.spec.Duration
+	case model.ProfileKindStep:
+		var total float64
+		for _, step := range p.spec.Steps {
+			total += step.Duration
+		}
+		if p.spec.Repeat {
+			t = math.Mod(t, total)
+		}
+		for _, step := range p.spec.Steps {
+			if t < step.Duration {
+				return step.Rate
+			}
+			t -= step.Duration
+		}
+		return p.spec.Steps[len(p.spec.Steps)-1].Rate
+	case model.ProfileKindSine:
+		return p.spec.Base + p.spec.Amplitude*math.Sin(2*math.Pi*t/p.spec.Period)
+	default:
+		return p.spec.Base
+	}
+}
+
+// averageRate returns the number of logs to be sent per second at the given time, with the bursts spread evenly
+// over their period.
+func (p *profile) averageRate(now time.Time) float64 {
+	ret := p.rate(now)
+	if p.spec.Kind == model.ProfileKindBurst {
+		ret += float64(p.spec.BurstSize) / p.spec.Period
+	}
+	return ret
+}
+
+// dueBursts returns the number of logs of the bursts that are due since the last call. The first burst is due
+// when the profile starts.
+func (p *profile) dueBursts(now time.Time) float64 {
+	if p.spec.Kind != model.ProfileKindBurst {
+		return 0
+	}
+	count := int64(p.elapsed(now)/p.spec.Period) + 1
+	due := count - p.bursts
+	p.bursts = count
+	return float64(due) * float64(p.spec.BurstSize)
+}
+
+// maxWait returns how long the pacer can wait before the profile needs to be looked at again.
+func (p *profile) maxWait(now time.Time) time.Duration {
+	if p.spec.Kind == model.ProfileKindBurst {
+		next := float64(p.bursts) * p.spec.Period
+		if wait := time.Duration((next - p.elapsed(now)) * float64(time.Second)); wait < maxProfileWait {
+			return wait
+		}
+	}
+	return maxProfileWait
+}
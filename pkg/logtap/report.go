@@ -0,0 +1,82 @@
+package logtap
+
+import (
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// Report summarizes how the LogTaps hosted by a Manager went; it is meant to be taken after they have stopped.
+type Report struct {
+	// Tasks are the reports of the LogTaps, sorted by name.
+	Tasks []TaskReport `json:"tasks"`
+}
+
+// TaskReport summarizes how a LogTap went from the time Run was called to the time it returned, or to the time the
+// report was taken if it is still running.
+type TaskReport struct {
+	// Name is the name of the task.
+	Name string `json:"name"`
+
+	// Phase and Reason are those of the task when the report was taken.
+	Phase  string `json:"phase"`
+	Reason string `json:"reason,omitempty"`
+
+	// StartTime is the time at which Run was called; it is zero if Run was never called.
+	StartTime time.Time `json:"startTime"`
+
+	// StopTime is the time at which Run returned; it is zero if Run has not returned.
+	StopTime time.Time `json:"stopTime"`
+
+	// Duration is the time in seconds from StartTime to StopTime.
+	Duration float64 `json:"duration"`
+
+	// ActiveDuration is the time in seconds spent generating log messages, leaving out the pauses.
+	ActiveDuration float64 `json:"activeDuration"`
+
+	// SentCount and SentBytes are the number and the size in bytes of the log messages sent.
+	SentCount int64 `json:"sentCount"`
+	SentBytes int64 `json:"sentBytes"`
+
+	// LogsPerSecond and BytesPerSecond are the average rates achieved over the ActiveDuration.
+	LogsPerSecond  float64 `json:"logsPerSecond"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+
+	// TargetLogsPerSecond and TargetBytesPerSecond are the average rates targeted over the ActiveDuration; they
+	// are zero if the rate was not limited in that way.
+	TargetLogsPerSecond  float64 `json:"targetLogsPerSecond,omitempty"`
+	TargetBytesPerSecond float64 `json:"targetBytesPerSecond,omitempty"`
+
+	// Shortfall is the fraction, from 0 to 1, by which the average achieved rate fell short of the average target
+	// rate; 0 means the target was met.
+	Shortfall float64 `json:"shortfall"`
+
+	// WriteLatency summarizes the time taken to write a single log message.
+	WriteLatency LatencyReport `json:"writeLatency"`
+
+	// WriteErrors is the number of log messages that failed to be written; the ones dropped by the output are only
+	// counted in DroppedWrites.
+	WriteErrors int64 `json:"writeErrors"`
+
+	// DroppedWrites, Reconnects and HTTPErrors are those of the status; HTTPErrors counts the HTTP requests that
+	// got no response or a response with a status code of 400 or above.
+	DroppedWrites int64 `json:"droppedWrites"`
+	Reconnects    int64 `json:"reconnects"`
+	HTTPErrors    int64 `json:"httpErrors"`
+
+	// Flushes and Fsyncs are those of the status.
+	Flushes int64 `json:"flushes,omitempty"`
+	Fsyncs  int64 `json:"fsyncs,omitempty"`
+
+	// Spec is the final LogTaskSpec of the task.
+	Spec *model.LogTaskSpec `json:"spec"`
+}
+
+// LatencyReport summarizes latencies in seconds; the quantiles are estimated from the histogram buckets.
+type LatencyReport struct {
+	Average float64 `json:"average"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+	Max     float64 `json:"max"`
+}
@@ -0,0 +1,408 @@
+package logtap
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"github.com/lichuan0620/logtap/pkg/output"
+)
+
+// maxBatchDuration is the longest period of time a batch of logs can take before the pending commands are
+// handled; the rest of the batch is sent right after.
+const maxBatchDuration = 100 * time.Millisecond
+
+// runner holds the state of a LogTap while Run is in progress; it is only accessed by the goroutine executing Run,
+// except for the workers sending a batch of logs in parallel, which share the pacer and the stopReason under the
+// mutex.
+type runner struct {
+	tap     *logTapImpl
+	spec    *model.LogTaskSpec
+	outs    []io.WriteCloser
+	workers []*worker
+	pacer   *pacer
+	meter   rateMeter
+	timer   *time.Timer
+	paused  bool
+
+	// mutex guards the pacer, the stopReason, inFlight, inFlightBytes and lastSize while a batch is being sent.
+	mutex sync.Mutex
+
+	// inFlight is the number of logs being written by the workers, and inFlightBytes is their estimated size, which
+	// is the size of the last log written when they were reserved.
+	inFlight      int64
+	inFlightBytes int64
+	lastSize      int64
+
+	// windowTarget is the target logs per second when the current window of the meter started.
+	windowTarget float64
+
+	// accounted is the time up to which the active time has been recorded.
+	accounted time.Time
+
+	// deadline fires when the Duration of the spec has passed; it is nil if the spec has no Duration.
+	deadline *time.Timer
+
+	// stopReason is set by tick when a limit of the spec has been reached.
+	stopReason string
+}
+
+func newRunner(tap *logTapImpl, spec *model.LogTaskSpec, paused bool) (*runner, error) {
+	outs, workers, err := tap.open(spec)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ret := &runner{
+		tap:     tap,
+		spec:    spec,
+		outs:    outs,
+		workers: workers,
+		pacer:   newPacer(spec, now),
+		timer:   time.NewTimer(0),
+		paused:  paused,
+
+		accounted: now,
+	}
+	ret.resetMeter(now)
+	ret.setDeadline()
+	if paused {
+		if ret.pacer != nil {
+			ret.pacer.pause(now)
+		}
+		stopTimer(ret.timer)
+		tap.setPhase(model.PhasePaused, "")
+	} else {
+		tap.setPhase(model.PhaseRunning, "")
+	}
+	return ret, nil
+}
+
+func (r *runner) close() {
+	r.account(time.Now())
+	r.timer.Stop()
+	if r.deadline != nil {
+		r.deadline.Stop()
+	}
+	closeAll(r.outs)
+}
+
+// handle executes the given command and returns the result.
+func (r *runner) handle(cmd command) error {
+	switch cmd.kind {
+	case commandPause:
+		r.pause()
+	case commandResume:
+		r.resume()
+	case commandUpdate:
+		return r.update(cmd.spec)
+	}
+	return nil
+}
+
+func (r *runner) pause() {
+	if r.paused {
+		return
+	}
+	r.account(time.Now())
+	r.paused = true
+	if r.pacer != nil {
+		r.pacer.pause(time.Now())
+	}
+	stopTimer(r.timer)
+	r.tap.setRates(0, 0, 0, 0)
+	r.tap.setPhase(model.PhasePaused, "")
+}
+
+func (r *runner) resume() {
+	if !r.paused {
+		return
+	}
+	r.paused = false
+	r.restart()
+	r.tap.setPhase(model.PhaseRunning, "")
+}
+
+// update rebuilds the output and the content generator with the given LogTaskSpec; the current ones are kept if
+// the new ones cannot be created.
+func (r *runner) update(spec *model.LogTaskSpec) error {
+	r.tap.setPhase(model.PhaseUpdating, "")
+	r.account(time.Now())
+	outs, workers, err := r.tap.open(spec)
+	if err == nil {
+		closeAll(r.outs)
+		r.spec, r.outs, r.workers = spec, outs, workers
+		r.pacer = newPacer(spec, time.Now())
+		r.setDeadline()
+		if r.paused && r.pacer != nil {
+			r.pacer.pause(time.Now())
+		}
+		r.tap.setSpec(spec)
+		if !r.paused {
+			r.restart()
+		}
+	}
+	if r.paused {
+		r.tap.setPhase(model.PhasePaused, "")
+	} else {
+		r.tap.setPhase(model.PhaseRunning, "")
+	}
+	return err
+}
+
+// restart resumes the pacing after a pause or an update and sends the logs that are due right away.
+func (r *runner) restart() {
+	now := time.Now()
+	if r.pacer != nil {
+		r.pacer.resume(now)
+	}
+	r.resetMeter(now)
+	r.accounted = now
+	stopTimer(r.timer)
+	r.timer.Reset(0)
+}
+
+// setDeadline schedules the end of the run after the Duration of the spec, counted from the time Run was called.
+func (r *runner) setDeadline() {
+	if r.deadline != nil {
+		r.deadline.Stop()
+		r.deadline = nil
+	}
+	if r.spec.Duration > 0 {
+		end := r.tap.startTime.Add(time.Duration(float64(time.Second) * r.spec.Duration))
+		r.deadline = time.NewTimer(time.Until(end))
+	}
+}
+
+// expired returns the channel of the deadline, or nil if there is none.
+func (r *runner) expired() <-chan time.Time {
+	if r.deadline == nil {
+		return nil
+	}
+	return r.deadline.C
+}
+
+// tick sends the logs that are due and schedules the next tick; it sets the stopReason instead if a limit of the
+// spec is reached.
+func (r *runner) tick() error {
+	if r.stopReason = r.limitReached(0, 0); len(r.stopReason) > 0 {
+		return nil
+	}
+	start := time.Now()
+	if r.pacer == nil {
+		r.timer.Reset(time.Duration(float64(time.Second) * r.spec.Interval))
+	} else {
+		r.pacer.refill(start)
+	}
+	count, bytes, err := r.batch(start)
+	if err != nil {
+		return err
+	}
+	if len(r.stopReason) == 0 {
+		r.stopReason = r.limitReached(0, 0)
+	}
+	if len(r.stopReason) > 0 {
+		return nil
+	}
+	if r.pacer != nil {
+		wait := time.Duration(0)
+		if !r.pacer.allow() {
+			wait = r.pacer.wait(time.Now())
+		}
+		r.timer.Reset(wait)
+	}
+	now := time.Now()
+	if logsPerSecond, bytesPerSecond, ok := r.meter.record(now, count, bytes); ok {
+		targetLogs := r.targetLogsPerSecond(now)
+		shortfall := getShortfall(
+			(r.windowTarget+targetLogs)/2, r.spec.TargetBytesPerSecond, logsPerSecond, bytesPerSecond,
+		)
+		r.windowTarget = targetLogs
+		r.tap.setRates(logsPerSecond, bytesPerSecond, targetLogs, shortfall)
+	}
+	r.account(now)
+	return nil
+}
+
+// account records the time since it was last called, unless paused, as active time along with the current target
+// rates.
+func (r *runner) account(now time.Time) {
+	if !r.paused {
+		r.tap.recordActiveTime(now.Sub(r.accounted), r.targetLogsPerSecond(now), r.spec.TargetBytesPerSecond)
+	}
+	r.accounted = now
+}
+
+// batch sends the logs that are due with all the workers in parallel, which is a log per worker without a pacer,
+// or as many logs as the pacer allows within maxBatchDuration. It returns the number and the size of the logs sent.
+func (r *runner) batch(start time.Time) (int64, int64, error) {
+	share := r.share()
+	if len(r.workers) == 1 {
+		return r.work(r.workers[0], start, share)
+	}
+	var (
+		wg           sync.WaitGroup
+		mutex        sync.Mutex
+		count, bytes int64
+		firstErr     error
+	)
+	for _, w := range r.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			c, b, err := r.work(w, start, share)
+			mutex.Lock()
+			defer mutex.Unlock()
+			count += c
+			bytes += b
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(w)
+	}
+	wg.Wait()
+	return count, bytes, firstErr
+}
+
+// share returns the largest number of logs a worker can send in a batch, which splits the logs that are due evenly
+// among the workers so that one worker does not take them all; the budget of the pacer is not refilled during a
+// batch, so the number of logs it allows is known beforehand unless only the bytes are limited.
+func (r *runner) share() int64 {
+	switch {
+	case r.pacer == nil:
+		return 1
+	case !r.pacer.limitLogs:
+		return math.MaxInt64
+	}
+	return int64(math.Max(1, math.Ceil(math.Floor(r.pacer.logTokens)/float64(len(r.workers)))))
+}
+
+// work sends the share of the given worker of the logs that are due; it is called for all the workers at once.
+func (r *runner) work(w *worker, start time.Time, share int64) (int64, int64, error) {
+	var count, bytes int64
+	for count < share {
+		estimate, ok := r.reserve(start)
+		if !ok {
+			break
+		}
+		size, err := r.log(w)
+		r.release(size, estimate)
+		if err != nil {
+			return count, bytes, err
+		}
+		count++
+		bytes += int64(size)
+	}
+	return count, bytes, nil
+}
+
+// reserve returns true if another log can be sent in the batch started at the given time, in which case the log is
+// taken out of the budget of the pacer, along with its estimated size, which must be passed to release. It sets the
+// stopReason if a limit of the spec is reached.
+func (r *runner) reserve(start time.Time) (int64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.stopReason) > 0 {
+		return 0, false
+	}
+	if r.pacer != nil && (!r.pacer.allow() || time.Since(start) > maxBatchDuration) {
+		return 0, false
+	}
+	if reason := r.limitReached(r.inFlight, r.inFlightBytes); len(reason) > 0 {
+		// the logs being written may still fail, in which case the limit is not reached yet
+		if r.inFlight == 0 {
+			r.stopReason = reason
+		}
+		return 0, false
+	}
+	if r.pacer != nil {
+		r.pacer.consume(0)
+	}
+	r.inFlight++
+	r.inFlightBytes += r.lastSize
+	return r.lastSize, true
+}
+
+// release takes the size of a log reserved by reserve out of the budget of the pacer once it has been written, and
+// the estimated size of the log out of the size of the logs being written.
+func (r *runner) release(size int, estimate int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.inFlight--
+	r.inFlightBytes -= estimate
+	if size > 0 {
+		r.lastSize = int64(size)
+	}
+	if r.pacer != nil {
+		r.pacer.consumeBytes(size)
+	}
+}
+
+// resetMeter starts a new window of the meter.
+func (r *runner) resetMeter(now time.Time) {
+	r.meter.reset(now)
+	r.windowTarget = r.targetLogsPerSecond(now)
+}
+
+// targetLogsPerSecond returns the number of logs that should currently be sent per second. The target rate of a
+// LogTaskSpec with an Interval is one log per Interval for every worker.
+func (r *runner) targetLogsPerSecond(now time.Time) float64 {
+	if r.pacer != nil {
+		return r.pacer.targetLogsPerSecond(now)
+	}
+	if r.spec.Interval > 0 {
+		return float64(len(r.workers)) / r.spec.Interval
+	}
+	return 0
+}
+
+// limitReached returns the reason to stop if the MaxCount or the MaxBytes of the spec is reached, counting the
+// given number and estimated size of the logs being written, or an empty string otherwise.
+func (r *runner) limitReached(inFlight, inFlightBytes int64) string {
+	if r.spec.MaxCount == 0 && r.spec.MaxBytes == 0 {
+		return ""
+	}
+	sentCount, sentBytes := r.tap.sent()
+	switch {
+	case r.spec.MaxCount > 0 && sentCount+inFlight >= r.spec.MaxCount:
+		return fmt.Sprintf("maxCount of %d reached", r.spec.MaxCount)
+	case r.spec.MaxBytes > 0 && sentBytes+inFlightBytes >= r.spec.MaxBytes:
+		return fmt.Sprintf("maxBytes of %d reached", r.spec.MaxBytes)
+	}
+	return ""
+}
+
+// log sends a single log with the given worker and records it in the status; a log dropped by the output is not
+// treated as an error.
+func (r *runner) log(w *worker) (int, error) {
+	start := time.Now()
+	_, size, err := w.logger.Log()
+	r.tap.recordWrite(w.status, size, time.Since(start), err)
+	if err == output.ErrDropped {
+		return size, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("[%s] failed to write log: %s", r.tap.task.Name, err.Error())
+	}
+	return size, nil
+}
+
+// closeAll closes all the outputs.
+func closeAll(outs []io.WriteCloser) {
+	for _, out := range outs {
+		out.Close()
+	}
+}
+
+// stopTimer stops the timer and drains its channel so that it can be safely reset.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package metrics implements a histogram and an encoder of the Prometheus text exposition format, which is just
+// enough for LogTap to expose its counters without depending on the Prometheus client library.
+package metrics
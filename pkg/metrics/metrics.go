@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types of the Prometheus text exposition format.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// LatencyBuckets are the upper bounds in seconds of the histogram buckets used for write latencies, ranging from
+// 10 microseconds to 1 second.
+var LatencyBuckets = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1,
+}
+
+// Label is a name-value pair that identifies a sample within a metric family.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Histogram counts observations into buckets with the given upper bounds. It is not safe for concurrent use.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets in increasing order; the +Inf bucket is implicit.
+	Bounds []float64
+
+	// Counts are the numbers of observations that fall into each bucket, not cumulative.
+	Counts []uint64
+
+	// Count is the total number of observations.
+	Count uint64
+
+	// Sum is the sum of all observations.
+	Sum float64
+}
+
+// NewHistogram creates an empty Histogram with the given bucket upper bounds.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe adds a single observation to the Histogram.
+func (h *Histogram) Observe(value float64) {
+	for i, bound := range h.Bounds {
+		if value <= bound {
+			h.Counts[i]++
+			break
+		}
+	}
+	h.Count++
+	h.Sum += value
+}
+
+// DeepCopy returns a copy of the Histogram.
+func (h *Histogram) DeepCopy() *Histogram {
+	if h == nil {
+		return nil
+	}
+	ret := *h
+	ret.Counts = make([]uint64, len(h.Counts))
+	copy(ret.Counts, h.Counts)
+	return &ret
+}
+
+// Quantile estimates the given quantile, from 0 to 1, of the observations by linear interpolation within the
+// bucket it falls into, as histogram_quantile of Prometheus does; it is the highest bound if the quantile falls
+// into the +Inf bucket, and 0 if there is no observation.
+func (h *Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := q * float64(h.Count)
+	var cumulative uint64
+	for i, count := range h.Counts {
+		if float64(cumulative+count) >= rank && count > 0 {
+			lower := 0.0
+			if i > 0 {
+				lower = h.Bounds[i-1]
+			}
+			return lower + (h.Bounds[i]-lower)*(rank-float64(cumulative))/float64(count)
+		}
+		cumulative += count
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// Encoder writes metric families in the Prometheus text exposition format. Every family must be started with
+// Family, followed by all of its samples. Write errors are kept and returned by Flush.
+type Encoder struct {
+	writer *bufio.Writer
+	err    error
+}
+
+// NewEncoder creates an Encoder that writes to the given writer.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+// Family writes the HELP and TYPE lines of a metric family.
+func (e *Encoder) Family(name, kind, help string) {
+	e.write("# HELP ", name, " ", escapeHelp(help), "\n")
+	e.write("# TYPE ", name, " ", kind, "\n")
+}
+
+// Sample writes a single sample of a counter or a gauge.
+func (e *Encoder) Sample(name string, labels []Label, value float64) {
+	e.write(name, formatLabels(labels), " ", formatFloat(value), "\n")
+}
+
+// Histogram writes the buckets, the sum and the count of a histogram.
+func (e *Encoder) Histogram(name string, labels []Label, h *Histogram) {
+	var cumulative uint64
+	bucketLabels := append(append(make([]Label, 0, len(labels)+1), labels...), Label{Name: "le"})
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		bucketLabels[len(labels)].Value = formatFloat(bound)
+		e.Sample(name+"_bucket", bucketLabels, float64(cumulative))
+	}
+	bucketLabels[len(labels)].Value = formatFloat(math.Inf(1))
+	e.Sample(name+"_bucket", bucketLabels, float64(h.Count))
+	e.Sample(name+"_sum", labels, h.Sum)
+	e.Sample(name+"_count", labels, float64(h.Count))
+}
+
+// Flush writes any buffered data and returns the first error that occurred.
+func (e *Encoder) Flush() error {
+	if e.err == nil {
+		e.err = e.writer.Flush()
+	}
+	return e.err
+}
+
+func (e *Encoder) write(s ...string) {
+	for i := range s {
+		if e.err != nil {
+			return
+		}
+		_, e.err = e.writer.WriteString(s[i])
+	}
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label.Name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueReplacer.Replace(label.Value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
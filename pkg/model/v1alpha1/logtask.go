@@ -1,6 +1,10 @@
 package v1alpha1
 
-import "time"
+import (
+	"encoding/json"
+	"text/template"
+	"time"
+)
 
 const (
 	// OutputKindFile means the log messages should be written to a file.
@@ -11,6 +15,53 @@ const (
 
 	// OutputKindStdOut means the log messages should be written to STDOUT
 	OutputKindStdOut = "STDOUT"
+
+	// OutputKindSyslog means the log messages should be sent to a syslog server.
+	OutputKindSyslog = "Syslog"
+
+	// OutputKindNetwork means the log messages should be sent to a TCP or UDP server.
+	OutputKindNetwork = "Network"
+
+	// OutputKindHTTP means the log messages should be sent to an HTTP endpoint in batches.
+	OutputKindHTTP = "HTTP"
+
+	// OutputKindForward means the log messages should be sent to a server speaking the Fluentd Forward protocol.
+	OutputKindForward = "Forward"
+
+	// OutputKindOTLP means the log messages should be exported to an OpenTelemetry receiver in batches.
+	OutputKindOTLP = "OTLP"
+)
+
+const (
+	// EncodingRaw means the log messages are written as they are.
+	EncodingRaw = "Raw"
+
+	// EncodingCRI means every log message is written as a line of the CRI logging format used by the kubelet, such
+	// as "2019-01-01T00:00:00.123456789Z stdout F message".
+	EncodingCRI = "CRI"
+
+	// EncodingDockerJSON means every log message is written as a record of the json-file logging driver of Docker,
+	// such as {"log":"message\n","stream":"stdout","time":"2019-01-01T00:00:00.123456789Z"}.
+	EncodingDockerJSON = "DockerJSON"
+
+	// ContainerStreamStdout means the log messages are written as if they were printed to STDOUT by a container.
+	ContainerStreamStdout = "stdout"
+
+	// ContainerStreamStderr means the log messages are written as if they were printed to STDERR by a container.
+	ContainerStreamStderr = "stderr"
+
+	// ContainerMaxLineSize is the largest size of the part of a log message in a single CRI line or Docker record;
+	// longer log messages are split into partial ones, as container runtimes do.
+	ContainerMaxLineSize = 16 * 1024
+)
+
+const (
+	// MultilineDefaultMinDepth is the MinDepth of a MultilineSpec that does not specify it.
+	MultilineDefaultMinDepth = 5
+
+	// MultilineDefaultMaxDepth is the MaxDepth of a MultilineSpec that does not specify it, unless its MinDepth is
+	// larger.
+	MultilineDefaultMaxDepth = 20
 )
 
 const (
@@ -19,6 +70,224 @@ const (
 
 	// ContentTypeRandom means the log messages have a predefined number of random characters
 	ContentTypeRandom = "Random"
+
+	// ContentTypeJSON means the log messages are JSON objects, one per line, with user-declared fields.
+	ContentTypeJSON = "JSON"
+
+	// ContentTypeTemplate means the log messages are rendered from a Go text/template.
+	ContentTypeTemplate = "Template"
+
+	// ContentTypeMultiline means some of the log messages are multi-line events, such as stack traces.
+	ContentTypeMultiline = "Multiline"
+
+	// ContentTypeApacheCommon means the log messages are access logs in the Common Log Format of Apache.
+	ContentTypeApacheCommon = "ApacheCommon"
+
+	// ContentTypeApacheCombined means the log messages are access logs in the Combined Log Format of Apache.
+	ContentTypeApacheCombined = "ApacheCombined"
+
+	// ContentTypeNginx means the log messages are access logs in the default "combined" format of Nginx.
+	ContentTypeNginx = "Nginx"
+
+	// ContentTypeEnvoy means the log messages are access logs in the default format of Envoy.
+	ContentTypeEnvoy = "Envoy"
+)
+
+const (
+	// JSONKeyTimestamp is the key of the timestamp field of the JSON log messages.
+	JSONKeyTimestamp = "timestamp"
+
+	// JSONKeyLevel is the key of the log level field of the JSON log messages.
+	JSONKeyLevel = "level"
+
+	// JSONKeyTask is the key of the task name field of the JSON log messages.
+	JSONKeyTask = "task"
+
+	// JSONKeySequence is the key of the sequence number field of the JSON log messages.
+	JSONKeySequence = "seq"
+
+	// JSONKeyMessage is the key of the message field of the JSON log messages.
+	JSONKeyMessage = "message"
+
+	// JSONKeyChecksum is the key of the checksum field of the JSON log messages.
+	JSONKeyChecksum = "crc"
+
+	// JSONKeySent is the key of the field of the JSON log messages holding the time at which they were created.
+	JSONKeySent = "sent"
+)
+
+const (
+	// FieldKindConstant means a field always has the same value.
+	FieldKindConstant = "Constant"
+
+	// FieldKindPick means a field has a value randomly picked from a list.
+	FieldKindPick = "Pick"
+
+	// FieldKindRandomString means a field has a random string value of a fixed size.
+	FieldKindRandomString = "RandomString"
+
+	// FieldKindRandomInt means a field has a random integer value within a range.
+	FieldKindRandomInt = "RandomInt"
+)
+
+const (
+	// MultilineKindJava means a multi-line event is a Java exception with "at" frames and "Caused by:" causes.
+	MultilineKindJava = "Java"
+
+	// MultilineKindPython means a multi-line event is a Python traceback.
+	MultilineKindPython = "Python"
+
+	// MultilineKindGo means a multi-line event is a Go panic followed by a goroutine dump.
+	MultilineKindGo = "Go"
+)
+
+const (
+	// FileDistributionRoundRobin means the log messages are written to the files in turn.
+	FileDistributionRoundRobin = "RoundRobin"
+
+	// FileDistributionWeighted means every log message is written to a file picked at random according to the
+	// weights of the files.
+	FileDistributionWeighted = "Weighted"
+
+	// FileDistributionRandom means every log message is written to a file picked at random.
+	FileDistributionRandom = "Random"
+)
+
+const (
+	// WorkerWriterShared means all the workers of a LogTask write to the same output, one at a time.
+	WorkerWriterShared = "Shared"
+
+	// WorkerWriterPerWorker means every worker of a LogTask writes to an output of its own, such as a connection
+	// of its own; for the File output, Filepath is a pattern with a single integer verb, such as "/logs/app-%d.log",
+	// that is replaced by the index of every worker.
+	WorkerWriterPerWorker = "PerWorker"
+)
+
+const (
+	// RotationStrategyRename means the log file is renamed to a backup and a new log file is opened in its place.
+	RotationStrategyRename = "Rename"
+
+	// RotationStrategyCopyTruncate means the log file is copied to a backup and then truncated in place.
+	RotationStrategyCopyTruncate = "CopyTruncate"
+)
+
+const (
+	// FsyncNever means the log file is never synced to the disk by LogTap, leaving it to the operating system.
+	FsyncNever = "Never"
+
+	// FsyncFlush means the log file is synced to the disk after every flush of the buffer.
+	FsyncFlush = "Flush"
+
+	// FsyncBytes means the log file is synced to the disk after every FsyncBytes bytes are flushed.
+	FsyncBytes = "Bytes"
+)
+
+const (
+	// SyslogTransportUDP means every syslog message is sent in its own UDP datagram.
+	SyslogTransportUDP = "UDP"
+
+	// SyslogTransportTCP means the syslog messages are sent over TCP, each terminated by a newline.
+	SyslogTransportTCP = "TCP"
+
+	// SyslogTransportTCPOctetCounting means the syslog messages are sent over TCP, each prefixed by its length as
+	// described in RFC 6587.
+	SyslogTransportTCPOctetCounting = "TCPOctetCounting"
+
+	// SyslogTransportUnixgram means every syslog message is sent in its own datagram to a Unix socket.
+	SyslogTransportUnixgram = "Unixgram"
+)
+
+const (
+	// SyslogFormatRFC3164 means the syslog messages have the BSD syslog header described in RFC 3164.
+	SyslogFormatRFC3164 = "RFC3164"
+
+	// SyslogFormatRFC5424 means the syslog messages have the header described in RFC 5424.
+	SyslogFormatRFC5424 = "RFC5424"
+)
+
+const (
+	// NetworkProtocolTCP means the log messages are sent over a TCP connection.
+	NetworkProtocolTCP = "TCP"
+
+	// NetworkProtocolUDP means every log message is sent in its own UDP datagram.
+	NetworkProtocolUDP = "UDP"
+)
+
+const (
+	// NetworkFramingNewline means every log message is terminated by a newline.
+	NetworkFramingNewline = "Newline"
+
+	// NetworkFramingOctetCounting means every log message is prefixed by its length in decimal and a space.
+	NetworkFramingOctetCounting = "OctetCounting"
+
+	// NetworkFramingLengthPrefix means every log message is prefixed by its length as a 4-byte big-endian integer.
+	NetworkFramingLengthPrefix = "LengthPrefix"
+)
+
+const (
+	// HTTPFormatNDJSON means the body of a request holds the log messages of a batch, one per line.
+	HTTPFormatNDJSON = "NDJSON"
+
+	// HTTPFormatTemplate means the body of a request is rendered from a template with the log messages of a batch.
+	HTTPFormatTemplate = "Template"
+)
+
+const (
+	// ForwardModeMessage means every log message is sent in its own Message mode event.
+	ForwardModeMessage = "Message"
+
+	// ForwardModeForward means the log messages are sent in batches as arrays of entries.
+	ForwardModeForward = "Forward"
+
+	// ForwardModePackedForward means the log messages are sent in batches as binary streams of entries.
+	ForwardModePackedForward = "PackedForward"
+
+	// ForwardTagNamePlaceholder is replaced by the name of the LogTask in the tag of a ForwardSpec.
+	ForwardTagNamePlaceholder = "{name}"
+
+	// ForwardRecordKey is the key of the record field that holds the log message.
+	ForwardRecordKey = "log"
+)
+
+const (
+	// OTLPProtocolHTTP means the log records are exported over OTLP/HTTP in binary Protobuf encoding.
+	OTLPProtocolHTTP = "http/protobuf"
+
+	// OTLPProtocolGRPC means the log records are exported over OTLP/gRPC.
+	OTLPProtocolGRPC = "grpc"
+
+	// OTLPServiceNameAttribute is the resource attribute that holds the name of the LogTask.
+	OTLPServiceNameAttribute = "service.name"
+)
+
+var (
+	// LogLevels are the levels of the log messages, from the least to the most severe. They are also the severity
+	// texts of the OTLP log records; the OTLP severity number of a level is four times its index plus one.
+	LogLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+	// SyslogFacilities are the names of the syslog facilities, indexed by their numerical codes.
+	SyslogFacilities = []string{
+		"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+		"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+	}
+
+	// SyslogSeverities are the names of the syslog severities, indexed by their numerical codes.
+	SyslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+)
+
+const (
+	// ProfileKindRamp means the target rate changes linearly from one rate to another over a duration.
+	ProfileKindRamp = "Ramp"
+
+	// ProfileKindStep means the target rate changes through a sequence of stages.
+	ProfileKindStep = "Step"
+
+	// ProfileKindSine means the target rate follows a sine wave.
+	ProfileKindSine = "Sine"
+
+	// ProfileKindBurst means a number of log messages are sent at once periodically on top of a base rate.
+	ProfileKindBurst = "Burst"
 )
 
 const (
@@ -28,6 +297,12 @@ const (
 	// PhaseRunning means a task is running.
 	PhaseRunning = "Running"
 
+	// PhasePaused means a task is running but has been paused from producing log messages.
+	PhasePaused = "Paused"
+
+	// PhaseUpdating means a task is applying a new spec.
+	PhaseUpdating = "Updating"
+
 	// PhaseStopped means a task has finished without any error.
 	PhaseStopped = "Stopped"
 
@@ -51,6 +326,49 @@ type LogTaskSpec struct {
 	// Path to the log file; only effective if `OutputKind` is `File`.
 	Filepath string `json:"filepath,omitempty"`
 
+	// Rotation defines how the log file should be rotated; only effective if `OutputKind` is `File`. The log
+	// file grows forever if Rotation is not specified.
+	Rotation *RotationSpec `json:"rotation,omitempty"`
+
+	// Files spreads the log messages over many files at once, in which case Filepath is a pattern with a single
+	// integer verb, such as "/logs/app-%d.log", that is replaced by the index of every file; it can only be
+	// specified if `OutputKind` is `File`. Every file is rotated according to Rotation.
+	Files *FilesSpec `json:"files,omitempty"`
+
+	// Buffer defines how the log messages are buffered before they are written to the output, so that many of them
+	// are written at once; the log messages are written one by one if Buffer is not specified. It can only be
+	// specified if `OutputKind` is `File`, `STDERR`, `STDOUT`, or `Network` over TCP with NetworkFramingNewline, and
+	// not along with Files.
+	Buffer *BufferSpec `json:"buffer,omitempty"`
+
+	// Syslog defines where and how the log messages are sent; it must be specified if and only if `OutputKind` is
+	// `Syslog`.
+	Syslog *SyslogSpec `json:"syslog,omitempty"`
+
+	// Network defines where and how the log messages are sent; it must be specified if and only if `OutputKind`
+	// is `Network`.
+	Network *NetworkSpec `json:"network,omitempty"`
+
+	// HTTP defines where and how the log messages are sent; it must be specified if and only if `OutputKind` is
+	// `HTTP`.
+	HTTP *HTTPSpec `json:"http,omitempty"`
+
+	// Forward defines where and how the log messages are sent; it must be specified if and only if `OutputKind`
+	// is `Forward`.
+	Forward *ForwardSpec `json:"forward,omitempty"`
+
+	// OTLP defines where and how the log records are exported; it must be specified if and only if `OutputKind`
+	// is `OTLP`.
+	OTLP *OTLPSpec `json:"otlp,omitempty"`
+
+	// Encoding is the way the log messages are written; it is one of the Encoding constants and defaults to
+	// EncodingRaw. Only the File, STDERR and STDOUT outputs support an Encoding other than EncodingRaw.
+	Encoding string `json:"encoding,omitempty"`
+
+	// Container describes the container emulated by the EncodingCRI and EncodingDockerJSON encodings; it can only
+	// be specified with either of them.
+	Container *ContainerSpec `json:"container,omitempty"`
+
 	// TimestampFormat is the format of the timestamp in front of every log message. If TimestampFormat is not a
 	// valid timestamp format, it will be used in place of the timestamps. Set it to an empty string to disable
 	// timestamp.
@@ -61,15 +379,538 @@ type LogTaskSpec struct {
 
 	// Message is the exact message that each log should print
 	// Message must hold non-zero value if and only if ContentType is ContentTypeExplicit
+	// If ContentType is ContentTypeJSON, Message is the start of the message field, which is padded with random
+	// characters if the log is smaller than MinSize.
+	// If ContentType is ContentTypeTemplate, Message is the template from which every message is rendered; see
+	// logger.NewTemplateLogger for the functions it can use.
+	// If ContentType is ContentTypeMultiline, Message is the first line of every log, after the prefix, and
+	// defaults to "request failed".
 	Message string `json:"message,omitempty"`
 
 	// MinSize defines size in bytes of each log message. The size includes the size of the timestamp, if there
 	// is one. The actual message might be larger than MinSize due to timestamp and name prefix.
-	// MinSize must hold non-zero value if and only if ContentType is ContentTypeRandom
+	// MinSize can only hold non-zero value if ContentType is ContentTypeRandom or ContentTypeJSON; for the latter,
+	// the message field is padded with random characters up to MinSize.
 	MinSize int `json:"minSize,omitempty"`
 
+	// Checksum determines whether every log message should carry a CRC-32 checksum of its payload so that
+	// corrupted logs can be detected.
+	Checksum bool `json:"checksum,omitempty"`
+
+	// LatencyMarker determines whether every log message should carry the time at which it was created, in
+	// nanoseconds since the Unix epoch, so that the receiver can measure the end-to-end latency. The text log
+	// messages carry it as "sent=<nanoseconds>" after the sequence number, and the JSON log messages in the sent
+	// field.
+	LatencyMarker bool `json:"latencyMarker,omitempty"`
+
+	// Fields are the extra fields of every log message; only effective if ContentType is ContentTypeJSON.
+	Fields []FieldSpec `json:"fields,omitempty"`
+
+	// Multiline defines the multi-line events; it must be specified if and only if ContentType is
+	// ContentTypeMultiline.
+	Multiline *MultilineSpec `json:"multiline,omitempty"`
+
+	// Levels are the relative weights of the levels of the log messages, keyed by LogLevels. If Levels is not
+	// empty, every log message gets a level picked according to the weights, which the text log messages carry
+	// after the timestamp and the JSON log messages carry in the level field; otherwise the text log messages have
+	// no level, and the JSON log messages are at the INFO level.
+	Levels map[string]float64 `json:"levels,omitempty"`
+
+	// AccessLog defines the requests described by the access logs; it is optional, and only effective if
+	// ContentType is one of the access log content types.
+	AccessLog *AccessLogSpec `json:"accessLog,omitempty"`
+
 	// Interval defines logging interval, or the amount of time, in seconds, to wait in-between log messages.
+	// Interval must hold zero value if any of the target rates is specified.
 	Interval float64 `json:"interval"`
+
+	// TargetLogsPerSecond is the number of log messages to be sent per second. The log messages are sent in
+	// batches when needed, and the batches grow to make up for slow writes.
+	TargetLogsPerSecond float64 `json:"targetLogsPerSecond,omitempty"`
+
+	// TargetBytesPerSecond is the size in bytes of log messages to be sent per second. If both target rates are
+	// specified, the stricter one is followed.
+	TargetBytesPerSecond float64 `json:"targetBytesPerSecond,omitempty"`
+
+	// Profile describes how the number of log messages to be sent per second changes over time. If Profile is
+	// specified, Interval and TargetLogsPerSecond must hold zero value; TargetBytesPerSecond can still be used to
+	// cap the rate.
+	Profile *ProfileSpec `json:"profile,omitempty"`
+
+	// MaxCount is the number of log messages after which the task stops; zero means no limit. Only the log
+	// messages counted in the SentCount of the status count.
+	MaxCount int64 `json:"maxCount,omitempty"`
+
+	// MaxBytes is the size in bytes of log messages after which the task stops; zero means no limit. The task
+	// stops as soon as the SentBytes of the status reaches MaxBytes, so the last log message may go over it. The
+	// log messages being written by the other Workers count with the size of the last log message written, so
+	// that the task stops at about the same size whatever the number of Workers.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+
+	// Duration is the amount of time, in seconds, after which the task stops, counted from the time it started
+	// running and including the time it was paused; zero means no limit.
+	Duration float64 `json:"duration,omitempty"`
+
+	// Workers is the number of content generators sending log messages in parallel, sharing the target rates and
+	// the limits; zero means one. If there are more than one, the name of the task in the log messages of every
+	// worker is followed by a slash and the index of the worker, such as "task/0", and every worker has its own
+	// sequence numbers. With Interval, every worker sends a log message per Interval.
+	Workers int `json:"workers,omitempty"`
+
+	// WorkerWriter is the way the Workers write to the output; either WorkerWriterShared or
+	// WorkerWriterPerWorker. It defaults to WorkerWriterShared.
+	WorkerWriter string `json:"workerWriter,omitempty"`
+}
+
+// RotationSpec defines when and how a log file should be rotated. The backups are named after the log file with a
+// numeric suffix, ".1" being the newest one, and an additional ".gz" suffix if they are compressed.
+type RotationSpec struct {
+	// MaxSize is the size in bytes above which the log file is rotated; zero disables size-based rotation.
+	MaxSize int64 `json:"maxSize,omitempty"`
+
+	// MaxAge is the amount of time, in seconds, after which the log file is rotated; zero disables time-based
+	// rotation. At least one of MaxSize and MaxAge must hold non-zero value.
+	MaxAge float64 `json:"maxAge,omitempty"`
+
+	// MaxBackups is the number of backups to keep; the older ones are removed. It must be at least 1.
+	MaxBackups int `json:"maxBackups"`
+
+	// Strategy is the way the log file is rotated; either RotationStrategyRename or RotationStrategyCopyTruncate.
+	Strategy string `json:"strategy"`
+
+	// Compress determines whether the backups should be compressed with gzip.
+	Compress bool `json:"compress,omitempty"`
+}
+
+// BufferSpec defines when the buffered log messages are flushed to the output and, for the File output, when the
+// log file is synced to the disk. The buffer is flushed when it is full, when it is older than FlushInterval, and
+// when the task stops.
+type BufferSpec struct {
+	// Size is the size in bytes of the buffer; zero means the log messages are flushed as soon as they are written,
+	// which is useful to sync every log message to the disk.
+	Size int `json:"size,omitempty"`
+
+	// FlushInterval is the amount of time, in seconds, after which the buffered log messages are flushed; zero
+	// means they are only flushed when the buffer is full.
+	FlushInterval float64 `json:"flushInterval,omitempty"`
+
+	// Fsync is the policy to sync the log file to the disk; it is one of the Fsync constants and defaults to
+	// FsyncNever. Only the File output supports a policy other than FsyncNever.
+	Fsync string `json:"fsync,omitempty"`
+
+	// FsyncBytes is the size in bytes of log messages flushed after which the log file is synced to the disk; it
+	// must hold non-zero value if and only if Fsync is FsyncBytes.
+	FsyncBytes int64 `json:"fsyncBytes,omitempty"`
+}
+
+// FilesSpec defines how many files are written at once, how the log messages are spread over them and how often
+// they are replaced. The files have the indexes 0 to Count-1 at first; when a file is replaced, it is removed and
+// a file with the next unused index is created in its place, as the log files of pods come and go.
+type FilesSpec struct {
+	// Count is the number of files written at once.
+	Count int `json:"count"`
+
+	// Distribution is the way the log messages are spread over the files; it is one of the FileDistribution
+	// constants.
+	Distribution string `json:"distribution"`
+
+	// Weights are the relative weights of the files, one for every file; they must be specified if and only if
+	// Distribution is FileDistributionWeighted. The weight of a file is passed on to the file replacing it.
+	Weights []float64 `json:"weights,omitempty"`
+
+	// ChurnInterval is the amount of time, in seconds, between two replacements of files; zero disables the
+	// replacement. The replacement is carried out when a log message is written.
+	ChurnInterval float64 `json:"churnInterval,omitempty"`
+
+	// ChurnCount is the number of files, the oldest ones first, replaced at a time; it defaults to 1.
+	ChurnCount int `json:"churnCount,omitempty"`
+}
+
+// FileStatus is the status of one of the files written by a LogTask with a FilesSpec.
+type FileStatus struct {
+	// Path is the path to the file.
+	Path string `json:"path"`
+
+	// SentCount is the number of log messages written to the file.
+	SentCount int64 `json:"sentCount"`
+
+	// SentBytes is the size in bytes of the log messages written to the file.
+	SentBytes int64 `json:"sentBytes"`
+}
+
+// WorkerStatus describes the log messages sent by one of the Workers of a LogTask.
+type WorkerStatus struct {
+	// Name is the name of the task in the log messages of the worker.
+	Name string `json:"name"`
+
+	// SentCount is the number of log messages sent by the worker.
+	SentCount int64 `json:"sentCount"`
+
+	// SentBytes is the size in bytes of the log messages sent by the worker.
+	SentBytes int64 `json:"sentBytes"`
+}
+
+// ContainerSpec describes the container whose log file is emulated. If LogRoot is specified, the log file is
+// placed in the directory layout of the encoding under LogRoot, which is
+// "<LogRoot>/<Namespace>_<Pod>_<PodUID>/<Name>/<RestartCount>.log" for EncodingCRI, as the kubelet does under
+// /var/log/pods, and "<LogRoot>/<ID>/<ID>-json.log" for EncodingDockerJSON, as Docker does under
+// /var/lib/docker/containers.
+type ContainerSpec struct {
+	// Stream is the stream the log messages are printed to; either ContainerStreamStdout or
+	// ContainerStreamStderr. It defaults to ContainerStreamStdout.
+	Stream string `json:"stream,omitempty"`
+
+	// LogRoot is the directory under which the log file is placed; it can only be specified for the File output,
+	// in place of the Filepath.
+	LogRoot string `json:"logRoot,omitempty"`
+
+	// Namespace is the namespace of the pod; it defaults to "default".
+	Namespace string `json:"namespace,omitempty"`
+
+	// Pod is the name of the pod; it defaults to the name of the LogTask.
+	Pod string `json:"pod,omitempty"`
+
+	// PodUID is the UID of the pod; it defaults to one derived from the name of the pod.
+	PodUID string `json:"podUID,omitempty"`
+
+	// Name is the name of the container; it defaults to "logtap".
+	Name string `json:"name,omitempty"`
+
+	// RestartCount is the number of times the container has been restarted.
+	RestartCount int `json:"restartCount,omitempty"`
+
+	// ID is the ID of the container; it defaults to one derived from the names of the pod and the container.
+	ID string `json:"id,omitempty"`
+}
+
+// SyslogSpec defines the syslog server to which the log messages are sent and the header they carry. The
+// connection is re-established with an exponential backoff if a write fails; the log messages are dropped while
+// the connection is down.
+type SyslogSpec struct {
+	// Address is the address of the syslog server, such as "localhost:514", or the path to its Unix socket.
+	Address string `json:"address"`
+
+	// Transport is the way the log messages are sent; it is one of the SyslogTransport constants.
+	Transport string `json:"transport"`
+
+	// Format is the format of the syslog header; either SyslogFormatRFC3164 or SyslogFormatRFC5424.
+	Format string `json:"format"`
+
+	// Facility is the name of the syslog facility, such as "local0"; it defaults to "user".
+	Facility string `json:"facility,omitempty"`
+
+	// Severity is the name of the syslog severity of the log messages without a level, such as "warning"; it
+	// defaults to "info". The severity of a log message with a level is mapped from its level.
+	Severity string `json:"severity,omitempty"`
+
+	// Hostname is the host name in the syslog header; it defaults to the name of the local host.
+	Hostname string `json:"hostname,omitempty"`
+
+	// AppName is the application name in the syslog header, used as the tag of RFC 3164 messages; it defaults to
+	// "logtap".
+	AppName string `json:"appName,omitempty"`
+}
+
+// NetworkSpec defines the TCP or UDP server to which the log messages are sent and how they are framed. The
+// connection is re-established with an exponential backoff if a write fails; the log messages are dropped while
+// the connection is down.
+type NetworkSpec struct {
+	// Address is the address of the server, such as "localhost:5170".
+	Address string `json:"address"`
+
+	// Protocol is either NetworkProtocolTCP or NetworkProtocolUDP.
+	Protocol string `json:"protocol"`
+
+	// Framing is the way the log messages are delimited; it is one of the NetworkFraming constants.
+	Framing string `json:"framing"`
+
+	// ConnectTimeout is the amount of time, in seconds, to wait for a connection to be established; it defaults
+	// to 5 seconds.
+	ConnectTimeout float64 `json:"connectTimeout,omitempty"`
+
+	// MaxReconnectBackoff is the longest amount of time, in seconds, to wait before reconnecting; it defaults to
+	// 10 seconds.
+	MaxReconnectBackoff float64 `json:"maxReconnectBackoff,omitempty"`
+}
+
+// HTTPSpec defines the HTTP endpoint to which the log messages are sent and how they are batched. A batch is sent
+// when it is full or old enough; the requests that fail with a 429 or 5xx response, or with no response at all,
+// are retried with an exponential backoff, and the log messages of a batch that eventually fails are dropped.
+type HTTPSpec struct {
+	// URL is the URL of the endpoint, such as "http://localhost:3100/loki/api/v1/push".
+	URL string `json:"url"`
+
+	// Method is the method of the requests; it defaults to POST.
+	Method string `json:"method,omitempty"`
+
+	// Headers are the extra header fields of the requests, such as Authorization.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Format is the format of the request body; either HTTPFormatNDJSON or HTTPFormatTemplate.
+	Format string `json:"format"`
+
+	// BodyTemplate is the Go text/template from which the body of a request is rendered with a HTTPBatch; it must
+	// be specified if and only if Format is HTTPFormatTemplate. The json function quotes a string as a JSON string.
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+
+	// Compress determines whether the request body should be compressed with gzip.
+	Compress bool `json:"compress,omitempty"`
+
+	// BatchSize is the largest number of log messages in a batch; it defaults to 1000.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// BatchBytes is the largest size in bytes of the log messages in a batch; it defaults to 1 MiB. A log message
+	// larger than BatchBytes is sent in a batch of its own.
+	BatchBytes int `json:"batchBytes,omitempty"`
+
+	// BatchInterval is the longest amount of time, in seconds, a log message waits in a batch; it defaults to 1.
+	BatchInterval float64 `json:"batchInterval,omitempty"`
+
+	// Timeout is the amount of time, in seconds, to wait for a response; it defaults to 10.
+	Timeout float64 `json:"timeout,omitempty"`
+
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// ForwardSpec defines the server speaking the Fluentd Forward protocol to which the log messages are sent and the
+// mode in which they are sent. Every log message is the ForwardRecordKey field of the record of an event. The
+// connection is re-established with an exponential backoff if a write fails; the log messages are dropped while
+// the connection is down or when they are not acknowledged in time.
+type ForwardSpec struct {
+	// Address is the address of the server, such as "localhost:24224".
+	Address string `json:"address"`
+
+	// Mode is the way the events are sent; it is one of the ForwardMode constants.
+	Mode string `json:"mode"`
+
+	// Tag is the tag of the events, in which ForwardTagNamePlaceholder is replaced by the name of the LogTask; it
+	// defaults to "logtap.{name}".
+	Tag string `json:"tag,omitempty"`
+
+	// RequireAck determines whether every message should carry a chunk option and be acknowledged by the server.
+	RequireAck bool `json:"requireAck,omitempty"`
+
+	// AckTimeout is the amount of time, in seconds, to wait for an acknowledgement; it defaults to 5.
+	AckTimeout float64 `json:"ackTimeout,omitempty"`
+
+	// BatchSize is the largest number of log messages in a message of the ForwardModeForward or
+	// ForwardModePackedForward mode; it defaults to 1000.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// BatchInterval is the longest amount of time, in seconds, a log message waits in a batch; it defaults to 1.
+	BatchInterval float64 `json:"batchInterval,omitempty"`
+}
+
+// OTLPSpec defines the OpenTelemetry receiver to which the log messages are exported and how they are batched.
+// Every log message is the body of a log record, whose timestamp is the time the log message was created. A batch
+// is exported when it is full or old enough; the requests that fail in a way the OTLP specification deems
+// retryable are retried with an exponential backoff, and the log records of a batch that eventually fails, or that
+// the receiver rejects, are dropped.
+type OTLPSpec struct {
+	// Endpoint is the URL of the receiver. For OTLPProtocolHTTP, it is the URL to which the requests are sent, to
+	// which "/v1/logs" is appended if it has no path, such as "http://localhost:4318"; for OTLPProtocolGRPC, only
+	// its scheme and host are used, such as "http://localhost:4317", and the "http" scheme means no TLS.
+	Endpoint string `json:"endpoint"`
+
+	// Protocol is the transport of the requests; either OTLPProtocolHTTP or OTLPProtocolGRPC.
+	Protocol string `json:"protocol"`
+
+	// Headers are the extra header fields, or gRPC metadata, of the requests, such as Authorization.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Compress determines whether the requests should be compressed with gzip.
+	Compress bool `json:"compress,omitempty"`
+
+	// Severity is the severity text of the log records of the log messages without a level; it is one of LogLevels
+	// and defaults to "INFO". The severity of a log record with a level is that level.
+	Severity string `json:"severity,omitempty"`
+
+	// ResourceAttributes are the extra attributes of the resource, besides OTLPServiceNameAttribute, whose value is
+	// the name of the LogTask unless given here.
+	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
+
+	// Attributes are the attributes of every log record.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// BatchSize is the largest number of log records in a request; it defaults to 1000.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// BatchInterval is the longest amount of time, in seconds, a log record waits in a batch; it defaults to 1.
+	BatchInterval float64 `json:"batchInterval,omitempty"`
+
+	// Timeout is the amount of time, in seconds, to wait for a response; it defaults to 10.
+	Timeout float64 `json:"timeout,omitempty"`
+
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// HTTPBatch is the value with which the BodyTemplate of a HTTPSpec is executed.
+type HTTPBatch struct {
+	// Lines are the log messages of the batch without the trailing newlines.
+	Lines []string
+
+	// Timestamp is the time at which the batch is sent.
+	Timestamp time.Time
+}
+
+// MessageTemplateFunctions are the names of the functions that the Message of a LogTaskSpec with ContentTypeTemplate
+// can use; see logger.NewTemplateLogger for what they do.
+var MessageTemplateFunctions = []string{
+	"seq", "now", "uuid", "randInt", "randFloat", "pick", "words", "ipv4", "ipv6", "email", "username", "duration",
+}
+
+// ParseHTTPBodyTemplate parses the BodyTemplate of a HTTPSpec, providing the json function.
+func ParseHTTPBodyTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{
+		"json": func(s string) (string, error) {
+			data, err := json.Marshal(s)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// MultilineSpec defines how often a log message is a multi-line event and what the events look like. The first
+// line of an event is a log message as usual, carrying the sequence number of the event; the rest are the lines of
+// a stack trace. If the log messages carry checksums, the checksum of an event is that of all its lines, so that
+// an event is only intact once its lines have been merged back together.
+type MultilineSpec struct {
+	// Kinds are the kinds of the events, one of which is picked at random for every event; they are MultilineKind
+	// constants and default to all of them.
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Frequency is the fraction, greater than 0 and up to 1, of the log messages that are multi-line events.
+	Frequency float64 `json:"frequency"`
+
+	// MinDepth is the smallest number of frames in a stack; it defaults to MultilineDefaultMinDepth.
+	MinDepth int `json:"minDepth,omitempty"`
+
+	// MaxDepth is the largest number of frames in a stack; it defaults to MultilineDefaultMaxDepth, or MinDepth if
+	// that is larger. It must not be smaller than MinDepth, or than MultilineDefaultMinDepth if MinDepth is not
+	// specified.
+	MaxDepth int `json:"maxDepth,omitempty"`
+
+	// RepeatPrefix determines whether every line of an event starts with the same timestamp, name and sequence
+	// number as the first one.
+	RepeatPrefix bool `json:"repeatPrefix,omitempty"`
+}
+
+// AccessLogSpec defines the distributions from which the requests described by the access logs are drawn. Every
+// distribution is a list of values with relative weights, and defaults to a mix typical of a web application if
+// empty.
+type AccessLogSpec struct {
+	// Prefix determines whether every access log follows the prefix of the log messages, with the timestamp, level,
+	// name and sequence number, which standard access log parsers do not expect. Otherwise, the access logs are
+	// printed as they are, and the name, the sequence number, the level and the latency marker are carried in the
+	// query string of the request path as the logtap_task, logtap_seq, logtap_level and logtap_sent parameters, and
+	// there is no checksum.
+	Prefix bool `json:"prefix,omitempty"`
+
+	// Methods are the request methods.
+	Methods []WeightedValue `json:"methods,omitempty"`
+
+	// Paths are the request paths, including the query strings if any.
+	Paths []WeightedValue `json:"paths,omitempty"`
+
+	// Statuses are the response status codes, such as "200" and "503".
+	Statuses []WeightedValue `json:"statuses,omitempty"`
+
+	// Referrers are the values of the Referer header; "-" means no referrer.
+	Referrers []WeightedValue `json:"referrers,omitempty"`
+
+	// UserAgents are the values of the User-Agent header.
+	UserAgents []WeightedValue `json:"userAgents,omitempty"`
+
+	// MinBytes is the smallest size of a response body in bytes.
+	MinBytes int `json:"minBytes,omitempty"`
+
+	// MaxBytes is the largest size of a response body in bytes; it defaults to 20000, or MinBytes if that is
+	// larger. Responses without a body, such as those to HEAD requests, are always empty.
+	MaxBytes int `json:"maxBytes,omitempty"`
+
+	// ClientNetwork is the network, in CIDR notation, in which the client IP addresses are randomly picked; it
+	// defaults to 10.0.0.0/16.
+	ClientNetwork string `json:"clientNetwork,omitempty"`
+}
+
+// WeightedValue is a value that is picked with a probability proportional to its weight.
+type WeightedValue struct {
+	// Value is the value.
+	Value string `json:"value"`
+
+	// Weight is the relative weight of the value; it must not be negative.
+	Weight float64 `json:"weight"`
+}
+
+// FieldSpec defines an extra field of the JSON log messages and how its value is generated.
+type FieldSpec struct {
+	// Name is the key of the field.
+	Name string `json:"name"`
+
+	// Kind determines how the value of the field is generated; it is one of the FieldKind constants.
+	Kind string `json:"kind"`
+
+	// Value is the value of a FieldKindConstant field.
+	Value string `json:"value,omitempty"`
+
+	// Values are the candidates of a FieldKindPick field.
+	Values []string `json:"values,omitempty"`
+
+	// Size is the size in bytes of a FieldKindRandomString field.
+	Size int `json:"size,omitempty"`
+
+	// Min is the inclusive lower bound of a FieldKindRandomInt field.
+	Min int64 `json:"min,omitempty"`
+
+	// Max is the inclusive upper bound of a FieldKindRandomInt field.
+	Max int64 `json:"max,omitempty"`
+}
+
+// ProfileSpec describes how the target rate, in log messages per second, changes over time. The time of a profile
+// starts when the task starts running, stops while the task is paused, and restarts when the spec is updated.
+type ProfileSpec struct {
+	// Kind is the kind of the profile; it is one of the ProfileKind constants.
+	Kind string `json:"kind"`
+
+	// From is the rate at which a ProfileKindRamp profile starts.
+	From float64 `json:"from,omitempty"`
+
+	// To is the rate at which a ProfileKindRamp profile ends; the rate stays there after Duration.
+	To float64 `json:"to,omitempty"`
+
+	// Duration is the amount of time, in seconds, that a ProfileKindRamp profile takes to go from From to To.
+	Duration float64 `json:"duration,omitempty"`
+
+	// Steps are the stages of a ProfileKindStep profile; the rate stays at the last stage after all the stages
+	// are done, unless Repeat is true.
+	Steps []ProfileStep `json:"steps,omitempty"`
+
+	// Repeat determines whether a ProfileKindStep profile starts over after the last stage.
+	Repeat bool `json:"repeat,omitempty"`
+
+	// Base is the rate around which a ProfileKindSine profile oscillates, or the rate in-between the bursts of a
+	// ProfileKindBurst profile.
+	Base float64 `json:"base,omitempty"`
+
+	// Amplitude is the largest difference between the rate of a ProfileKindSine profile and Base.
+	Amplitude float64 `json:"amplitude,omitempty"`
+
+	// Period is the amount of time, in seconds, of a cycle of a ProfileKindSine profile, or in-between the bursts
+	// of a ProfileKindBurst profile.
+	Period float64 `json:"period,omitempty"`
+
+	// BurstSize is the number of log messages sent at once by each burst of a ProfileKindBurst profile.
+	BurstSize int `json:"burstSize,omitempty"`
+}
+
+// ProfileStep is a stage of a ProfileKindStep profile.
+type ProfileStep struct {
+	// Rate is the number of log messages to be sent per second during the stage.
+	Rate float64 `json:"rate"`
+
+	// Duration is the amount of time, in seconds, that the stage lasts.
+	Duration float64 `json:"duration"`
 }
 
 // LogTaskStatus describes the status of a running log task.
@@ -83,11 +924,73 @@ type LogTaskStatus struct {
 	// Reason describes why the task is in its current phase.
 	Reason string `json:"reason,omitempty"`
 
-	// The number of logs messages that a running log task has produced.
+	// The number of logs messages that a running log task has written to the output, including the ones dropped by
+	// the output; it matches the sequence numbers handed out, except for a write that failed the task.
 	SentCount int64 `json:"sentCount"`
 
 	// The size in bytes of logs messages that a running log task has produced.
 	SentBytes int64 `json:"sentBytes"`
+
+	// LogsPerSecond is the number of log messages sent per second, measured over the last second or so.
+	LogsPerSecond float64 `json:"logsPerSecond"`
+
+	// BytesPerSecond is the size in bytes of log messages sent per second, measured over the last second or so.
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+
+	// TargetLogsPerSecond is the number of log messages that should currently be sent per second, which changes
+	// over time if the spec has a Profile; for a ProfileKindBurst profile, it is the average rate including the
+	// bursts. With an Interval, it is one log message per Interval for every worker. It is zero if the rate is only
+	// limited in bytes per second.
+	TargetLogsPerSecond float64 `json:"targetLogsPerSecond,omitempty"`
+
+	// Shortfall is the fraction, from 0 to 1, by which the achieved rate falls short of the target rate; 0 means
+	// the target is met.
+	Shortfall float64 `json:"shortfall"`
+
+	// The number of times that the log file has been rotated.
+	Rotations int64 `json:"rotations,omitempty"`
+
+	// BufferedBytes is the size in bytes of the log messages that are written to the buffer of a LogTask with a
+	// BufferSpec but have not been flushed to the output yet; they are already counted in SentBytes. The size is
+	// that of the log messages as written to the output, after the Encoding if any.
+	BufferedBytes int64 `json:"bufferedBytes,omitempty"`
+
+	// Flushes is the number of times that the buffer of a LogTask with a BufferSpec has been flushed.
+	Flushes int64 `json:"flushes,omitempty"`
+
+	// Fsyncs is the number of times that the log file has been synced to the disk.
+	Fsyncs int64 `json:"fsyncs,omitempty"`
+
+	// The number of times that the connection to the server has been redialed after it was lost or could not be
+	// made, whether the dial succeeded or not.
+	Reconnects int64 `json:"reconnects,omitempty"`
+
+	// The number of log messages dropped because the connection to the server was down or, for the HTTP output or
+	// an output with a BufferSpec, because the batch or the buffer could not be sent; they are also counted in
+	// SentCount, as they were written to the output.
+	DroppedWrites int64 `json:"droppedWrites,omitempty"`
+
+	// HTTPResponses is the number of HTTP requests, keyed by the status code of their responses; the requests that
+	// got no response are counted under "error".
+	HTTPResponses map[string]int64 `json:"httpResponses,omitempty"`
+
+	// HTTPRequestLatency is the average time, in seconds, taken by an HTTP request.
+	HTTPRequestLatency float64 `json:"httpRequestLatency,omitempty"`
+
+	// Files are the statuses of the files that have been written to by a LogTask with a FilesSpec and have not
+	// been removed since, in the order they were first written to.
+	Files []FileStatus `json:"files,omitempty"`
+
+	// RemovedFiles is the number of files removed by a LogTask with a FilesSpec to make room for new ones.
+	RemovedFiles int64 `json:"removedFiles,omitempty"`
+
+	// LevelCounts is the number of log messages produced at every level by a LogTask with Levels, keyed by the
+	// level; it includes the log messages that failed to be written.
+	LevelCounts map[string]int64 `json:"levelCounts,omitempty"`
+
+	// Workers are the statuses of the workers of a LogTask with more than one of them, in the order they were
+	// first started; they are kept when the number of workers changes.
+	Workers []WorkerStatus `json:"workers,omitempty"`
 }
 
 // LogTaskList describes a list of tasks.
@@ -103,12 +1006,42 @@ func (in *LogTask) DeepCopyInto(out *LogTask) {
 	if in.Spec != nil {
 		in, out := &in.Spec, &out.Spec
 		*out = new(LogTaskSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Status != nil {
 		in, out := &in.Status, &out.Status
 		*out = new(LogTaskStatus)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogTaskStatus) DeepCopyInto(out *LogTaskStatus) {
+	*out = *in
+	if in.HTTPResponses != nil {
+		in, out := &in.HTTPResponses, &out.HTTPResponses
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]FileStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = make([]WorkerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.LevelCounts != nil {
+		in, out := &in.LevelCounts, &out.LevelCounts
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
 	return
 }
@@ -126,6 +1059,195 @@ func (in *LogTask) DeepCopy() *LogTask {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LogTaskSpec) DeepCopyInto(out *LogTaskSpec) {
 	*out = *in
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(RotationSpec)
+		**out = **in
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = new(FilesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Buffer != nil {
+		in, out := &in.Buffer, &out.Buffer
+		*out = new(BufferSpec)
+		**out = **in
+	}
+	if in.Container != nil {
+		in, out := &in.Container, &out.Container
+		*out = new(ContainerSpec)
+		**out = **in
+	}
+	if in.Syslog != nil {
+		in, out := &in.Syslog, &out.Syslog
+		*out = new(SyslogSpec)
+		**out = **in
+	}
+	if in.Network != nil {
+		in, out := &in.Network, &out.Network
+		*out = new(NetworkSpec)
+		**out = **in
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Forward != nil {
+		in, out := &in.Forward, &out.Forward
+		*out = new(ForwardSpec)
+		**out = **in
+	}
+	if in.OTLP != nil {
+		in, out := &in.OTLP, &out.OTLP
+		*out = new(OTLPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Profile != nil {
+		in, out := &in.Profile, &out.Profile
+		*out = new(ProfileSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]FieldSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Levels != nil {
+		in, out := &in.Levels, &out.Levels
+		*out = make(map[string]float64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Multiline != nil {
+		in, out := &in.Multiline, &out.Multiline
+		*out = new(MultilineSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccessLog != nil {
+		in, out := &in.AccessLog, &out.AccessLog
+		*out = new(AccessLogSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLogSpec) DeepCopyInto(out *AccessLogSpec) {
+	*out = *in
+	if in.Methods != nil {
+		in, out := &in.Methods, &out.Methods
+		*out = make([]WeightedValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]WeightedValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.Statuses != nil {
+		in, out := &in.Statuses, &out.Statuses
+		*out = make([]WeightedValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.Referrers != nil {
+		in, out := &in.Referrers, &out.Referrers
+		*out = make([]WeightedValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.UserAgents != nil {
+		in, out := &in.UserAgents, &out.UserAgents
+		*out = make([]WeightedValue, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultilineSpec) DeepCopyInto(out *MultilineSpec) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileSpec) DeepCopyInto(out *ProfileSpec) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]ProfileStep, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesSpec) DeepCopyInto(out *FilesSpec) {
+	*out = *in
+	if in.Weights != nil {
+		in, out := &in.Weights, &out.Weights
+		*out = make([]float64, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPSpec) DeepCopyInto(out *HTTPSpec) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OTLPSpec) DeepCopyInto(out *OTLPSpec) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceAttributes != nil {
+		in, out := &in.ResourceAttributes, &out.ResourceAttributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldSpec) DeepCopyInto(out *FieldSpec) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
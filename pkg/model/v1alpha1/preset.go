@@ -22,32 +22,32 @@ const (
 var (
 	presets = map[string]*LogTaskSpec{
 		TaskPresetStandard: {
-			OutputKind:      OutputKindStdErr,
-			TimestampFormat: time.RFC3339,
-			ContentType:     ContentTypeRandom,
-			MinSize:         256,
-			Interval:        0.1,
+			OutputKind:          OutputKindStdErr,
+			TimestampFormat:     time.RFC3339,
+			ContentType:         ContentTypeRandom,
+			MinSize:             256,
+			TargetLogsPerSecond: 10,
 		},
 		TaskPresetLong: {
-			OutputKind:      OutputKindStdErr,
-			TimestampFormat: time.RFC3339,
-			ContentType:     ContentTypeRandom,
-			MinSize:         20971520,
-			Interval:        2.,
+			OutputKind:          OutputKindStdErr,
+			TimestampFormat:     time.RFC3339,
+			ContentType:         ContentTypeRandom,
+			MinSize:             20971520,
+			TargetLogsPerSecond: 0.5,
 		},
 		TaskPresetFrequent: {
-			OutputKind:      OutputKindStdErr,
-			TimestampFormat: time.RFC3339,
-			ContentType:     ContentTypeRandom,
-			MinSize:         256,
-			Interval:        0.00002,
+			OutputKind:          OutputKindStdErr,
+			TimestampFormat:     time.RFC3339,
+			ContentType:         ContentTypeRandom,
+			MinSize:             256,
+			TargetLogsPerSecond: 50000,
 		},
 		TaskPresetRoast: {
-			OutputKind:      OutputKindStdErr,
-			TimestampFormat: time.RFC3339,
-			ContentType:     ContentTypeRandom,
-			MinSize:         1048576,
-			Interval:        0.025,
+			OutputKind:          OutputKindStdErr,
+			TimestampFormat:     time.RFC3339,
+			ContentType:         ContentTypeRandom,
+			MinSize:             1048576,
+			TargetLogsPerSecond: 40,
 		},
 	}
 )
@@ -2,6 +2,11 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/lichuan0620/logtap/pkg/fieldpath"
 )
@@ -28,6 +33,12 @@ func ValidateMetadata(path fieldpath.FieldPath, metadata *Metadata) error {
 			fmt.Sprintf("unexpected version '%s' (want '%s')", metadata.Version, Version),
 		)
 	}
+	if len(metadata.Name) == 0 {
+		return newValidationError(path.Add("name").String(), "name not specified")
+	}
+	if strings.Contains(metadata.Name, "/") {
+		return newValidationError(path.Add("name").String(), "name must not contain '/'")
+	}
 	return nil
 }
 
@@ -45,13 +56,102 @@ func ValidateLogTaskSpec(path fieldpath.FieldPath, spec *LogTaskSpec) error {
 		if spec.MinSize != 0 {
 			return newValidationError(path.Add("minSize").String(), "invalid field")
 		}
+	case ContentTypeJSON:
+		if spec.MinSize < 0 {
+			return newInvalidValueError(path.Add("minSize").String())
+		}
+		names := map[string]bool{
+			JSONKeyTimestamp: true,
+			JSONKeyLevel:     true,
+			JSONKeyTask:      true,
+			JSONKeySequence:  true,
+			JSONKeyMessage:   true,
+			JSONKeyChecksum:  true,
+			JSONKeySent:      true,
+		}
+		for i := range spec.Fields {
+			fieldPath := path.Add(fmt.Sprintf("fields[%d]", i))
+			if names[spec.Fields[i].Name] {
+				return newValidationError(fieldPath.Add("name").String(), "duplicated or reserved name")
+			}
+			names[spec.Fields[i].Name] = true
+			if err := ValidateFieldSpec(fieldPath, &spec.Fields[i]); err != nil {
+				return err
+			}
+		}
+	case ContentTypeTemplate:
+		if spec.MinSize != 0 {
+			return newValidationError(path.Add("minSize").String(), "invalid field")
+		}
+		if len(spec.Message) == 0 {
+			return newValidationError(path.Add("message").String(), "message not specified for template content")
+		}
+		if err := checkMessageTemplate(spec.Message); err != nil {
+			return newValidationError(path.Add("message").String(), err.Error())
+		}
+	case ContentTypeMultiline:
+		if spec.MinSize != 0 {
+			return newValidationError(path.Add("minSize").String(), "invalid field")
+		}
+		if spec.Multiline == nil {
+			return newValidationError(
+				path.Add("multiline").String(),
+				"multiline not specified for multiline content",
+			)
+		}
+		if err := ValidateMultilineSpec(path.Add("multiline"), spec.Multiline); err != nil {
+			return err
+		}
+	case ContentTypeApacheCommon, ContentTypeApacheCombined, ContentTypeNginx, ContentTypeEnvoy:
+		if len(spec.Message) > 0 {
+			return newValidationError(path.Add("message").String(), "invalid field")
+		}
+		if spec.MinSize != 0 {
+			return newValidationError(path.Add("minSize").String(), "invalid field")
+		}
+		if spec.AccessLog != nil {
+			if err := ValidateAccessLogSpec(path.Add("accessLog"), spec.AccessLog); err != nil {
+				return err
+			}
+		}
+		if spec.Checksum && (spec.AccessLog == nil || !spec.AccessLog.Prefix) {
+			return newValidationError(path.Add("checksum").String(), "checksum specified for access logs without prefix")
+		}
 	default:
 		return newValidationError(path.Add("contentType").String(), "unrecognized contentType")
 	}
+	if len(spec.Fields) > 0 && spec.ContentType != ContentTypeJSON {
+		return newValidationError(path.Add("fields").String(), "invalid field")
+	}
+	if spec.Multiline != nil && spec.ContentType != ContentTypeMultiline {
+		return newValidationError(path.Add("multiline").String(), "multiline specified for non-multiline content")
+	}
+	if len(spec.Levels) > 0 {
+		var total float64
+		for level, weight := range spec.Levels {
+			if indexOf(LogLevels, level) < 0 {
+				return newValidationError(path.Add("levels").Add(level).String(), "unrecognized level")
+			}
+			if weight < 0 {
+				return newInvalidValueError(path.Add("levels").Add(level).String())
+			}
+			total += weight
+		}
+		if total == 0 {
+			return newValidationError(path.Add("levels").String(), "all weights are zero")
+		}
+	}
+	if spec.AccessLog != nil {
+		switch spec.ContentType {
+		case ContentTypeApacheCommon, ContentTypeApacheCombined, ContentTypeNginx, ContentTypeEnvoy:
+		default:
+			return newValidationError(path.Add("accessLog").String(), "accessLog specified for non-access-log content")
+		}
+	}
 	filepathProvided := len(spec.Filepath) > 0
 	switch spec.OutputKind {
 	case OutputKindFile:
-		if !filepathProvided {
+		if !filepathProvided && (spec.Container == nil || len(spec.Container.LogRoot) == 0) {
 			return newValidationError(path.Add(
 				"filepath").String(),
 				"filepath not specified for file output",
@@ -71,12 +171,677 @@ func ValidateLogTaskSpec(path fieldpath.FieldPath, spec *LogTaskSpec) error {
 				"filepath specified for STDOUT output",
 			)
 		}
+	case OutputKindSyslog:
+		if filepathProvided {
+			return newValidationError(path.Add(
+				"filepath").String(),
+				"filepath specified for syslog output",
+			)
+		}
+		if spec.Syslog == nil {
+			return newValidationError(path.Add("syslog").String(), "syslog not specified for syslog output")
+		}
+		if err := ValidateSyslogSpec(path.Add("syslog"), spec.Syslog); err != nil {
+			return err
+		}
+	case OutputKindNetwork:
+		if filepathProvided {
+			return newValidationError(path.Add(
+				"filepath").String(),
+				"filepath specified for network output",
+			)
+		}
+		if spec.Network == nil {
+			return newValidationError(path.Add("network").String(), "network not specified for network output")
+		}
+		if err := ValidateNetworkSpec(path.Add("network"), spec.Network); err != nil {
+			return err
+		}
+	case OutputKindHTTP:
+		if filepathProvided {
+			return newValidationError(path.Add(
+				"filepath").String(),
+				"filepath specified for HTTP output",
+			)
+		}
+		if spec.HTTP == nil {
+			return newValidationError(path.Add("http").String(), "http not specified for HTTP output")
+		}
+		if err := ValidateHTTPSpec(path.Add("http"), spec.HTTP); err != nil {
+			return err
+		}
+	case OutputKindForward:
+		if filepathProvided {
+			return newValidationError(path.Add(
+				"filepath").String(),
+				"filepath specified for forward output",
+			)
+		}
+		if spec.Forward == nil {
+			return newValidationError(path.Add("forward").String(), "forward not specified for forward output")
+		}
+		if err := ValidateForwardSpec(path.Add("forward"), spec.Forward); err != nil {
+			return err
+		}
+	case OutputKindOTLP:
+		if filepathProvided {
+			return newValidationError(path.Add(
+				"filepath").String(),
+				"filepath specified for OTLP output",
+			)
+		}
+		if spec.OTLP == nil {
+			return newValidationError(path.Add("otlp").String(), "otlp not specified for OTLP output")
+		}
+		if err := ValidateOTLPSpec(path.Add("otlp"), spec.OTLP); err != nil {
+			return err
+		}
 	default:
 		return newValidationError(path.Add("outputKind").String(), "unrecognized output kind")
 	}
+	if spec.Syslog != nil && spec.OutputKind != OutputKindSyslog {
+		return newValidationError(path.Add("syslog").String(), "syslog specified for non-syslog output")
+	}
+	if spec.Network != nil && spec.OutputKind != OutputKindNetwork {
+		return newValidationError(path.Add("network").String(), "network specified for non-network output")
+	}
+	if spec.HTTP != nil && spec.OutputKind != OutputKindHTTP {
+		return newValidationError(path.Add("http").String(), "http specified for non-HTTP output")
+	}
+	if spec.Forward != nil && spec.OutputKind != OutputKindForward {
+		return newValidationError(path.Add("forward").String(), "forward specified for non-forward output")
+	}
+	if spec.OTLP != nil && spec.OutputKind != OutputKindOTLP {
+		return newValidationError(path.Add("otlp").String(), "otlp specified for non-OTLP output")
+	}
+	if spec.Files != nil {
+		if spec.OutputKind != OutputKindFile {
+			return newValidationError(path.Add("files").String(), "files specified for non-file output")
+		}
+		if spec.Container != nil && len(spec.Container.LogRoot) > 0 {
+			return newValidationError(path.Add("files").String(), "files specified along with logRoot")
+		}
+		if err := ValidateFilePattern(path.Add("filepath"), spec.Filepath); err != nil {
+			return err
+		}
+		if err := ValidateFilesSpec(path.Add("files"), spec.Files); err != nil {
+			return err
+		}
+	}
+	switch spec.Encoding {
+	case "", EncodingRaw:
+		if spec.Container != nil {
+			return newValidationError(path.Add("container").String(), "container specified for raw encoding")
+		}
+	case EncodingCRI, EncodingDockerJSON:
+		if spec.OutputKind != OutputKindFile && spec.OutputKind != OutputKindStdErr &&
+			spec.OutputKind != OutputKindStdOut {
+			return newValidationError(path.Add("encoding").String(), "unsupported encoding for the output kind")
+		}
+		if spec.Container != nil {
+			if err := ValidateContainerSpec(path.Add("container"), spec.Container); err != nil {
+				return err
+			}
+			if len(spec.Container.LogRoot) > 0 {
+				if spec.OutputKind != OutputKindFile {
+					return newValidationError(
+						path.Add("container").Add("logRoot").String(),
+						"logRoot specified for non-file output",
+					)
+				}
+				if filepathProvided {
+					return newValidationError(path.Add("filepath").String(), "filepath specified along with logRoot")
+				}
+			}
+		}
+	default:
+		return newValidationError(path.Add("encoding").String(), "unrecognized encoding")
+	}
+	if spec.Rotation != nil {
+		if spec.OutputKind != OutputKindFile {
+			return newValidationError(path.Add("rotation").String(), "rotation specified for non-file output")
+		}
+		if err := ValidateRotationSpec(path.Add("rotation"), spec.Rotation); err != nil {
+			return err
+		}
+	}
+	if spec.Buffer != nil {
+		switch {
+		case spec.OutputKind == OutputKindFile:
+			if spec.Files != nil {
+				return newValidationError(path.Add("buffer").String(), "buffer specified along with files")
+			}
+		case spec.OutputKind == OutputKindStdErr, spec.OutputKind == OutputKindStdOut:
+		case spec.OutputKind == OutputKindNetwork && spec.Network != nil && spec.Network.Protocol == NetworkProtocolTCP &&
+			spec.Network.Framing == NetworkFramingNewline:
+		default:
+			return newValidationError(path.Add("buffer").String(), "buffer specified for unsupported output")
+		}
+		if err := ValidateBufferSpec(path.Add("buffer"), spec.Buffer); err != nil {
+			return err
+		}
+		if len(spec.Buffer.Fsync) > 0 && spec.Buffer.Fsync != FsyncNever && spec.OutputKind != OutputKindFile {
+			return newValidationError(path.Add("buffer").Add("fsync").String(), "fsync specified for non-file output")
+		}
+	}
 	if spec.Interval < 0 {
 		return newInvalidValueError(path.Add("interval").String())
 	}
+	if spec.TargetLogsPerSecond < 0 {
+		return newInvalidValueError(path.Add("targetLogsPerSecond").String())
+	}
+	if spec.TargetBytesPerSecond < 0 {
+		return newInvalidValueError(path.Add("targetBytesPerSecond").String())
+	}
+	if spec.Interval > 0 && (spec.TargetLogsPerSecond > 0 || spec.TargetBytesPerSecond > 0) {
+		return newValidationError(path.Add("interval").String(), "interval specified along with a target rate")
+	}
+	if spec.Profile != nil {
+		if spec.Interval > 0 {
+			return newValidationError(path.Add("interval").String(), "interval specified along with a profile")
+		}
+		if spec.TargetLogsPerSecond > 0 {
+			return newValidationError(
+				path.Add("targetLogsPerSecond").String(),
+				"targetLogsPerSecond specified along with a profile",
+			)
+		}
+		if err := ValidateProfileSpec(path.Add("profile"), spec.Profile); err != nil {
+			return err
+		}
+	}
+	if spec.MaxCount < 0 {
+		return newInvalidValueError(path.Add("maxCount").String())
+	}
+	if spec.MaxBytes < 0 {
+		return newInvalidValueError(path.Add("maxBytes").String())
+	}
+	if spec.Duration < 0 {
+		return newInvalidValueError(path.Add("duration").String())
+	}
+	if spec.Workers < 0 {
+		return newInvalidValueError(path.Add("workers").String())
+	}
+	switch spec.WorkerWriter {
+	case "", WorkerWriterShared:
+	case WorkerWriterPerWorker:
+		if spec.OutputKind == OutputKindFile && spec.Workers > 1 {
+			if spec.Files != nil {
+				return newValidationError(path.Add("workerWriter").String(), "per-worker writers specified with files")
+			}
+			if spec.Container != nil && len(spec.Container.LogRoot) > 0 {
+				return newValidationError(
+					path.Add("workerWriter").String(), "per-worker writers specified along with logRoot",
+				)
+			}
+			if err := ValidateFilePattern(path.Add("filepath"), spec.Filepath); err != nil {
+				return err
+			}
+		}
+	default:
+		return newValidationError(path.Add("workerWriter").String(), "unrecognized worker writer")
+	}
+	return nil
+}
+
+// ValidateMultilineSpec validates a MultilineSpec object.
+func ValidateMultilineSpec(path fieldpath.FieldPath, spec *MultilineSpec) error {
+	for i, kind := range spec.Kinds {
+		switch kind {
+		case MultilineKindJava, MultilineKindPython, MultilineKindGo:
+		default:
+			return newValidationError(path.Add(fmt.Sprintf("kinds[%d]", i)).String(), "unrecognized multiline kind")
+		}
+	}
+	if spec.Frequency <= 0 || spec.Frequency > 1 {
+		return newInvalidValueError(path.Add("frequency").String())
+	}
+	if spec.MinDepth < 0 {
+		return newInvalidValueError(path.Add("minDepth").String())
+	}
+	minDepth := spec.MinDepth
+	if minDepth == 0 {
+		minDepth = MultilineDefaultMinDepth
+	}
+	if spec.MaxDepth < 0 || (spec.MaxDepth > 0 && spec.MaxDepth < minDepth) {
+		return newInvalidValueError(path.Add("maxDepth").String())
+	}
+	return nil
+}
+
+// ValidateAccessLogSpec validates an AccessLogSpec object.
+func ValidateAccessLogSpec(path fieldpath.FieldPath, spec *AccessLogSpec) error {
+	distributions := []struct {
+		name   string
+		values []WeightedValue
+	}{
+		{"methods", spec.Methods},
+		{"paths", spec.Paths},
+		{"statuses", spec.Statuses},
+		{"referrers", spec.Referrers},
+		{"userAgents", spec.UserAgents},
+	}
+	for _, distribution := range distributions {
+		var total float64
+		for i, value := range distribution.values {
+			valuePath := path.Add(fmt.Sprintf("%s[%d]", distribution.name, i))
+			if len(value.Value) == 0 {
+				return newValidationError(valuePath.Add("value").String(), "value not specified")
+			}
+			if value.Weight < 0 {
+				return newInvalidValueError(valuePath.Add("weight").String())
+			}
+			total += value.Weight
+		}
+		if len(distribution.values) > 0 && total == 0 {
+			return newValidationError(path.Add(distribution.name).String(), "all weights are zero")
+		}
+	}
+	for i := range spec.Statuses {
+		if code, err := strconv.Atoi(spec.Statuses[i].Value); err != nil || code < 100 || code > 599 {
+			return newInvalidValueError(path.Add(fmt.Sprintf("statuses[%d]", i)).Add("value").String())
+		}
+	}
+	if spec.MinBytes < 0 {
+		return newInvalidValueError(path.Add("minBytes").String())
+	}
+	if spec.MaxBytes < 0 || (spec.MaxBytes > 0 && spec.MaxBytes < spec.MinBytes) {
+		return newInvalidValueError(path.Add("maxBytes").String())
+	}
+	if len(spec.ClientNetwork) > 0 {
+		if _, _, err := net.ParseCIDR(spec.ClientNetwork); err != nil {
+			return newInvalidValueError(path.Add("clientNetwork").String())
+		}
+	}
+	return nil
+}
+
+// ValidateFieldSpec validates a FieldSpec object.
+func ValidateFieldSpec(path fieldpath.FieldPath, spec *FieldSpec) error {
+	if len(spec.Name) == 0 {
+		return newValidationError(path.Add("name").String(), "name not specified")
+	}
+	switch spec.Kind {
+	case FieldKindConstant:
+	case FieldKindPick:
+		if len(spec.Values) == 0 {
+			return newValidationError(path.Add("values").String(), "values not specified")
+		}
+	case FieldKindRandomString:
+		if spec.Size <= 0 {
+			return newInvalidValueError(path.Add("size").String())
+		}
+	case FieldKindRandomInt:
+		if spec.Max < spec.Min {
+			return newValidationError(path.Add("max").String(), "max smaller than min")
+		}
+		if spec.Max-spec.Min+1 <= 0 {
+			return newValidationError(path.String(), "range too large")
+		}
+	default:
+		return newValidationError(path.Add("kind").String(), "unrecognized field kind")
+	}
+	return nil
+}
+
+// ValidateRotationSpec validates a RotationSpec object.
+func ValidateRotationSpec(path fieldpath.FieldPath, spec *RotationSpec) error {
+	if spec.MaxSize < 0 {
+		return newInvalidValueError(path.Add("maxSize").String())
+	}
+	if spec.MaxAge < 0 {
+		return newInvalidValueError(path.Add("maxAge").String())
+	}
+	if spec.MaxSize == 0 && spec.MaxAge == 0 {
+		return newValidationError(path.String(), "neither maxSize nor maxAge specified")
+	}
+	if spec.MaxBackups < 1 {
+		return newInvalidValueError(path.Add("maxBackups").String())
+	}
+	switch spec.Strategy {
+	case RotationStrategyRename:
+	case RotationStrategyCopyTruncate:
+	default:
+		return newValidationError(path.Add("strategy").String(), "unrecognized rotation strategy")
+	}
+	return nil
+}
+
+// ValidateBufferSpec validates a BufferSpec object.
+func ValidateBufferSpec(path fieldpath.FieldPath, spec *BufferSpec) error {
+	if spec.Size < 0 {
+		return newInvalidValueError(path.Add("size").String())
+	}
+	if spec.FlushInterval < 0 {
+		return newInvalidValueError(path.Add("flushInterval").String())
+	}
+	if spec.FsyncBytes < 0 {
+		return newInvalidValueError(path.Add("fsyncBytes").String())
+	}
+	switch spec.Fsync {
+	case "", FsyncNever, FsyncFlush:
+		if spec.FsyncBytes > 0 {
+			return newValidationError(path.Add("fsyncBytes").String(), "fsyncBytes specified without the Bytes policy")
+		}
+	case FsyncBytes:
+		if spec.FsyncBytes == 0 {
+			return newValidationError(path.Add("fsyncBytes").String(), "fsyncBytes not specified")
+		}
+	default:
+		return newValidationError(path.Add("fsync").String(), "unrecognized fsync policy")
+	}
+	return nil
+}
+
+// ValidateFilePattern validates the Filepath of a LogTaskSpec with a FilesSpec, which must have a single integer
+// verb.
+func ValidateFilePattern(path fieldpath.FieldPath, pattern string) error {
+	first, second := fmt.Sprintf(pattern, 0), fmt.Sprintf(pattern, 1)
+	if strings.Contains(first, "%!") || first == second {
+		return newValidationError(path.String(), "filepath is not a pattern with a single integer verb such as %d")
+	}
+	return nil
+}
+
+// ValidateFilesSpec validates a FilesSpec object.
+func ValidateFilesSpec(path fieldpath.FieldPath, spec *FilesSpec) error {
+	if spec.Count < 1 {
+		return newInvalidValueError(path.Add("count").String())
+	}
+	switch spec.Distribution {
+	case FileDistributionRoundRobin, FileDistributionRandom:
+		if len(spec.Weights) > 0 {
+			return newValidationError(path.Add("weights").String(), "invalid field")
+		}
+	case FileDistributionWeighted:
+		if len(spec.Weights) != spec.Count {
+			return newValidationError(path.Add("weights").String(), "the number of weights does not match count")
+		}
+		var total float64
+		for i, weight := range spec.Weights {
+			if weight < 0 {
+				return newInvalidValueError(path.Add(fmt.Sprintf("weights[%d]", i)).String())
+			}
+			total += weight
+		}
+		if total <= 0 {
+			return newValidationError(path.Add("weights").String(), "all weights are zero")
+		}
+	default:
+		return newValidationError(path.Add("distribution").String(), "unrecognized distribution")
+	}
+	if spec.ChurnInterval < 0 {
+		return newInvalidValueError(path.Add("churnInterval").String())
+	}
+	if spec.ChurnCount < 0 || spec.ChurnCount > spec.Count {
+		return newInvalidValueError(path.Add("churnCount").String())
+	}
+	if spec.ChurnCount > 0 && spec.ChurnInterval == 0 {
+		return newValidationError(path.Add("churnCount").String(), "churnCount specified without churnInterval")
+	}
+	return nil
+}
+
+// ValidateContainerSpec validates a ContainerSpec object.
+func ValidateContainerSpec(path fieldpath.FieldPath, spec *ContainerSpec) error {
+	switch spec.Stream {
+	case "", ContainerStreamStdout, ContainerStreamStderr:
+	default:
+		return newValidationError(path.Add("stream").String(), "unrecognized stream")
+	}
+	names := []struct {
+		field string
+		value string
+	}{
+		{"namespace", spec.Namespace},
+		{"pod", spec.Pod},
+		{"podUID", spec.PodUID},
+		{"name", spec.Name},
+		{"id", spec.ID},
+	}
+	for _, name := range names {
+		if strings.ContainsAny(name.value, "/_") || name.value == "." || name.value == ".." {
+			return newInvalidValueError(path.Add(name.field).String())
+		}
+	}
+	if spec.RestartCount < 0 {
+		return newInvalidValueError(path.Add("restartCount").String())
+	}
+	return nil
+}
+
+// ValidateSyslogSpec validates a SyslogSpec object.
+func ValidateSyslogSpec(path fieldpath.FieldPath, spec *SyslogSpec) error {
+	if len(spec.Address) == 0 {
+		return newValidationError(path.Add("address").String(), "address not specified")
+	}
+	switch spec.Transport {
+	case SyslogTransportUDP:
+	case SyslogTransportTCP:
+	case SyslogTransportTCPOctetCounting:
+	case SyslogTransportUnixgram:
+	default:
+		return newValidationError(path.Add("transport").String(), "unrecognized syslog transport")
+	}
+	switch spec.Format {
+	case SyslogFormatRFC3164:
+	case SyslogFormatRFC5424:
+	default:
+		return newValidationError(path.Add("format").String(), "unrecognized syslog format")
+	}
+	if len(spec.Facility) > 0 && indexOf(SyslogFacilities, spec.Facility) < 0 {
+		return newValidationError(path.Add("facility").String(), "unrecognized syslog facility")
+	}
+	if len(spec.Severity) > 0 && indexOf(SyslogSeverities, spec.Severity) < 0 {
+		return newValidationError(path.Add("severity").String(), "unrecognized syslog severity")
+	}
+	if strings.ContainsAny(spec.Hostname, " \t\n") {
+		return newValidationError(path.Add("hostname").String(), "hostname must not contain whitespace")
+	}
+	if strings.ContainsAny(spec.AppName, " \t\n:[]") {
+		return newValidationError(path.Add("appName").String(), "appName must not contain whitespace or ':[]'")
+	}
+	return nil
+}
+
+// ValidateNetworkSpec validates a NetworkSpec object.
+func ValidateNetworkSpec(path fieldpath.FieldPath, spec *NetworkSpec) error {
+	if len(spec.Address) == 0 {
+		return newValidationError(path.Add("address").String(), "address not specified")
+	}
+	switch spec.Protocol {
+	case NetworkProtocolTCP:
+	case NetworkProtocolUDP:
+	default:
+		return newValidationError(path.Add("protocol").String(), "unrecognized network protocol")
+	}
+	switch spec.Framing {
+	case NetworkFramingNewline:
+	case NetworkFramingOctetCounting:
+	case NetworkFramingLengthPrefix:
+	default:
+		return newValidationError(path.Add("framing").String(), "unrecognized network framing")
+	}
+	if spec.ConnectTimeout < 0 {
+		return newInvalidValueError(path.Add("connectTimeout").String())
+	}
+	if spec.MaxReconnectBackoff < 0 {
+		return newInvalidValueError(path.Add("maxReconnectBackoff").String())
+	}
+	return nil
+}
+
+// ValidateHTTPSpec validates a HTTPSpec object.
+func ValidateHTTPSpec(path fieldpath.FieldPath, spec *HTTPSpec) error {
+	endpoint, err := url.Parse(spec.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+		return newValidationError(path.Add("url").String(), "invalid HTTP or HTTPS URL")
+	}
+	if strings.ContainsAny(spec.Method, " \t\r\n") {
+		return newInvalidValueError(path.Add("method").String())
+	}
+	for key := range spec.Headers {
+		if len(key) == 0 || strings.ContainsAny(key, " \t\r\n:") {
+			return newValidationError(path.Add("headers").String(), fmt.Sprintf("invalid header name '%s'", key))
+		}
+	}
+	switch spec.Format {
+	case HTTPFormatNDJSON:
+		if len(spec.BodyTemplate) > 0 {
+			return newValidationError(path.Add("bodyTemplate").String(), "invalid field")
+		}
+	case HTTPFormatTemplate:
+		if len(spec.BodyTemplate) == 0 {
+			return newValidationError(path.Add("bodyTemplate").String(), "bodyTemplate not specified")
+		}
+		if _, err = ParseHTTPBodyTemplate(spec.BodyTemplate); err != nil {
+			return newValidationError(path.Add("bodyTemplate").String(), err.Error())
+		}
+	default:
+		return newValidationError(path.Add("format").String(), "unrecognized HTTP format")
+	}
+	if spec.BatchSize < 0 {
+		return newInvalidValueError(path.Add("batchSize").String())
+	}
+	if spec.BatchBytes < 0 {
+		return newInvalidValueError(path.Add("batchBytes").String())
+	}
+	if spec.BatchInterval < 0 {
+		return newInvalidValueError(path.Add("batchInterval").String())
+	}
+	if spec.Timeout < 0 {
+		return newInvalidValueError(path.Add("timeout").String())
+	}
+	if spec.MaxRetries < 0 {
+		return newInvalidValueError(path.Add("maxRetries").String())
+	}
+	return nil
+}
+
+// ValidateForwardSpec validates a ForwardSpec object.
+func ValidateForwardSpec(path fieldpath.FieldPath, spec *ForwardSpec) error {
+	if len(spec.Address) == 0 {
+		return newValidationError(path.Add("address").String(), "address not specified")
+	}
+	switch spec.Mode {
+	case ForwardModeMessage:
+		if spec.BatchSize != 0 {
+			return newValidationError(path.Add("batchSize").String(), "invalid field")
+		}
+		if spec.BatchInterval != 0 {
+			return newValidationError(path.Add("batchInterval").String(), "invalid field")
+		}
+	case ForwardModeForward:
+	case ForwardModePackedForward:
+	default:
+		return newValidationError(path.Add("mode").String(), "unrecognized forward mode")
+	}
+	if spec.AckTimeout < 0 {
+		return newInvalidValueError(path.Add("ackTimeout").String())
+	}
+	if spec.BatchSize < 0 {
+		return newInvalidValueError(path.Add("batchSize").String())
+	}
+	if spec.BatchInterval < 0 {
+		return newInvalidValueError(path.Add("batchInterval").String())
+	}
+	return nil
+}
+
+// ValidateOTLPSpec validates an OTLPSpec object.
+func ValidateOTLPSpec(path fieldpath.FieldPath, spec *OTLPSpec) error {
+	endpoint, err := url.Parse(spec.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+		return newValidationError(path.Add("endpoint").String(), "invalid HTTP or HTTPS URL")
+	}
+	switch spec.Protocol {
+	case OTLPProtocolHTTP:
+	case OTLPProtocolGRPC:
+	default:
+		return newValidationError(path.Add("protocol").String(), "unrecognized OTLP protocol")
+	}
+	for key := range spec.Headers {
+		if len(key) == 0 || strings.ContainsAny(key, " \t\r\n:") {
+			return newValidationError(path.Add("headers").String(), fmt.Sprintf("invalid header name '%s'", key))
+		}
+	}
+	if len(spec.Severity) > 0 && indexOf(LogLevels, spec.Severity) < 0 {
+		return newValidationError(path.Add("severity").String(), "unrecognized severity")
+	}
+	for key := range spec.ResourceAttributes {
+		if len(key) == 0 {
+			return newValidationError(path.Add("resourceAttributes").String(), "empty attribute name")
+		}
+	}
+	for key := range spec.Attributes {
+		if len(key) == 0 {
+			return newValidationError(path.Add("attributes").String(), "empty attribute name")
+		}
+	}
+	if spec.BatchSize < 0 {
+		return newInvalidValueError(path.Add("batchSize").String())
+	}
+	if spec.BatchInterval < 0 {
+		return newInvalidValueError(path.Add("batchInterval").String())
+	}
+	if spec.Timeout < 0 {
+		return newInvalidValueError(path.Add("timeout").String())
+	}
+	if spec.MaxRetries < 0 {
+		return newInvalidValueError(path.Add("maxRetries").String())
+	}
+	return nil
+}
+
+// ValidateProfileSpec validates a ProfileSpec object.
+func ValidateProfileSpec(path fieldpath.FieldPath, spec *ProfileSpec) error {
+	switch spec.Kind {
+	case ProfileKindRamp:
+		if spec.From < 0 {
+			return newInvalidValueError(path.Add("from").String())
+		}
+		if spec.To < 0 {
+			return newInvalidValueError(path.Add("to").String())
+		}
+		if spec.Duration <= 0 {
+			return newInvalidValueError(path.Add("duration").String())
+		}
+	case ProfileKindStep:
+		if len(spec.Steps) == 0 {
+			return newValidationError(path.Add("steps").String(), "steps not specified")
+		}
+		for i, step := range spec.Steps {
+			stepPath := path.Add(fmt.Sprintf("steps[%d]", i))
+			if step.Rate < 0 {
+				return newInvalidValueError(stepPath.Add("rate").String())
+			}
+			if step.Duration <= 0 {
+				return newInvalidValueError(stepPath.Add("duration").String())
+			}
+		}
+	case ProfileKindSine:
+		if spec.Amplitude < 0 {
+			return newInvalidValueError(path.Add("amplitude").String())
+		}
+		if spec.Base < spec.Amplitude {
+			return newValidationError(path.Add("base").String(), "base smaller than amplitude")
+		}
+		if spec.Period <= 0 {
+			return newInvalidValueError(path.Add("period").String())
+		}
+	case ProfileKindBurst:
+		if spec.Base < 0 {
+			return newInvalidValueError(path.Add("base").String())
+		}
+		if spec.BurstSize <= 0 {
+			return newInvalidValueError(path.Add("burstSize").String())
+		}
+		if spec.Period <= 0 {
+			return newInvalidValueError(path.Add("period").String())
+		}
+	default:
+		return newValidationError(path.Add("kind").String(), "unrecognized profile kind")
+	}
 	return nil
 }
 
@@ -85,6 +850,9 @@ func ValidateLogTaskStatus(path fieldpath.FieldPath, status *LogTaskStatus) erro
 	switch status.Phase {
 	case PhaseIdle:
 	case PhaseRunning:
+	case PhasePaused:
+	case PhaseUpdating:
+	case PhaseStopped:
 	case PhaseFailed:
 	default:
 		return newValidationError(path.Add("phase").String(), "unrecognized phase")
@@ -95,9 +863,81 @@ func ValidateLogTaskStatus(path fieldpath.FieldPath, status *LogTaskStatus) erro
 	if status.SentBytes < 0 {
 		return newInvalidValueError(path.Add("sentBytes").String())
 	}
+	if status.LogsPerSecond < 0 {
+		return newInvalidValueError(path.Add("logsPerSecond").String())
+	}
+	if status.BytesPerSecond < 0 {
+		return newInvalidValueError(path.Add("bytesPerSecond").String())
+	}
+	if status.TargetLogsPerSecond < 0 {
+		return newInvalidValueError(path.Add("targetLogsPerSecond").String())
+	}
+	if status.Shortfall < 0 || status.Shortfall > 1 {
+		return newInvalidValueError(path.Add("shortfall").String())
+	}
+	if status.Rotations < 0 {
+		return newInvalidValueError(path.Add("rotations").String())
+	}
+	if status.BufferedBytes < 0 {
+		return newInvalidValueError(path.Add("bufferedBytes").String())
+	}
+	if status.Flushes < 0 {
+		return newInvalidValueError(path.Add("flushes").String())
+	}
+	if status.Fsyncs < 0 {
+		return newInvalidValueError(path.Add("fsyncs").String())
+	}
+	if status.Reconnects < 0 {
+		return newInvalidValueError(path.Add("reconnects").String())
+	}
+	if status.DroppedWrites < 0 {
+		return newInvalidValueError(path.Add("droppedWrites").String())
+	}
+	for code, count := range status.HTTPResponses {
+		if count < 0 {
+			return newInvalidValueError(path.Add("httpResponses").Add(code).String())
+		}
+	}
+	for level, count := range status.LevelCounts {
+		if count < 0 {
+			return newInvalidValueError(path.Add("levelCounts").Add(level).String())
+		}
+	}
+	if status.HTTPRequestLatency < 0 {
+		return newInvalidValueError(path.Add("httpRequestLatency").String())
+	}
+	for i := range status.Files {
+		if status.Files[i].SentCount < 0 {
+			return newInvalidValueError(path.Add(fmt.Sprintf("files[%d]", i)).Add("sentCount").String())
+		}
+		if status.Files[i].SentBytes < 0 {
+			return newInvalidValueError(path.Add(fmt.Sprintf("files[%d]", i)).Add("sentBytes").String())
+		}
+	}
+	if status.RemovedFiles < 0 {
+		return newInvalidValueError(path.Add("removedFiles").String())
+	}
+	for i := range status.Workers {
+		if status.Workers[i].SentCount < 0 {
+			return newInvalidValueError(path.Add(fmt.Sprintf("workers[%d]", i)).Add("sentCount").String())
+		}
+		if status.Workers[i].SentBytes < 0 {
+			return newInvalidValueError(path.Add(fmt.Sprintf("workers[%d]", i)).Add("sentBytes").String())
+		}
+	}
 	return nil
 }
 
+// indexOf returns the index of the given value in the slice, or -1 if it is not found.
+func indexOf(values []string, value string) int {
+	for i := range values {
+		if values[i] == value {
+			return i
+		}
+	}
+	return -1
+}
+
 func newValidationError(path, reason string) error {
 	return fmt.Errorf("failed to validate '%s': %s", path, reason)
 }
@@ -105,3 +945,14 @@ func newValidationError(path, reason string) error {
 func newInvalidValueError(path string) error {
 	return newValidationError(path, "invalid value")
 }
+
+// checkMessageTemplate returns an error if the Message of a LogTaskSpec with ContentTypeTemplate cannot be parsed.
+// The functions are stubs that only let the template be parsed.
+func checkMessageTemplate(message string) error {
+	funcs := make(template.FuncMap, len(MessageTemplateFunctions))
+	for _, name := range MessageTemplateFunctions {
+		funcs[name] = func(...interface{}) string { return "" }
+	}
+	_, err := template.New("message").Funcs(funcs).Parse(message)
+	return err
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// minFlushPeriod is the shortest period at which the age of the pending batch is checked.
+const minFlushPeriod = 10 * time.Millisecond
+
+// batcher groups the log messages written to an output, or the items an output encodes them into, into batches
+// and hands every batch to a send function.
+// A batch is sent by the goroutine writing to the output when it is full, or in the background when it is old
+// enough. The send function is always called with the mutex of the batcher held, so it is never called
+// concurrently.
+type batcher struct {
+	send      func(lines [][]byte)
+	maxSize   int
+	maxBytes  int
+	interval  time.Duration
+	mutex     sync.Mutex
+	lines     [][]byte
+	size      int
+	startedAt time.Time
+	stopCh    chan struct{}
+	done      chan struct{}
+}
+
+// newBatcher creates a batcher that sends a batch once it has maxSize log messages, maxBytes bytes, or is older
+// than interval. A zero maxBytes means no limit on the size in bytes.
+func newBatcher(maxSize, maxBytes int, interval time.Duration, send func(lines [][]byte)) *batcher {
+	ret := &batcher{
+		send:     send,
+		maxSize:  maxSize,
+		maxBytes: maxBytes,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go ret.flushPeriodically()
+	return ret
+}
+
+// add adds a copy of p, without the trailing newline, to the pending batch, sending the batch if it is full.
+func (b *batcher) add(p []byte) {
+	b.addItem(bytes.TrimSuffix(p, []byte("\n")))
+}
+
+// addItem adds a copy of item to the pending batch as is, sending the batch if it is full.
+func (b *batcher) addItem(item []byte) {
+	line := append([]byte(nil), item...)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.lines) > 0 && b.maxBytes > 0 && b.size+len(line) > b.maxBytes {
+		b.flush()
+	}
+	if len(b.lines) == 0 {
+		b.startedAt = time.Now()
+	}
+	b.lines = append(b.lines, line)
+	b.size += len(line)
+	if len(b.lines) >= b.maxSize || (b.maxBytes > 0 && b.size >= b.maxBytes) {
+		b.flush()
+	}
+}
+
+// close sends the pending batch and stops the background goroutine; stopping is closed before that so that the
+// send function can give up retrying.
+func (b *batcher) close() {
+	close(b.stopCh)
+	<-b.done
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flush()
+}
+
+// stopping returns a channel that is closed when the batcher starts closing.
+func (b *batcher) stopping() <-chan struct{} {
+	return b.stopCh
+}
+
+func (b *batcher) flushPeriodically() {
+	defer close(b.done)
+	period := b.interval / 10
+	if period < minFlushPeriod {
+		period = minFlushPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			b.mutex.Lock()
+			if len(b.lines) > 0 && time.Since(b.startedAt) >= b.interval {
+				b.flush()
+			}
+			b.mutex.Unlock()
+		}
+	}
+}
+
+// flush sends the pending batch and empties it; it must be called with the mutex held.
+func (b *batcher) flush() {
+	if len(b.lines) == 0 {
+		return
+	}
+	b.send(b.lines)
+	b.lines = b.lines[:0]
+	b.size = 0
+}
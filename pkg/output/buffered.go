@@ -0,0 +1,168 @@
+package output
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// syncer is implemented by the outputs that can sync what has been written to them to the disk.
+type syncer interface {
+	Sync() error
+}
+
+// bufferedOutput gathers the log messages written to it and writes them to an output at once, syncing the output to
+// the disk according to the fsync policy. The buffer is flushed by the goroutine writing to the output when it is
+// full, or in the background when it is old enough.
+// The log messages buffered when the output drops a flush with ErrDropped are recorded as dropped; any other error
+// of a flush is returned by the Write that triggered it, or by the next Write or Close if it happened in the
+// background.
+type bufferedOutput struct {
+	out        io.WriteCloser
+	recorder   Recorder
+	size       int
+	interval   time.Duration
+	fsync      string
+	fsyncBytes int64
+	mutex      sync.Mutex
+	buf        []byte
+	count      int
+	startedAt  time.Time
+	unsynced   int64
+	err        error
+	stopCh     chan struct{}
+	done       chan struct{}
+}
+
+func newBufferedOutput(out io.WriteCloser, spec *model.BufferSpec, recorder Recorder) *bufferedOutput {
+	ret := &bufferedOutput{
+		out:        out,
+		recorder:   recorder,
+		size:       spec.Size,
+		interval:   time.Duration(float64(time.Second) * spec.FlushInterval),
+		fsync:      spec.Fsync,
+		fsyncBytes: spec.FsyncBytes,
+		buf:        make([]byte, 0, spec.Size),
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if ret.interval > 0 {
+		go ret.flushPeriodically()
+	} else {
+		close(ret.done)
+	}
+	return ret
+}
+
+// Write adds p to the buffer, flushing the buffer before if p does not fit in it and after if it is full; a log
+// message larger than the buffer is flushed on its own.
+func (b *bufferedOutput) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err := b.err; err != nil {
+		b.err = nil
+		return 0, err
+	}
+	if len(b.buf) > 0 && len(b.buf)+len(p) > b.size {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if len(b.buf) == 0 {
+		b.startedAt = time.Now()
+	}
+	b.buf = append(b.buf, p...)
+	b.count++
+	b.recorder.RecordBuffer(len(p))
+	if len(b.buf) >= b.size {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes the buffer, syncs the output if the fsync policy is not FsyncNever, and closes the output.
+func (b *bufferedOutput) Close() error {
+	close(b.stopCh)
+	<-b.done
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	err := b.err
+	if flushErr := b.flush(); err == nil {
+		err = flushErr
+	}
+	if b.unsynced > 0 && len(b.fsync) > 0 && b.fsync != model.FsyncNever {
+		if syncErr := b.sync(); err == nil {
+			err = syncErr
+		}
+	}
+	if closeErr := b.out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (b *bufferedOutput) flushPeriodically() {
+	defer close(b.done)
+	period := b.interval / 10
+	if period < minFlushPeriod {
+		period = minFlushPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			b.mutex.Lock()
+			if len(b.buf) > 0 && time.Since(b.startedAt) >= b.interval {
+				if err := b.flush(); err != nil && b.err == nil {
+					b.err = err
+				}
+			}
+			b.mutex.Unlock()
+		}
+	}
+}
+
+// flush writes the buffered log messages to the output and syncs it if the fsync policy says so; it must be called
+// with the mutex held.
+func (b *bufferedOutput) flush() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	_, err := b.out.Write(b.buf)
+	size, count := len(b.buf), b.count
+	b.buf, b.count = b.buf[:0], 0
+	b.recorder.RecordFlush(size)
+	if err == ErrDropped {
+		b.recorder.RecordDrop(count)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.unsynced += int64(size)
+	if b.fsync == model.FsyncFlush || (b.fsync == model.FsyncBytes && b.unsynced >= b.fsyncBytes) {
+		return b.sync()
+	}
+	return nil
+}
+
+// sync syncs the output to the disk; it must be called with the mutex held.
+func (b *bufferedOutput) sync() error {
+	s, ok := b.out.(syncer)
+	if !ok {
+		return nil
+	}
+	if err := s.Sync(); err != nil {
+		return err
+	}
+	b.unsynced = 0
+	b.recorder.RecordSync()
+	return nil
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestBufferedOutput(t *testing.T) {
+	const line = "0123456789\n"
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.log")
+	recorder := new(countingRecorder)
+	file, err := newFileOutput(path, nil, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	out := newBufferedOutput(file, &model.BufferSpec{
+		Size:       3 * len(line),
+		Fsync:      model.FsyncBytes,
+		FsyncBytes: int64(5 * len(line)),
+	}, recorder)
+	for i := 0; i < 10; i++ {
+		if _, err = out.Write([]byte(line)); err != nil {
+			t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+		}
+	}
+	// 9 log messages are flushed in 3 flushes, and the file is synced once 5 of them are flushed
+	checkContent(t, path, false, strings.Repeat(line, 9))
+	if recorder.flushes != 3 || recorder.syncs != 1 || recorder.buffered != len(line) {
+		t.Fatalf("unexpected records before close: %+v", recorder)
+	}
+	if err = out.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	checkContent(t, path, false, strings.Repeat(line, 10))
+	if recorder.flushes != 4 || recorder.syncs != 2 || recorder.buffered != 0 {
+		t.Fatalf("unexpected records after close: %+v", recorder)
+	}
+
+	recorder = new(countingRecorder)
+	if file, err = newFileOutput(path, nil, recorder); err != nil {
+		t.Fatal(err.Error())
+	}
+	out = newBufferedOutput(file, &model.BufferSpec{Size: 1024, FlushInterval: 0.05}, recorder)
+	defer out.Close()
+	if _, err = out.Write([]byte(line)); err != nil {
+		t.Fatal(err.Error())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		recorder.mutex.Lock()
+		flushes := recorder.flushes
+		recorder.mutex.Unlock()
+		if flushes == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the buffer to be flushed in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	checkContent(t, path, false, strings.Repeat(line, 11))
+}
@@ -0,0 +1,120 @@
+package output
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrDropped is returned by the outputs that send the log messages over the network when a log message is
+// dropped because the connection is down; unlike other errors, it does not mean the output is broken. It is
+// returned along with the size of the log message, which is counted as sent like the log messages dropped from a
+// batch.
+var ErrDropped = errors.New("log message dropped")
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultMinBackoff  = 100 * time.Millisecond
+	defaultMaxBackoff  = 10 * time.Second
+
+	// writeTimeout bounds the time spent on writing a single log message.
+	writeTimeout = 5 * time.Second
+)
+
+// reconnectingConn is a connection to a server that is made by the first write and re-established when a write
+// fails. While the connection is down, including when the server is not up yet, it is only redialed when the
+// backoff, which doubles after every failed attempt, has expired; writes in the meantime are dropped.
+type reconnectingConn struct {
+	network     string
+	address     string
+	dialTimeout time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	recorder    Recorder
+	conn        net.Conn
+	dialed      bool
+	backoff     time.Duration
+	retryAt     time.Time
+}
+
+func newReconnectingConn(network, address string, dialTimeout, maxBackoff time.Duration,
+	recorder Recorder) *reconnectingConn {
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	minBackoff := defaultMinBackoff
+	if minBackoff > maxBackoff {
+		minBackoff = maxBackoff
+	}
+	return &reconnectingConn{
+		network:     network,
+		address:     address,
+		dialTimeout: dialTimeout,
+		minBackoff:  minBackoff,
+		maxBackoff:  maxBackoff,
+		recorder:    recorder,
+		backoff:     minBackoff,
+	}
+}
+
+// write writes b to the server as a whole. If the write fails, the connection is re-established right away and
+// b is written again; ErrDropped is returned if that fails too or if the connection is down.
+func (c *reconnectingConn) write(b []byte) error {
+	if c.conn != nil {
+		if c.send(b) == nil {
+			return nil
+		}
+		c.close()
+		c.retryAt = time.Time{}
+	}
+	if !c.reconnect() {
+		return ErrDropped
+	}
+	if c.send(b) != nil {
+		c.close()
+		return ErrDropped
+	}
+	return nil
+}
+
+func (c *reconnectingConn) send(b []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// reconnect dials the server if the backoff has expired and reports whether it is connected. Every dial but a
+// successful first one is recorded as a reconnection.
+func (c *reconnectingConn) reconnect() bool {
+	now := time.Now()
+	if now.Before(c.retryAt) {
+		return false
+	}
+	conn, err := net.DialTimeout(c.network, c.address, c.dialTimeout)
+	if err != nil || c.dialed {
+		c.recorder.RecordReconnect()
+	}
+	c.dialed = true
+	if err != nil {
+		c.retryAt = time.Now().Add(c.backoff)
+		if c.backoff *= 2; c.backoff > c.maxBackoff {
+			c.backoff = c.maxBackoff
+		}
+		return false
+	}
+	c.conn = conn
+	c.backoff = c.minBackoff
+	return true
+}
+
+func (c *reconnectingConn) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
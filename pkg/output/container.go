@@ -0,0 +1,143 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultContainerNamespace = "default"
+	defaultContainerName      = "logtap"
+)
+
+// containerOutput writes the log messages to the underlying output in the format of the log files of a container
+// runtime, splitting the log messages longer than ContainerMaxLineSize into partial ones.
+type containerOutput struct {
+	io.WriteCloser
+	encoding string
+	stream   string
+	buf      []byte
+}
+
+func newContainerOutput(out io.WriteCloser, encoding string, spec *model.ContainerSpec) *containerOutput {
+	ret := &containerOutput{
+		WriteCloser: out,
+		encoding:    encoding,
+		stream:      model.ContainerStreamStdout,
+	}
+	if spec != nil && len(spec.Stream) > 0 {
+		ret.stream = spec.Stream
+	}
+	return ret
+}
+
+// Write writes p as a log message printed at the current time.
+func (c *containerOutput) Write(p []byte) (int, error) {
+	return c.WriteTimed(p, time.Now(), "")
+}
+
+// WriteTimed writes p, which is a single log message, as one or more lines stamped with the given time; every line
+// of a multi-line log message becomes a line of its own, as the container runtime would see it. It returns the size
+// of p rather than that of the lines. The level is ignored.
+func (c *containerOutput) WriteTimed(p []byte, t time.Time, _ string) (int, error) {
+	timestamp := t.UTC().Format(time.RFC3339Nano)
+	c.buf = c.buf[:0]
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		c.writeLine(timestamp, line)
+	}
+	if _, err := c.WriteCloser.Write(c.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeLine writes a line without the trailing new line, splitting it into partial ones if it is too long.
+func (c *containerOutput) writeLine(timestamp string, line []byte) {
+	for {
+		chunk, partial := line, len(line) > model.ContainerMaxLineSize
+		if partial {
+			chunk = line[:model.ContainerMaxLineSize]
+		}
+		line = line[len(chunk):]
+		if c.encoding == model.EncodingCRI {
+			c.writeCRILine(timestamp, chunk, partial)
+		} else {
+			c.writeDockerRecord(timestamp, chunk, partial)
+		}
+		if !partial {
+			return
+		}
+	}
+}
+
+// writeCRILine writes a line in the format of "<timestamp> <stream> <tag> <content>", where the tag is P for a
+// partial line and F for the last one.
+func (c *containerOutput) writeCRILine(timestamp string, chunk []byte, partial bool) {
+	tag := " F "
+	if partial {
+		tag = " P "
+	}
+	c.buf = append(c.buf, timestamp...)
+	c.buf = append(c.buf, ' ')
+	c.buf = append(c.buf, c.stream...)
+	c.buf = append(c.buf, tag...)
+	c.buf = append(c.buf, chunk...)
+	c.buf = append(c.buf, '\n')
+}
+
+// writeDockerRecord writes a record of the json-file logging driver, whose log ends with a newline unless it is
+// partial.
+func (c *containerOutput) writeDockerRecord(timestamp string, chunk []byte, partial bool) {
+	log := string(chunk)
+	if !partial {
+		log += "\n"
+	}
+	encoded, _ := json.Marshal(log)
+	c.buf = append(c.buf, `{"log":`...)
+	c.buf = append(c.buf, encoded...)
+	c.buf = append(c.buf, `,"stream":"`...)
+	c.buf = append(c.buf, c.stream...)
+	c.buf = append(c.buf, `","time":"`...)
+	c.buf = append(c.buf, timestamp...)
+	c.buf = append(c.buf, "\"}\n"...)
+}
+
+// containerLogPath returns the path to the log file of the given container in the directory layout of the
+// encoding, filling in the defaults of the container of the LogTask of the given name.
+func containerLogPath(name, encoding string, spec *model.ContainerSpec) string {
+	namespace, pod, container := spec.Namespace, spec.Pod, spec.Name
+	if len(namespace) == 0 {
+		namespace = defaultContainerNamespace
+	}
+	if len(pod) == 0 {
+		pod = name
+	}
+	if len(container) == 0 {
+		container = defaultContainerName
+	}
+	if encoding == model.EncodingDockerJSON {
+		id := spec.ID
+		if len(id) == 0 {
+			sum := sha256.Sum256([]byte(namespace + "/" + pod + "/" + container))
+			id = hex.EncodeToString(sum[:])
+		}
+		return filepath.Join(spec.LogRoot, id, id+"-json.log")
+	}
+	uid := spec.PodUID
+	if len(uid) == 0 {
+		sum := sha256.Sum256([]byte(namespace + "/" + pod))
+		uid = fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	}
+	return filepath.Join(
+		spec.LogRoot, namespace+"_"+pod+"_"+uid, container, strconv.Itoa(spec.RestartCount)+".log",
+	)
+}
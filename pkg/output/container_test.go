@@ -0,0 +1,98 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// bufferCloser is a bytes.Buffer that can be used as an output.
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (bufferCloser) Close() error {
+	return nil
+}
+
+func TestContainerOutput_CRI(t *testing.T) {
+	buf := new(bufferCloser)
+	out := newContainerOutput(buf, model.EncodingCRI, &model.ContainerSpec{Stream: model.ContainerStreamStderr})
+	at := time.Date(2019, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	long := strings.Repeat("a", model.ContainerMaxLineSize) + strings.Repeat("b", model.ContainerMaxLineSize) + "c"
+	if n, err := out.WriteTimed([]byte("short\n"), at, ""); err != nil || n != len("short\n") {
+		t.Fatalf("unexpected result: %d, %v", n, err)
+	}
+	if _, err := out.WriteTimed([]byte(long+"\n"), at, ""); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := out.WriteTimed([]byte("multi\n\tline\n"), at, ""); err != nil {
+		t.Fatal(err.Error())
+	}
+	prefix := "2019-01-02T03:04:05.123456789Z stderr "
+	expected := prefix + "F short\n" +
+		prefix + "P " + long[:model.ContainerMaxLineSize] + "\n" +
+		prefix + "P " + long[model.ContainerMaxLineSize:2*model.ContainerMaxLineSize] + "\n" +
+		prefix + "F c\n" +
+		prefix + "F multi\n" +
+		prefix + "F \tline\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected lines: want %.200q; got %.200q", expected, buf.String())
+	}
+}
+
+func TestContainerOutput_DockerJSON(t *testing.T) {
+	buf := new(bufferCloser)
+	out := newContainerOutput(buf, model.EncodingDockerJSON, nil)
+	at := time.Date(2019, 1, 2, 3, 4, 5, 120000000, time.UTC)
+	long := strings.Repeat("x", model.ContainerMaxLineSize) + `"<quoted>"`
+	if _, err := out.WriteTimed([]byte(long+"\n"), at, ""); err != nil {
+		t.Fatal(err.Error())
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{long[:model.ContainerMaxLineSize], `"<quoted>"` + "\n"}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected number of records: want %d; got %d", len(expected), len(lines))
+	}
+	for i := range lines {
+		var record struct {
+			Log    string `json:"log"`
+			Stream string `json:"stream"`
+			Time   string `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("invalid record %q: %s", lines[i], err.Error())
+		}
+		if record.Log != expected[i] || record.Stream != "stdout" || record.Time != "2019-01-02T03:04:05.12Z" {
+			t.Fatalf("unexpected record %d: %+v", i, record)
+		}
+	}
+}
+
+func TestContainerLogPath(t *testing.T) {
+	spec := &model.ContainerSpec{
+		LogRoot:      "/var/log/pods",
+		Namespace:    "kube-system",
+		PodUID:       "0123",
+		Name:         "app",
+		RestartCount: 2,
+	}
+	expected := filepath.Join("/var/log/pods", "kube-system_test_0123", "app", "2.log")
+	if path := containerLogPath("test", model.EncodingCRI, spec); path != expected {
+		t.Fatalf("unexpected CRI path: want %s; got %s", expected, path)
+	}
+	spec = &model.ContainerSpec{LogRoot: "/var/lib/docker/containers"}
+	path := containerLogPath("test", model.EncodingDockerJSON, spec)
+	id := filepath.Base(filepath.Dir(path))
+	if len(id) != 64 || path != filepath.Join(spec.LogRoot, id, id+"-json.log") {
+		t.Fatalf("unexpected Docker path: %s", path)
+	}
+	if path != containerLogPath("test", model.EncodingDockerJSON, spec) {
+		t.Fatal("container ID is not stable")
+	}
+}
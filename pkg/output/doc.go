@@ -0,0 +1,2 @@
+// Package output implements the destinations to which LogTap writes the log messages.
+package output
@@ -0,0 +1,177 @@
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// fileOutput appends the log messages to a file and rotates it if a RotationSpec is given.
+type fileOutput struct {
+	path     string
+	rotation *model.RotationSpec
+	maxAge   time.Duration
+	recorder Recorder
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newFileOutput(path string, rotation *model.RotationSpec, recorder Recorder) (*fileOutput, error) {
+	ret := &fileOutput{
+		path:     path,
+		rotation: rotation,
+		recorder: recorder,
+	}
+	if rotation != nil {
+		ret.maxAge = time.Duration(float64(time.Second) * rotation.MaxAge)
+	}
+	if err := ret.open(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (f *fileOutput) Write(p []byte) (int, error) {
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync syncs the log file to the disk.
+func (f *fileOutput) Sync() error {
+	return f.file.Sync()
+}
+
+func (f *fileOutput) Close() error {
+	return f.file.Close()
+}
+
+func (f *fileOutput) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s", err.Error())
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %s", err.Error())
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *fileOutput) shouldRotate(writeSize int) bool {
+	if f.rotation == nil || f.size == 0 {
+		return false
+	}
+	if f.rotation.MaxSize > 0 && f.size+int64(writeSize) > f.rotation.MaxSize {
+		return true
+	}
+	return f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge
+}
+
+// rotate moves the current content of the log file to the first backup, shifting the existing backups and
+// removing the ones beyond MaxBackups, then continues writing to an empty log file.
+func (f *fileOutput) rotate() error {
+	for i := f.rotation.MaxBackups; i > 1; i-- {
+		if err := os.Rename(f.backupPath(i-1), f.backupPath(i)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to shift log file backup: %s", err.Error())
+		}
+	}
+	backup := f.rawBackupPath(1)
+	switch f.rotation.Strategy {
+	case model.RotationStrategyCopyTruncate:
+		if err := copyFile(f.path, backup); err != nil {
+			return fmt.Errorf("failed to copy log file: %s", err.Error())
+		}
+		if err := f.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate log file: %s", err.Error())
+		}
+		f.size = 0
+		f.openedAt = time.Now()
+	default:
+		if err := f.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %s", err.Error())
+		}
+		if err := os.Rename(f.path, backup); err != nil {
+			return fmt.Errorf("failed to rename log file: %s", err.Error())
+		}
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.rotation.Compress {
+		if err := compressFile(backup, f.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to compress log file backup: %s", err.Error())
+		}
+	}
+	f.recorder.RecordRotation()
+	return nil
+}
+
+// backupPath returns the path of the i-th backup as it is kept, i.e. compressed if Compress is true.
+func (f *fileOutput) backupPath(i int) string {
+	if f.rotation.Compress {
+		return f.rawBackupPath(i) + ".gz"
+	}
+	return f.rawBackupPath(i)
+}
+
+func (f *fileOutput) rawBackupPath(i int) string {
+	return fmt.Sprintf("%s.%d", f.path, i)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// compressFile writes the gzip-compressed content of src to dst and removes src.
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(out)
+	if _, err = io.Copy(writer, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
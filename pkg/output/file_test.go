@@ -0,0 +1,160 @@
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+type countingRecorder struct {
+	mutex      sync.Mutex
+	rotations  int
+	reconnects int
+	codes      []int
+	drops      int
+	fileWrites map[string]int
+	removals   []string
+	buffered   int
+	flushes    int
+	syncs      int
+}
+
+func (r *countingRecorder) RecordRotation() {
+	r.rotations++
+}
+
+func (r *countingRecorder) RecordReconnect() {
+	r.reconnects++
+}
+
+func (r *countingRecorder) RecordRequest(code int, _ time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.codes = append(r.codes, code)
+}
+
+func (r *countingRecorder) RecordDrop(count int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.drops += count
+}
+
+func (r *countingRecorder) RecordFileWrite(path string, _ int) {
+	if r.fileWrites == nil {
+		r.fileWrites = make(map[string]int)
+	}
+	r.fileWrites[path]++
+}
+
+func (r *countingRecorder) RecordFileRemoval(path string) {
+	r.removals = append(r.removals, path)
+}
+
+func (r *countingRecorder) RecordBuffer(size int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.buffered += size
+}
+
+func (r *countingRecorder) RecordFlush(size int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.buffered -= size
+	r.flushes++
+}
+
+func (r *countingRecorder) RecordSync() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.syncs++
+}
+
+func TestFileOutput_Rotate(t *testing.T) {
+	const (
+		line    = "0123456789\n"
+		repeats = 10
+	)
+	testCases := []struct {
+		name     string
+		strategy string
+		compress bool
+	}{
+		{"Rename", model.RotationStrategyRename, false},
+		{"CopyTruncate", model.RotationStrategyCopyTruncate, false},
+		{"RenameCompress", model.RotationStrategyRename, true},
+		{"CopyTruncateCompress", model.RotationStrategyCopyTruncate, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logtap")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "test.log")
+			recorder := new(countingRecorder)
+			out, err := newFileOutput(path, &model.RotationSpec{
+				MaxSize:    int64(2 * len(line)),
+				MaxBackups: 3,
+				Strategy:   tc.strategy,
+				Compress:   tc.compress,
+			}, recorder)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			for i := 0; i < repeats; i++ {
+				if _, err = out.Write([]byte(line)); err != nil {
+					t.Fatalf("unexpected failure at message %d: %s", i, err.Error())
+				}
+			}
+			if err = out.Close(); err != nil {
+				t.Fatal(err.Error())
+			}
+			if want := repeats/2 - 1; recorder.rotations != want {
+				t.Fatalf("unexpected rotation count: want %d; got %d", want, recorder.rotations)
+			}
+			checkContent(t, path, false, line+line)
+			for i := 1; i <= 3; i++ {
+				backup := fmt.Sprintf("%s.%d", path, i)
+				if tc.compress {
+					backup += ".gz"
+				}
+				checkContent(t, backup, tc.compress, line+line)
+			}
+			if _, err = os.Stat(fmt.Sprintf("%s.4", path)); !os.IsNotExist(err) {
+				t.Fatalf("unexpected backup beyond maxBackups: %v", err)
+			}
+		})
+	}
+}
+
+func checkContent(t *testing.T, path string, compressed bool, want string) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer file.Close()
+	var data []byte
+	if compressed {
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		data, err = ioutil.ReadAll(reader)
+	} else {
+		data, err = ioutil.ReadAll(file)
+	}
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(data) != want {
+		t.Fatalf(`unexpected content of %s: want "%s"; got "%s"`, path, want, string(data))
+	}
+}
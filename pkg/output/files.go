@@ -0,0 +1,134 @@
+package output
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// multiFileOutput spreads the log messages over many files, each of which is a fileOutput, and replaces the
+// oldest files with new ones at a regular interval. The files are kept in slots so that a new file takes over the
+// weight of the file it replaces.
+type multiFileOutput struct {
+	pattern       string
+	spec          *model.FilesSpec
+	rotation      *model.RotationSpec
+	recorder      Recorder
+	slots         []*fileOutput
+	cumulative    []float64
+	next          int
+	nextIndex     int
+	oldest        int
+	churnCount    int
+	churnInterval time.Duration
+	churnedAt     time.Time
+}
+
+func newMultiFileOutput(
+	pattern string, spec *model.FilesSpec, rotation *model.RotationSpec, recorder Recorder,
+) (*multiFileOutput, error) {
+	ret := &multiFileOutput{
+		pattern:       pattern,
+		spec:          spec,
+		rotation:      rotation,
+		recorder:      recorder,
+		slots:         make([]*fileOutput, spec.Count),
+		churnCount:    spec.ChurnCount,
+		churnInterval: time.Duration(float64(time.Second) * spec.ChurnInterval),
+		churnedAt:     time.Now(),
+	}
+	if ret.churnCount == 0 {
+		ret.churnCount = 1
+	}
+	if spec.Distribution == model.FileDistributionWeighted {
+		ret.cumulative = make([]float64, len(spec.Weights))
+		var total float64
+		for i, weight := range spec.Weights {
+			total += weight
+			ret.cumulative[i] = total
+		}
+	}
+	for i := range ret.slots {
+		if err := ret.openSlot(i); err != nil {
+			ret.Close()
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Write replaces the oldest files if it is time to, and then writes p to the file picked by the distribution.
+func (m *multiFileOutput) Write(p []byte) (int, error) {
+	if m.churnInterval > 0 && time.Since(m.churnedAt) >= m.churnInterval {
+		m.churnedAt = time.Now()
+		for i := 0; i < m.churnCount; i++ {
+			if err := m.replace(m.oldest); err != nil {
+				return 0, err
+			}
+			m.oldest = (m.oldest + 1) % len(m.slots)
+		}
+	}
+	file := m.slots[m.pick()]
+	n, err := file.Write(p)
+	if err == nil {
+		m.recorder.RecordFileWrite(file.path, n)
+	}
+	return n, err
+}
+
+// Close closes all the files; none of them is removed.
+func (m *multiFileOutput) Close() error {
+	var ret error
+	for _, file := range m.slots {
+		if file == nil {
+			continue
+		}
+		if err := file.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// pick returns the slot of the file to which the next log message is written.
+func (m *multiFileOutput) pick() int {
+	switch m.spec.Distribution {
+	case model.FileDistributionWeighted:
+		target := rand.Float64() * m.cumulative[len(m.cumulative)-1]
+		return sort.Search(len(m.cumulative)-1, func(i int) bool {
+			return m.cumulative[i] > target
+		})
+	case model.FileDistributionRandom:
+		return rand.Intn(len(m.slots))
+	default:
+		ret := m.next
+		m.next = (m.next + 1) % len(m.slots)
+		return ret
+	}
+}
+
+// openSlot opens the file with the next unused index in the given slot.
+func (m *multiFileOutput) openSlot(slot int) error {
+	file, err := newFileOutput(fmt.Sprintf(m.pattern, m.nextIndex), m.rotation, m.recorder)
+	if err != nil {
+		return err
+	}
+	m.nextIndex++
+	m.slots[slot] = file
+	return nil
+}
+
+// replace closes and removes the file in the given slot, and opens a new one in its place.
+func (m *multiFileOutput) replace(slot int) error {
+	file := m.slots[slot]
+	file.Close()
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove log file: %s", err.Error())
+	}
+	m.recorder.RecordFileRemoval(file.path)
+	return m.openSlot(slot)
+}
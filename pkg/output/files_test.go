@@ -0,0 +1,107 @@
+package output
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestMultiFileOutput_Distribution(t *testing.T) {
+	const writes = 300
+	testCases := []struct {
+		name     string
+		spec     model.FilesSpec
+		expected []int
+	}{
+		{
+			name:     "RoundRobin",
+			spec:     model.FilesSpec{Count: 3, Distribution: model.FileDistributionRoundRobin},
+			expected: []int{100, 100, 100},
+		},
+		{
+			name: "Weighted",
+			spec: model.FilesSpec{
+				Count:        3,
+				Distribution: model.FileDistributionWeighted,
+				Weights:      []float64{0, 1, 0},
+			},
+			expected: []int{0, writes, 0},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logtap")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			defer os.RemoveAll(dir)
+			recorder := new(countingRecorder)
+			out, err := newMultiFileOutput(filepath.Join(dir, "app-%d.log"), &tc.spec, nil, recorder)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			for i := 0; i < writes; i++ {
+				if _, err = out.Write([]byte("line\n")); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+			out.Close()
+			counts := make([]int, tc.spec.Count)
+			for i := range counts {
+				path := filepath.Join(dir, fmt.Sprintf("app-%d.log", i))
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				counts[i] = strings.Count(string(data), "\n")
+				if counts[i] != recorder.fileWrites[path] {
+					t.Fatalf("unexpected recorded writes to %s: want %d; got %d",
+						path, counts[i], recorder.fileWrites[path])
+				}
+			}
+			if !reflect.DeepEqual(counts, tc.expected) {
+				t.Fatalf("unexpected distribution: want %v; got %v", tc.expected, counts)
+			}
+		})
+	}
+}
+
+func TestMultiFileOutput_Churn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtap")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	recorder := new(countingRecorder)
+	out, err := newMultiFileOutput(filepath.Join(dir, "app-%02d.log"), &model.FilesSpec{
+		Count:         3,
+		Distribution:  model.FileDistributionRandom,
+		ChurnInterval: 0.05,
+		ChurnCount:    2,
+	}, nil, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer out.Close()
+	out.Write([]byte("before\n"))
+	time.Sleep(60 * time.Millisecond)
+	out.Write([]byte("after\n"))
+	removed := []string{filepath.Join(dir, "app-00.log"), filepath.Join(dir, "app-01.log")}
+	if !reflect.DeepEqual(recorder.removals, removed) {
+		t.Fatalf("unexpected removals: want %v; got %v", removed, recorder.removals)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	expected := []string{
+		filepath.Join(dir, "app-02.log"), filepath.Join(dir, "app-03.log"), filepath.Join(dir, "app-04.log"),
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("unexpected files: want %v; got %v", expected, matches)
+	}
+}
@@ -0,0 +1,176 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"math/rand"
+	"strings"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultForwardTag           = "logtap." + model.ForwardTagNamePlaceholder
+	defaultForwardAckTimeout    = 5 * time.Second
+	defaultForwardBatchSize     = 1000
+	defaultForwardBatchInterval = time.Second
+)
+
+// forwardOutput sends the log messages written to it as events of the Fluentd Forward protocol, either one by
+// one in the Message mode or in batches in the Forward and PackedForward modes; the events are dropped while the
+// connection is down, including before the server first accepts it.
+type forwardOutput struct {
+	spec       *model.ForwardSpec
+	tag        string
+	conn       *reconnectingConn
+	recorder   Recorder
+	ackTimeout time.Duration
+	batcher    *batcher
+	message    msgpackEncoder
+	entry      msgpackEncoder
+	entries    msgpackEncoder
+	chunk      [16]byte
+}
+
+func newForwardOutput(name string, spec *model.ForwardSpec, recorder Recorder) (*forwardOutput, error) {
+	tag := spec.Tag
+	if len(tag) == 0 {
+		tag = defaultForwardTag
+	}
+	ret := &forwardOutput{
+		spec:       spec,
+		tag:        strings.Replace(tag, model.ForwardTagNamePlaceholder, name, -1),
+		conn:       newReconnectingConn("tcp", spec.Address, 0, 0, recorder),
+		recorder:   recorder,
+		ackTimeout: time.Duration(float64(time.Second) * spec.AckTimeout),
+	}
+	if ret.ackTimeout == 0 {
+		ret.ackTimeout = defaultForwardAckTimeout
+	}
+	if spec.Mode != model.ForwardModeMessage {
+		batchSize := spec.BatchSize
+		interval := time.Duration(float64(time.Second) * spec.BatchInterval)
+		if batchSize == 0 {
+			batchSize = defaultForwardBatchSize
+		}
+		if interval == 0 {
+			interval = defaultForwardBatchInterval
+		}
+		ret.batcher = newBatcher(batchSize, 0, interval, ret.sendBatch)
+	}
+	return ret, nil
+}
+
+// Write sends p as an event created at the current time.
+func (f *forwardOutput) Write(p []byte) (int, error) {
+	return f.WriteTimed(p, time.Now(), "")
+}
+
+// WriteTimed sends p, without the trailing newline, as a single event created at the given time in the Message
+// mode, or adds it to the pending batch as an encoded entry otherwise. It returns the size of p rather than that
+// of the event. The level is ignored.
+func (f *forwardOutput) WriteTimed(p []byte, t time.Time, _ string) (int, error) {
+	line := bytes.TrimSuffix(p, []byte("\n"))
+	if f.batcher != nil {
+		e := &f.entry
+		e.reset()
+		e.writeArrayHeader(2)
+		f.writeEntry(e, t, line)
+		f.batcher.addItem(e.buf)
+		return len(p), nil
+	}
+	e := &f.message
+	e.reset()
+	e.writeArrayHeader(f.arraySize(3))
+	e.writeString(f.tag)
+	f.writeEntry(e, t, line)
+	if !f.send(1) {
+		return len(p), ErrDropped
+	}
+	return len(p), nil
+}
+
+// Close sends the pending batch, if there is one.
+func (f *forwardOutput) Close() error {
+	if f.batcher != nil {
+		f.batcher.close()
+	}
+	return f.conn.close()
+}
+
+// sendBatch sends the given encoded entries in a single message.
+func (f *forwardOutput) sendBatch(entries [][]byte) {
+	e := &f.message
+	e.reset()
+	e.writeArrayHeader(f.arraySize(2))
+	e.writeString(f.tag)
+	if f.spec.Mode == model.ForwardModePackedForward {
+		f.entries.reset()
+		for _, entry := range entries {
+			f.entries.buf = append(f.entries.buf, entry...)
+		}
+		e.writeBinary(f.entries.buf)
+	} else {
+		e.writeArrayHeader(len(entries))
+		for _, entry := range entries {
+			e.buf = append(e.buf, entry...)
+		}
+	}
+	if !f.send(len(entries)) {
+		f.recorder.RecordDrop(len(entries))
+	}
+}
+
+// arraySize returns the size of the outermost array of a message that has n elements before the option.
+func (f *forwardOutput) arraySize(n int) int {
+	if f.spec.RequireAck {
+		return n + 1
+	}
+	return n
+}
+
+// writeEntry writes the time and the record of an event.
+func (f *forwardOutput) writeEntry(e *msgpackEncoder, t time.Time, line []byte) {
+	e.writeEventTime(t)
+	e.writeMapHeader(1)
+	e.writeString(model.ForwardRecordKey)
+	e.writeStringBytes(line)
+}
+
+// send appends the option to the encoded message if needed, sends the message and waits for the acknowledgement
+// if needed. It reports whether the message has been sent.
+func (f *forwardOutput) send(size int) bool {
+	var chunk string
+	if f.spec.RequireAck {
+		rand.Read(f.chunk[:])
+		chunk = base64.StdEncoding.EncodeToString(f.chunk[:])
+		f.message.writeMapHeader(2)
+		f.message.writeString("chunk")
+		f.message.writeString(chunk)
+		f.message.writeString("size")
+		f.message.writeUint(uint64(size))
+	}
+	if f.conn.write(f.message.buf) != nil {
+		return false
+	}
+	if f.spec.RequireAck && !f.readAck(chunk) {
+		// the connection might be left with a late acknowledgement in it
+		f.conn.close()
+		return false
+	}
+	return true
+}
+
+// readAck reads a response from the server and reports whether it acknowledges the given chunk.
+func (f *forwardOutput) readAck(chunk string) bool {
+	conn := f.conn.conn
+	conn.SetReadDeadline(time.Now().Add(f.ackTimeout))
+	response, err := decodeMsgpack(bufio.NewReader(conn))
+	if err != nil {
+		return false
+	}
+	fields, ok := response.(map[string]interface{})
+	return ok && fields["ack"] == chunk
+}
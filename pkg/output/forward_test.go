@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// forwardEvent is an event decoded from a message of the Fluentd Forward protocol.
+type forwardEvent struct {
+	tag  string
+	time time.Time
+	log  string
+}
+
+// decodeForwardMessage decodes the events of a message of any mode, along with its option.
+func decodeForwardMessage(t *testing.T, message interface{}) ([]forwardEvent, map[string]interface{}) {
+	array, ok := message.([]interface{})
+	if !ok || len(array) < 2 {
+		t.Fatalf("unexpected message: %v", message)
+	}
+	tag, ok := array[0].(string)
+	if !ok {
+		t.Fatalf("unexpected tag: %v", array[0])
+	}
+	var entries []interface{}
+	optionIndex := 2
+	switch second := array[1].(type) {
+	case msgpackEventTime:
+		if len(array) < 3 {
+			t.Fatalf("unexpected message: %v", message)
+		}
+		entries = []interface{}{[]interface{}{second, array[2]}}
+		optionIndex = 3
+	case []interface{}:
+		entries = second
+	case []byte:
+		reader := bufio.NewReader(bytes.NewReader(second))
+		for {
+			entry, err := decodeMsgpack(reader)
+			if err != nil {
+				break
+			}
+			entries = append(entries, entry)
+		}
+	default:
+		t.Fatalf("unexpected message: %v", message)
+	}
+	var options map[string]interface{}
+	if len(array) > optionIndex {
+		options, _ = array[optionIndex].(map[string]interface{})
+	}
+	var events []forwardEvent
+	for _, entry := range entries {
+		pair, ok := entry.([]interface{})
+		if !ok || len(pair) != 2 {
+			t.Fatalf("unexpected entry: %v", entry)
+		}
+		eventTime, ok := pair[0].(msgpackEventTime)
+		if !ok {
+			t.Fatalf("unexpected time: %v", pair[0])
+		}
+		record, ok := pair[1].(map[string]interface{})
+		if !ok {
+			t.Fatalf("unexpected record: %v", pair[1])
+		}
+		events = append(events, forwardEvent{
+			tag:  tag,
+			time: eventTime.Time,
+			log:  record[model.ForwardRecordKey].(string),
+		})
+	}
+	return events, options
+}
+
+func TestForwardOutput(t *testing.T) {
+	testCases := []struct {
+		mode       string
+		requireAck bool
+	}{
+		{model.ForwardModeMessage, false},
+		{model.ForwardModeMessage, true},
+		{model.ForwardModeForward, false},
+		{model.ForwardModePackedForward, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			defer listener.Close()
+			received := make(chan []forwardEvent, 2)
+			go func() {
+				defer close(received)
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					message, err := decodeMsgpack(reader)
+					if err != nil {
+						return
+					}
+					events, options := decodeForwardMessage(t, message)
+					if chunk, ok := options["chunk"]; ok {
+						ack := &msgpackEncoder{}
+						ack.writeMapHeader(1)
+						ack.writeString("ack")
+						ack.writeString(chunk.(string))
+						conn.Write(ack.buf)
+					} else if tc.requireAck {
+						t.Errorf("chunk option missing from %v", message)
+					}
+					received <- events
+				}
+			}()
+			out, err := newForwardOutput("test", &model.ForwardSpec{
+				Address:    listener.Addr().String(),
+				Mode:       tc.mode,
+				RequireAck: tc.requireAck,
+			}, nopRecorder{})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			start := time.Unix(1500000000, 123)
+			for i, line := range []string{"first\n", "second\n"} {
+				if _, err = out.WriteTimed([]byte(line), start.Add(time.Duration(i)*time.Second), ""); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+			go out.Close()
+			var events []forwardEvent
+			timeout := time.After(5 * time.Second)
+			for len(events) < 2 {
+				select {
+				case batch, ok := <-received:
+					if !ok {
+						t.Fatalf("connection closed after %d events", len(events))
+					}
+					events = append(events, batch...)
+				case <-timeout:
+					t.Fatal("timed out waiting for events")
+				}
+			}
+			expected := []forwardEvent{
+				{"logtap.test", start, "first"},
+				{"logtap.test", start.Add(time.Second), "second"},
+			}
+			if !reflect.DeepEqual(events, expected) {
+				t.Fatalf("unexpected events: want %v; got %v", expected, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/httputil"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultHTTPBatchSize     = 1000
+	defaultHTTPBatchBytes    = 1 << 20
+	defaultHTTPBatchInterval = time.Second
+	defaultHTTPTimeout       = 10 * time.Second
+
+	// minHTTPRetryBackoff and maxHTTPRetryBackoff bound the delay before retrying a failed request; the delay
+	// doubles after every attempt unless the response tells otherwise with a Retry-After header field.
+	minHTTPRetryBackoff = 200 * time.Millisecond
+	maxHTTPRetryBackoff = 10 * time.Second
+
+	ndjsonContentType = "application/x-ndjson"
+	jsonContentType   = "application/json"
+)
+
+// httpOutput groups the log messages written to it into batches and sends every batch in a HTTP request.
+type httpOutput struct {
+	spec     *model.HTTPSpec
+	method   string
+	headers  []httputil.HeaderField
+	template *template.Template
+	client   *http.Client
+	recorder Recorder
+	batcher  *batcher
+	body     bytes.Buffer
+	zipper   *gzip.Writer
+}
+
+func newHTTPOutput(spec *model.HTTPSpec, recorder Recorder) (*httpOutput, error) {
+	ret := &httpOutput{
+		spec:     spec,
+		method:   spec.Method,
+		client:   &http.Client{Timeout: defaultHTTPTimeout},
+		recorder: recorder,
+	}
+	if len(ret.method) == 0 {
+		ret.method = http.MethodPost
+	}
+	contentType := ndjsonContentType
+	if spec.Format == model.HTTPFormatTemplate {
+		var err error
+		if ret.template, err = model.ParseHTTPBodyTemplate(spec.BodyTemplate); err != nil {
+			return nil, fmt.Errorf("failed to parse body template: %s", err.Error())
+		}
+		contentType = jsonContentType
+	}
+	ret.headers = append(ret.headers, httputil.NewHeaderField("Content-Type", contentType))
+	if spec.Compress {
+		ret.headers = append(ret.headers, httputil.NewHeaderField("Content-Encoding", "gzip"))
+		ret.zipper = gzip.NewWriter(ioutil.Discard)
+	}
+	for key, value := range spec.Headers {
+		ret.headers = append(ret.headers, httputil.NewHeaderField(key, value))
+	}
+	if spec.Timeout > 0 {
+		ret.client.Timeout = time.Duration(float64(time.Second) * spec.Timeout)
+	}
+	batchSize, batchBytes := spec.BatchSize, spec.BatchBytes
+	interval := time.Duration(float64(time.Second) * spec.BatchInterval)
+	if batchSize == 0 {
+		batchSize = defaultHTTPBatchSize
+	}
+	if batchBytes == 0 {
+		batchBytes = defaultHTTPBatchBytes
+	}
+	if interval == 0 {
+		interval = defaultHTTPBatchInterval
+	}
+	ret.batcher = newBatcher(batchSize, batchBytes, interval, ret.sendBatch)
+	return ret, nil
+}
+
+// Write adds p to the pending batch, sending the batch if it is full. It never fails; the log messages of a batch
+// that cannot be sent are recorded as dropped.
+func (h *httpOutput) Write(p []byte) (int, error) {
+	h.batcher.add(p)
+	return len(p), nil
+}
+
+// Close sends the pending batch.
+func (h *httpOutput) Close() error {
+	h.batcher.close()
+	return nil
+}
+
+func (h *httpOutput) sendBatch(lines [][]byte) {
+	if err := h.encode(lines); err != nil || !h.send() {
+		h.recorder.RecordDrop(len(lines))
+	}
+}
+
+// encode writes the body of the request for the given batch to the body buffer.
+func (h *httpOutput) encode(lines [][]byte) error {
+	h.body.Reset()
+	var w io.Writer = &h.body
+	if h.zipper != nil {
+		h.zipper.Reset(&h.body)
+		w = h.zipper
+	}
+	if h.template != nil {
+		batch := &model.HTTPBatch{
+			Lines:     make([]string, len(lines)),
+			Timestamp: time.Now(),
+		}
+		for i := range lines {
+			batch.Lines[i] = string(lines[i])
+		}
+		if err := h.template.Execute(w, batch); err != nil {
+			return err
+		}
+	} else {
+		for _, line := range lines {
+			w.Write(line)
+			w.Write([]byte("\n"))
+		}
+	}
+	if h.zipper != nil {
+		return h.zipper.Close()
+	}
+	return nil
+}
+
+// send sends the body buffer, retrying the requests that fail with a 429 or 5xx response or with no response at
+// all, and reports whether the batch has been accepted.
+func (h *httpOutput) send() bool {
+	return retryRequest(h.do, h.spec.MaxRetries, h.batcher.stopping())
+}
+
+// do makes a single request and reports whether it has succeeded and, if not, whether it should be retried and
+// the delay asked for by the server, if any.
+func (h *httpOutput) do() (ok, retry bool, retryAfter time.Duration) {
+	req, err := http.NewRequest(h.method, h.spec.URL, bytes.NewReader(h.body.Bytes()))
+	if err != nil {
+		return false, false, 0
+	}
+	for _, header := range h.headers {
+		req.Header.Set(header.Key(), header.Value())
+	}
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.recorder.RecordRequest(0, time.Since(start))
+		return false, true, 0
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	h.recorder.RecordRequest(resp.StatusCode, time.Since(start))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return true, false, 0
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return false, true, parseRetryAfter(resp.Header)
+	default:
+		return false, false, 0
+	}
+}
+
+// retryRequest calls do until it succeeds, it fails in a way that should not be retried, or it has been retried
+// maxRetries times, and reports whether it has eventually succeeded. The delay between the attempts doubles every
+// time unless do returns one asked for by the server. Failed requests are no longer retried once stopping is
+// closed.
+func retryRequest(do func() (ok, retry bool, retryAfter time.Duration), maxRetries int, stopping <-chan struct{}) bool {
+	backoff := minHTTPRetryBackoff
+	for attempt := 0; ; attempt++ {
+		ok, retry, retryAfter := do()
+		if ok || !retry || attempt >= maxRetries {
+			return ok
+		}
+		if retryAfter <= 0 {
+			retryAfter = backoff
+			if backoff *= 2; backoff > maxHTTPRetryBackoff {
+				backoff = maxHTTPRetryBackoff
+			}
+		} else if retryAfter > maxHTTPRetryBackoff {
+			retryAfter = maxHTTPRetryBackoff
+		}
+		select {
+		case <-stopping:
+			return false
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// parseRetryAfter returns the delay given in seconds by the Retry-After field of a response, or 0 if there is
+// none.
+func parseRetryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// httpSink is a HTTP server that records the bodies of the requests and responds with the given status codes in
+// turn, and with 200 afterwards.
+type httpSink struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	codes  []int
+	bodies []string
+	header http.Header
+}
+
+func newHTTPSink(codes ...int) *httpSink {
+	ret := &httpSink{codes: codes}
+	ret.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			var err error
+			if body, err = gzip.NewReader(r.Body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		data, _ := ioutil.ReadAll(body)
+		ret.mutex.Lock()
+		defer ret.mutex.Unlock()
+		ret.header = r.Header
+		code := http.StatusOK
+		if len(ret.codes) > 0 {
+			code, ret.codes = ret.codes[0], ret.codes[1:]
+		}
+		if code == http.StatusOK {
+			ret.bodies = append(ret.bodies, string(data))
+		}
+		w.WriteHeader(code)
+	}))
+	return ret
+}
+
+func (s *httpSink) getBodies() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestHTTPOutput_Batch(t *testing.T) {
+	sink := newHTTPSink()
+	defer sink.Close()
+	out, err := newHTTPOutput(&model.HTTPSpec{
+		URL:           sink.URL,
+		Format:        model.HTTPFormatNDJSON,
+		BatchSize:     3,
+		BatchInterval: 60,
+	}, nopRecorder{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := 0; i < 7; i++ {
+		if _, err = out.Write([]byte(fmt.Sprintf("%d\n", i))); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if bodies := sink.getBodies(); len(bodies) != 2 {
+		t.Fatalf("unexpected number of requests before close: want 2; got %d", len(bodies))
+	}
+	out.Close()
+	expected := []string{"0\n1\n2\n", "3\n4\n5\n", "6\n"}
+	if bodies := sink.getBodies(); !reflect.DeepEqual(bodies, expected) {
+		t.Fatalf("unexpected bodies: want %q; got %q", expected, bodies)
+	}
+	if contentType := sink.header.Get("Content-Type"); contentType != ndjsonContentType {
+		t.Fatalf("unexpected content type: %s", contentType)
+	}
+}
+
+func TestHTTPOutput_TemplateRetry(t *testing.T) {
+	sink := newHTTPSink(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer sink.Close()
+	recorder := new(countingRecorder)
+	out, err := newHTTPOutput(&model.HTTPSpec{
+		URL:           sink.URL,
+		Headers:       map[string]string{"Authorization": "Splunk token"},
+		Format:        model.HTTPFormatTemplate,
+		BodyTemplate:  `{{range .Lines}}{"event":{{json .}}}{{end}}`,
+		Compress:      true,
+		BatchInterval: 0.01,
+		MaxRetries:    2,
+	}, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	out.Write([]byte("a \"quoted\" line\n"))
+	out.Write([]byte("another line\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.getBodies()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the batch")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	out.Close()
+	expected := []string{`{"event":"a \"quoted\" line"}{"event":"another line"}`}
+	if bodies := sink.getBodies(); !reflect.DeepEqual(bodies, expected) {
+		t.Fatalf("unexpected bodies: want %q; got %q", expected, bodies)
+	}
+	if auth := sink.header.Get("Authorization"); auth != "Splunk token" {
+		t.Fatalf("unexpected Authorization header: %s", auth)
+	}
+	codes := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	if !reflect.DeepEqual(recorder.codes, codes) || recorder.drops != 0 {
+		t.Fatalf("unexpected recorded requests: %v, %d dropped", recorder.codes, recorder.drops)
+	}
+}
+
+func TestHTTPOutput_Drop(t *testing.T) {
+	sink := newHTTPSink(http.StatusBadRequest)
+	defer sink.Close()
+	recorder := new(countingRecorder)
+	out, err := newHTTPOutput(&model.HTTPSpec{
+		URL:        sink.URL,
+		Format:     model.HTTPFormatNDJSON,
+		MaxRetries: 3,
+	}, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	out.Write([]byte("dropped\n"))
+	out.Close()
+	if !reflect.DeepEqual(recorder.codes, []int{http.StatusBadRequest}) || recorder.drops != 1 {
+		t.Fatalf("unexpected recorded requests: %v, %d dropped", recorder.codes, recorder.drops)
+	}
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// timedWriter is implemented by the outputs that take the time and the level of a log message along with it; the
+// level is empty if the log message has none.
+type timedWriter interface {
+	WriteTimed(p []byte, t time.Time, level string) (int, error)
+}
+
+// lockedOutput serializes the writes to an output shared by many goroutines.
+type lockedOutput struct {
+	out   io.WriteCloser
+	mutex sync.Mutex
+}
+
+// lockedTimedOutput is a lockedOutput that also serializes WriteTimed.
+type lockedTimedOutput struct {
+	lockedOutput
+}
+
+// NewLocked returns an output that can be written to by many goroutines at once; the writes to out are made one
+// at a time. The returned output implements WriteTimed if out does.
+func NewLocked(out io.WriteCloser) io.WriteCloser {
+	if _, ok := out.(timedWriter); ok {
+		return &lockedTimedOutput{lockedOutput{out: out}}
+	}
+	return &lockedOutput{out: out}
+}
+
+func (l *lockedOutput) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.out.Write(p)
+}
+
+func (l *lockedOutput) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.out.Close()
+}
+
+func (l *lockedTimedOutput) WriteTimed(p []byte, t time.Time, level string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.out.(timedWriter).WriteTimed(p, t, level)
+}
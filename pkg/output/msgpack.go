@@ -0,0 +1,238 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// msgpackEventTimeType is the MessagePack extension type of the EventTime of the Fluentd Forward protocol.
+const msgpackEventTimeType = 0
+
+// msgpackEncoder appends MessagePack values to a byte slice; it only covers the types used by the Fluentd Forward
+// protocol.
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) reset() {
+	e.buf = e.buf[:0]
+}
+
+func (e *msgpackEncoder) writeArrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xdc, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdd)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) writeMapHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xde, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdf)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	e.writeStringHeader(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// writeStringBytes writes b as a MessagePack string.
+func (e *msgpackEncoder) writeStringBytes(b []byte) {
+	e.writeStringHeader(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) writeStringHeader(n int) {
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) writeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.buf = append(e.buf, byte(v))
+	case v <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = appendUint32(e.buf, uint32(v))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = appendUint32(e.buf, uint32(v>>32))
+		e.buf = appendUint32(e.buf, uint32(v))
+	}
+}
+
+func (e *msgpackEncoder) writeBinary(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// writeEventTime writes t as an EventTime, a fixext 8 holding the seconds and the nanoseconds.
+func (e *msgpackEncoder) writeEventTime(t time.Time) {
+	e.buf = append(e.buf, 0xd7, msgpackEventTimeType)
+	e.buf = appendUint32(e.buf, uint32(t.Unix()))
+	e.buf = appendUint32(e.buf, uint32(t.Nanosecond()))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// msgpackEventTime is a decoded EventTime.
+type msgpackEventTime struct {
+	time.Time
+}
+
+// decodeMsgpack decodes a single MessagePack value. Maps are decoded into map[string]interface{}, strings into
+// string, binaries into []byte, integers into int64, EventTimes into msgpackEventTime, and arrays into
+// []interface{}; other types are not supported.
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return decodeMsgpackMap(r, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return decodeMsgpackArray(r, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		return decodeMsgpackString(r, int(b&0x1f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(r, 1<<(b-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, n)
+		_, err = io.ReadFull(r, data)
+		return data, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readMsgpackLength(r, 1<<(b-0xcc))
+		return int64(n), err
+	case 0xd7:
+		data := make([]byte, 9)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if data[0] != msgpackEventTimeType {
+			return nil, fmt.Errorf("unsupported MessagePack extension type %d", data[0])
+		}
+		sec, nsec := binary.BigEndian.Uint32(data[1:5]), binary.BigEndian.Uint32(data[5:9])
+		return msgpackEventTime{time.Unix(int64(sec), int64(nsec))}, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, 1<<(b-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, n)
+	}
+	return nil, fmt.Errorf("unsupported MessagePack type 0x%02x", b)
+}
+
+// readMsgpackLength reads a big-endian unsigned integer of the given size in bytes.
+func readMsgpackLength(r *bufio.Reader, size int) (int, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return int(n), nil
+}
+
+func decodeMsgpackString(r *bufio.Reader, n int) (string, error) {
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return string(data), err
+}
+
+func decodeMsgpackArray(r *bufio.Reader, n int) ([]interface{}, error) {
+	ret := make([]interface{}, n)
+	for i := range ret {
+		var err error
+		if ret[i], err = decodeMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func decodeMsgpackMap(r *bufio.Reader, n int) (map[string]interface{}, error) {
+	ret := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported MessagePack map key %v", key)
+		}
+		if ret[keyString], err = decodeMsgpack(r); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// networkOutput sends the log messages written to it to a TCP or UDP server with the given framing; the log
+// messages are dropped while the connection is down, including before the server first accepts it.
+type networkOutput struct {
+	framing string
+	conn    *reconnectingConn
+	buffer  bytes.Buffer
+}
+
+func newNetworkOutput(spec *model.NetworkSpec, recorder Recorder) (*networkOutput, error) {
+	var network string
+	switch spec.Protocol {
+	case model.NetworkProtocolTCP:
+		network = "tcp"
+	case model.NetworkProtocolUDP:
+		network = "udp"
+	default:
+		return nil, fmt.Errorf("unsupported network protocol: %s", spec.Protocol)
+	}
+	return &networkOutput{
+		framing: spec.Framing,
+		conn: newReconnectingConn(
+			network, spec.Address,
+			time.Duration(float64(time.Second)*spec.ConnectTimeout),
+			time.Duration(float64(time.Second)*spec.MaxReconnectBackoff),
+			recorder,
+		),
+	}, nil
+}
+
+// Write sends p as a single frame. It returns the size of p rather than that of the frame, even if p is dropped.
+func (n *networkOutput) Write(p []byte) (int, error) {
+	message := bytes.TrimSuffix(p, []byte("\n"))
+	n.buffer.Reset()
+	switch n.framing {
+	case model.NetworkFramingOctetCounting:
+		n.buffer.WriteString(strconv.Itoa(len(message)))
+		n.buffer.WriteByte(' ')
+		n.buffer.Write(message)
+	case model.NetworkFramingLengthPrefix:
+		var prefix [4]byte
+		binary.BigEndian.PutUint32(prefix[:], uint32(len(message)))
+		n.buffer.Write(prefix[:])
+		n.buffer.Write(message)
+	default:
+		n.buffer.Write(message)
+		n.buffer.WriteByte('\n')
+	}
+	return len(p), n.conn.write(n.buffer.Bytes())
+}
+
+func (n *networkOutput) Close() error {
+	return n.conn.close()
+}
@@ -0,0 +1,194 @@
+package output
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestNetworkOutput_Framing(t *testing.T) {
+	testCases := []struct {
+		framing  string
+		expected string
+	}{
+		{model.NetworkFramingNewline, "hello\nworld\n"},
+		{model.NetworkFramingOctetCounting, "5 hello5 world"},
+		{model.NetworkFramingLengthPrefix, "\x00\x00\x00\x05hello\x00\x00\x00\x05world"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.framing, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			defer listener.Close()
+			received := make(chan []byte, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				data, _ := ioutil.ReadAll(conn)
+				received <- data
+			}()
+			out, err := newNetworkOutput(&model.NetworkSpec{
+				Address:  listener.Addr().String(),
+				Protocol: model.NetworkProtocolTCP,
+				Framing:  tc.framing,
+			}, nopRecorder{})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			for _, line := range []string{"hello\n", "world\n"} {
+				if n, err := out.Write([]byte(line)); err != nil || n != len(line) {
+					t.Fatalf("unexpected write result: %d, %v", n, err)
+				}
+			}
+			out.Close()
+			select {
+			case data := <-received:
+				if string(data) != tc.expected {
+					t.Fatalf("unexpected data: want %q; got %q", tc.expected, data)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for data")
+			}
+		})
+	}
+}
+
+func TestNetworkOutput_Reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	address := listener.Addr().String()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	recorder := new(countingRecorder)
+	out, err := newNetworkOutput(&model.NetworkSpec{
+		Address:             address,
+		Protocol:            model.NetworkProtocolTCP,
+		Framing:             model.NetworkFramingNewline,
+		MaxReconnectBackoff: 0.1,
+	}, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer out.Close()
+	if _, err = out.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// take the server down; the writes are dropped once the connection is found broken
+	listener.Close()
+	(<-accepted).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = out.Write([]byte("hello\n")); err == ErrDropped {
+			break
+		} else if err != nil {
+			t.Fatal(err.Error())
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writes are not dropped after the server is down")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// bring the server back; the connection is re-established after the backoff
+	if listener, err = net.Listen("tcp", address); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			ioutil.ReadAll(conn)
+		}
+	}()
+	for {
+		if _, err = out.Write([]byte("hello\n")); err == nil {
+			break
+		} else if err != ErrDropped {
+			t.Fatal(err.Error())
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connection is not re-established after the server is back")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the dial that failed while the server was down and the one that succeeded
+	if recorder.reconnects != 2 {
+		t.Fatalf("unexpected number of reconnects: want 2; got %d", recorder.reconnects)
+	}
+}
+
+func TestNetworkOutput_LateServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	recorder := new(countingRecorder)
+	out, err := newNetworkOutput(&model.NetworkSpec{
+		Address:             address,
+		Protocol:            model.NetworkProtocolTCP,
+		Framing:             model.NetworkFramingNewline,
+		MaxReconnectBackoff: 0.1,
+	}, recorder)
+	if err != nil {
+		t.Fatalf("the output should be created before the server is up: %s", err.Error())
+	}
+	defer out.Close()
+	if _, err = out.Write([]byte("hello\n")); err != ErrDropped {
+		t.Fatalf("unexpected error before the server is up: want %v; got %v", ErrDropped, err)
+	}
+
+	// start the server; the connection is made once the backoff has expired
+	if listener, err = net.Listen("tcp", address); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			received <- line
+		}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = out.Write([]byte("world\n")); err == nil {
+			break
+		} else if err != ErrDropped {
+			t.Fatal(err.Error())
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connection is not made after the server is up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case line := <-received:
+		if line != "world\n" {
+			t.Fatalf("unexpected data: %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for data")
+	}
+	if recorder.reconnects < 2 {
+		t.Fatalf("unexpected number of reconnects: want at least 2; got %d", recorder.reconnects)
+	}
+}
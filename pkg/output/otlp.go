@@ -0,0 +1,378 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/httputil"
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"golang.org/x/net/http2"
+)
+
+const (
+	defaultOTLPBatchSize     = 1000
+	defaultOTLPBatchInterval = time.Second
+	defaultOTLPSeverity      = "INFO"
+
+	otlpHTTPPath  = "/v1/logs"
+	otlpGRPCPath  = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	otlpScopeName = "logtap"
+
+	protobufContentType = "application/x-protobuf"
+	grpcContentType     = "application/grpc"
+
+	// grpcFrameHeaderSize is the size of the header of a gRPC message, a compressed flag and a 4-byte length.
+	grpcFrameHeaderSize = 5
+)
+
+// The fields of the OTLP messages, as numbered in the opentelemetry-proto repository.
+const (
+	otlpRequestResourceLogs     = 1  // ExportLogsServiceRequest.resource_logs
+	otlpResponsePartialSuccess  = 1  // ExportLogsServiceResponse.partial_success
+	otlpPartialSuccessRejected  = 1  // ExportLogsPartialSuccess.rejected_log_records
+	otlpResourceLogsResource    = 1  // ResourceLogs.resource
+	otlpResourceLogsScopeLogs   = 2  // ResourceLogs.scope_logs
+	otlpResourceAttributes      = 1  // Resource.attributes
+	otlpScopeLogsScope          = 1  // ScopeLogs.scope
+	otlpScopeLogsLogRecords     = 2  // ScopeLogs.log_records
+	otlpScopeNameField          = 1  // InstrumentationScope.name
+	otlpLogRecordTime           = 1  // LogRecord.time_unix_nano
+	otlpLogRecordSeverityNumber = 2  // LogRecord.severity_number
+	otlpLogRecordSeverityText   = 3  // LogRecord.severity_text
+	otlpLogRecordBody           = 5  // LogRecord.body
+	otlpLogRecordAttributes     = 6  // LogRecord.attributes
+	otlpLogRecordObservedTime   = 11 // LogRecord.observed_time_unix_nano
+	otlpKeyValueKey             = 1  // KeyValue.key
+	otlpKeyValueValue           = 2  // KeyValue.value
+	otlpAnyValueString          = 1  // AnyValue.string_value
+)
+
+// otlpRetryableGRPCCodes are the gRPC status codes of the exports that are retried, as listed by the OTLP
+// specification; RESOURCE_EXHAUSTED is left out since it is only retryable along with a RetryInfo.
+var otlpRetryableGRPCCodes = map[string]bool{
+	"1":  true, // CANCELLED
+	"4":  true, // DEADLINE_EXCEEDED
+	"10": true, // ABORTED
+	"11": true, // OUT_OF_RANGE
+	"14": true, // UNAVAILABLE
+	"15": true, // DATA_LOSS
+}
+
+// otlpOutput turns the log messages written to it into OTLP log records and exports them in batches over either
+// OTLP/HTTP or OTLP/gRPC. The parts of the request that are the same for every batch are encoded beforehand.
+type otlpOutput struct {
+	spec     *model.OTLPSpec
+	url      string
+	grpc     bool
+	headers  []httputil.HeaderField
+	client   *http.Client
+	recorder Recorder
+	batcher  *batcher
+	severity string
+	resource []byte
+	scope    []byte
+	shared   []byte
+	record   protobufEncoder
+	request  protobufEncoder
+	body     bytes.Buffer
+	zipper   *gzip.Writer
+}
+
+func newOTLPOutput(name string, spec *model.OTLPSpec, recorder Recorder) (*otlpOutput, error) {
+	endpoint, err := url.Parse(spec.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint: %s", err.Error())
+	}
+	ret := &otlpOutput{
+		spec:     spec,
+		grpc:     spec.Protocol == model.OTLPProtocolGRPC,
+		client:   &http.Client{Timeout: defaultHTTPTimeout},
+		recorder: recorder,
+	}
+	if ret.grpc {
+		endpoint.Path, endpoint.RawQuery = otlpGRPCPath, ""
+		// gRPC needs HTTP/2, which is negotiated over TLS for https and spoken right away for http
+		transport := &http2.Transport{}
+		if endpoint.Scheme == "http" {
+			transport.AllowHTTP = true
+			transport.DialTLS = func(network, address string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, address)
+			}
+		}
+		ret.client.Transport = transport
+		ret.headers = append(ret.headers,
+			httputil.NewHeaderField("Content-Type", grpcContentType),
+			httputil.NewHeaderField("TE", "trailers"),
+		)
+		if spec.Compress {
+			ret.headers = append(ret.headers, httputil.NewHeaderField("Grpc-Encoding", "gzip"))
+		}
+	} else {
+		if len(endpoint.Path) == 0 || endpoint.Path == "/" {
+			endpoint.Path = otlpHTTPPath
+		}
+		ret.headers = append(ret.headers, httputil.NewHeaderField("Content-Type", protobufContentType))
+		if spec.Compress {
+			ret.headers = append(ret.headers, httputil.NewHeaderField("Content-Encoding", "gzip"))
+		}
+	}
+	ret.url = endpoint.String()
+	if spec.Compress {
+		ret.zipper = gzip.NewWriter(ioutil.Discard)
+	}
+	for key, value := range spec.Headers {
+		ret.headers = append(ret.headers, httputil.NewHeaderField(key, value))
+	}
+	if spec.Timeout > 0 {
+		ret.client.Timeout = time.Duration(float64(time.Second) * spec.Timeout)
+	}
+	ret.encodeConstants(name)
+	batchSize := spec.BatchSize
+	interval := time.Duration(float64(time.Second) * spec.BatchInterval)
+	if batchSize == 0 {
+		batchSize = defaultOTLPBatchSize
+	}
+	if interval == 0 {
+		interval = defaultOTLPBatchInterval
+	}
+	ret.batcher = newBatcher(batchSize, 0, interval, ret.sendBatch)
+	return ret, nil
+}
+
+// encodeConstants encodes the resource, the scope and the fields shared by every log record, which are the
+// attributes.
+func (o *otlpOutput) encodeConstants(name string) {
+	o.severity = o.spec.Severity
+	if len(o.severity) == 0 {
+		o.severity = defaultOTLPSeverity
+	}
+	var e protobufEncoder
+	resourceAttributes := map[string]string{model.OTLPServiceNameAttribute: name}
+	for key, value := range o.spec.ResourceAttributes {
+		resourceAttributes[key] = value
+	}
+	writeOTLPAttributes(&e, otlpResourceAttributes, resourceAttributes)
+	attributes := e.buf
+	e = protobufEncoder{}
+	e.writeBytes(otlpResourceLogsResource, attributes)
+	o.resource = e.buf
+
+	e = protobufEncoder{}
+	e.writeString(otlpScopeNameField, otlpScopeName)
+	scope := e.buf
+	e = protobufEncoder{}
+	e.writeBytes(otlpScopeLogsScope, scope)
+	o.scope = e.buf
+
+	e = protobufEncoder{}
+	writeOTLPAttributes(&e, otlpLogRecordAttributes, o.spec.Attributes)
+	o.shared = e.buf
+}
+
+// Write exports p as a log record created at the current time.
+func (o *otlpOutput) Write(p []byte) (int, error) {
+	return o.WriteTimed(p, time.Now(), "")
+}
+
+// WriteTimed adds p, without the trailing newline, to the pending batch as the body of a log record created at
+// the given time with the given level as its severity, or the severity of the spec if the level is empty, sending
+// the batch if it is full. It never fails; the log records of a batch that cannot be sent are recorded as dropped.
+func (o *otlpOutput) WriteTimed(p []byte, t time.Time, level string) (int, error) {
+	if len(level) == 0 {
+		level = o.severity
+	}
+	line := bytes.TrimSuffix(p, []byte("\n"))
+	e := &o.record
+	e.reset()
+	e.writeFixed64(otlpLogRecordTime, uint64(t.UnixNano()))
+	e.writeFixed64(otlpLogRecordObservedTime, uint64(time.Now().UnixNano()))
+	e.writeVarint(otlpLogRecordSeverityNumber, otlpSeverityNumber(level))
+	e.writeString(otlpLogRecordSeverityText, level)
+	e.writeMessageHeader(otlpLogRecordBody, protobufFieldSize(otlpAnyValueString, len(line)))
+	e.writeBytes(otlpAnyValueString, line)
+	e.buf = append(e.buf, o.shared...)
+	o.batcher.addItem(e.buf)
+	return len(p), nil
+}
+
+// Close exports the pending batch.
+func (o *otlpOutput) Close() error {
+	o.batcher.close()
+	return nil
+}
+
+// sendBatch exports the given encoded log records in a single ResourceLogs with a single ScopeLogs.
+func (o *otlpOutput) sendBatch(records [][]byte) {
+	scopeLogsSize := len(o.scope)
+	for _, record := range records {
+		scopeLogsSize += protobufFieldSize(otlpScopeLogsLogRecords, len(record))
+	}
+	resourceLogsSize := len(o.resource) + protobufFieldSize(otlpResourceLogsScopeLogs, scopeLogsSize)
+	e := &o.request
+	e.reset()
+	e.writeMessageHeader(otlpRequestResourceLogs, resourceLogsSize)
+	e.buf = append(e.buf, o.resource...)
+	e.writeMessageHeader(otlpResourceLogsScopeLogs, scopeLogsSize)
+	e.buf = append(e.buf, o.scope...)
+	for _, record := range records {
+		e.writeBytes(otlpScopeLogsLogRecords, record)
+	}
+	if err := o.encode(); err != nil ||
+		!retryRequest(o.do, o.spec.MaxRetries, o.batcher.stopping()) {
+		o.recorder.RecordDrop(len(records))
+	}
+}
+
+// encode writes the body of the request for the encoded request message to the body buffer, wrapping it in a
+// gRPC message if needed.
+func (o *otlpOutput) encode() error {
+	o.body.Reset()
+	if o.grpc {
+		o.body.Write(make([]byte, grpcFrameHeaderSize))
+	}
+	if o.zipper != nil {
+		o.zipper.Reset(&o.body)
+		o.zipper.Write(o.request.buf)
+		if err := o.zipper.Close(); err != nil {
+			return err
+		}
+	} else {
+		o.body.Write(o.request.buf)
+	}
+	if o.grpc {
+		header := o.body.Bytes()[:grpcFrameHeaderSize]
+		if o.zipper != nil {
+			header[0] = 1
+		}
+		binary.BigEndian.PutUint32(header[1:], uint32(o.body.Len()-grpcFrameHeaderSize))
+	}
+	return nil
+}
+
+// do makes a single request and reports whether it has succeeded and, if not, whether it should be retried and
+// the delay asked for by the server, if any. The log records rejected by a successful request are recorded as
+// dropped. For OTLP/gRPC, the status code recorded is that of the HTTP/2 response.
+func (o *otlpOutput) do() (ok, retry bool, retryAfter time.Duration) {
+	req, err := http.NewRequest(http.MethodPost, o.url, bytes.NewReader(o.body.Bytes()))
+	if err != nil {
+		return false, false, 0
+	}
+	for _, header := range o.headers {
+		req.Header.Set(header.Key(), header.Value())
+	}
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		o.recorder.RecordRequest(0, time.Since(start))
+		return false, true, 0
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	o.recorder.RecordRequest(resp.StatusCode, time.Since(start))
+	switch {
+	case err != nil:
+		return false, true, 0
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if o.grpc {
+			return o.handleGRPCResponse(resp, data)
+		}
+		o.recordRejected(data)
+		return true, false, 0
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusBadGateway ||
+		resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		return false, true, parseRetryAfter(resp.Header)
+	default:
+		return false, false, 0
+	}
+}
+
+// handleGRPCResponse checks the status of a gRPC response, which is in the trailers or, if the response has no
+// body, in the header.
+func (o *otlpOutput) handleGRPCResponse(resp *http.Response, data []byte) (ok, retry bool, retryAfter time.Duration) {
+	status := resp.Trailer.Get("Grpc-Status")
+	if len(status) == 0 {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	switch {
+	case status == "0":
+	case len(status) == 0:
+		return false, true, 0
+	default:
+		return false, otlpRetryableGRPCCodes[status], 0
+	}
+	if len(data) < grpcFrameHeaderSize {
+		return true, false, 0
+	}
+	message := data[grpcFrameHeaderSize:]
+	if size := binary.BigEndian.Uint32(data[1:]); uint64(size) < uint64(len(message)) {
+		message = message[:size]
+	}
+	if data[0] == 1 {
+		reader, err := gzip.NewReader(bytes.NewReader(message))
+		if err != nil {
+			return true, false, 0
+		}
+		if message, err = ioutil.ReadAll(reader); err != nil {
+			return true, false, 0
+		}
+	}
+	o.recordRejected(message)
+	return true, false, 0
+}
+
+// recordRejected records the log records rejected by the receiver, as told by the partial success of an
+// ExportLogsServiceResponse, as dropped.
+func (o *otlpOutput) recordRejected(response []byte) {
+	var rejected uint64
+	decodeProtobuf(response, func(field, wireType int, _ uint64, b []byte) {
+		if field != otlpResponsePartialSuccess || wireType != protobufBytes {
+			return
+		}
+		decodeProtobuf(b, func(field, wireType int, v uint64, _ []byte) {
+			if field == otlpPartialSuccessRejected && wireType == protobufVarint {
+				rejected = v
+			}
+		})
+	})
+	if rejected > 0 {
+		o.recorder.RecordDrop(int(rejected))
+	}
+}
+
+// otlpSeverityNumber returns the OTLP severity number of the given level, the lowest one of the range of the level.
+func otlpSeverityNumber(level string) uint64 {
+	for i := range model.LogLevels {
+		if model.LogLevels[i] == level {
+			return uint64(4*i + 1)
+		}
+	}
+	return 0
+}
+
+// writeOTLPAttributes writes the given attributes as repeated KeyValue fields with string values, in the order of
+// their keys.
+func writeOTLPAttributes(e *protobufEncoder, field int, attributes map[string]string) {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := attributes[key]
+		valueSize := protobufFieldSize(otlpAnyValueString, len(value))
+		e.writeMessageHeader(field, protobufFieldSize(otlpKeyValueKey, len(key))+
+			protobufFieldSize(otlpKeyValueValue, valueSize))
+		e.writeString(otlpKeyValueKey, key)
+		e.writeMessageHeader(otlpKeyValueValue, valueSize)
+		e.writeString(otlpAnyValueString, value)
+	}
+}
@@ -0,0 +1,242 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// otlpRecord is the part of a decoded OTLP log record checked by the tests.
+type otlpRecord struct {
+	time         uint64
+	severity     uint64
+	severityText string
+	body         string
+	attributes   map[string]string
+}
+
+// otlpReceiver is an in-process OTLP/HTTP and OTLP/gRPC receiver that decodes the log records it receives and
+// rejects the given number of them in every response.
+type otlpReceiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	rejected uint64
+	resource map[string]string
+	records  []otlpRecord
+	err      error
+}
+
+func newOTLPReceiver(rejected uint64) *otlpReceiver {
+	ret := &otlpReceiver{rejected: rejected}
+	// the receiver speaks both HTTP/1.1 and HTTP/2 without TLS
+	ret.Server = httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		grpc := r.Header.Get("Content-Type") == grpcContentType
+		compressed := r.Header.Get("Content-Encoding") == "gzip"
+		if (grpc && r.URL.Path != otlpGRPCPath) || (!grpc && r.URL.Path != otlpHTTPPath) {
+			ret.fail(fmt.Errorf("unexpected path: %s", r.URL.Path))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if grpc {
+			if len(data) < grpcFrameHeaderSize ||
+				int(binary.BigEndian.Uint32(data[1:])) != len(data)-grpcFrameHeaderSize {
+				ret.fail(errInvalidProtobuf)
+				return
+			}
+			compressed, data = data[0] == 1, data[grpcFrameHeaderSize:]
+		}
+		if compressed {
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				ret.fail(err)
+				return
+			}
+			data, _ = ioutil.ReadAll(reader)
+		}
+		if err := ret.decode(data); err != nil {
+			ret.fail(err)
+			return
+		}
+		var response, partialSuccess protobufEncoder
+		if ret.rejected > 0 {
+			partialSuccess.writeVarint(otlpPartialSuccessRejected, ret.rejected)
+			response.writeBytes(otlpResponsePartialSuccess, partialSuccess.buf)
+		}
+		if !grpc {
+			w.Header().Set("Content-Type", protobufContentType)
+			w.Write(response.buf)
+			return
+		}
+		w.Header().Set("Content-Type", grpcContentType)
+		w.Header().Set("Trailer", "Grpc-Status")
+		frame := make([]byte, grpcFrameHeaderSize)
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(response.buf)))
+		w.Write(append(frame, response.buf...))
+		w.Header().Set("Grpc-Status", "0")
+	}), new(http2.Server)))
+	return ret
+}
+
+func (r *otlpReceiver) fail(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+}
+
+// decode decodes an ExportLogsServiceRequest.
+func (r *otlpReceiver) decode(data []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return decodeProtobuf(data, func(field, _ int, _ uint64, resourceLogs []byte) {
+		decodeProtobuf(resourceLogs, func(field, _ int, _ uint64, b []byte) {
+			switch field {
+			case otlpResourceLogsResource:
+				r.resource = decodeOTLPAttributes(b, otlpResourceAttributes)
+			case otlpResourceLogsScopeLogs:
+				decodeProtobuf(b, func(field, _ int, _ uint64, b []byte) {
+					if field == otlpScopeLogsLogRecords {
+						r.records = append(r.records, decodeOTLPRecord(b))
+					}
+				})
+			}
+		})
+	})
+}
+
+func (r *otlpReceiver) getRecords() []otlpRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]otlpRecord(nil), r.records...)
+}
+
+func decodeOTLPRecord(data []byte) otlpRecord {
+	ret := otlpRecord{attributes: decodeOTLPAttributes(data, otlpLogRecordAttributes)}
+	decodeProtobuf(data, func(field, _ int, v uint64, b []byte) {
+		switch field {
+		case otlpLogRecordTime:
+			ret.time = v
+		case otlpLogRecordSeverityNumber:
+			ret.severity = v
+		case otlpLogRecordSeverityText:
+			ret.severityText = string(b)
+		case otlpLogRecordBody:
+			ret.body = decodeOTLPString(b)
+		}
+	})
+	return ret
+}
+
+// decodeOTLPAttributes decodes the KeyValue fields of the given number with string values.
+func decodeOTLPAttributes(data []byte, attributesField int) map[string]string {
+	ret := make(map[string]string)
+	decodeProtobuf(data, func(field, _ int, _ uint64, keyValue []byte) {
+		if field != attributesField {
+			return
+		}
+		var key, value string
+		decodeProtobuf(keyValue, func(field, _ int, _ uint64, b []byte) {
+			switch field {
+			case otlpKeyValueKey:
+				key = string(b)
+			case otlpKeyValueValue:
+				value = decodeOTLPString(b)
+			}
+		})
+		ret[key] = value
+	})
+	return ret
+}
+
+// decodeOTLPString decodes an AnyValue with a string value.
+func decodeOTLPString(data []byte) string {
+	var ret string
+	decodeProtobuf(data, func(field, _ int, _ uint64, b []byte) {
+		if field == otlpAnyValueString {
+			ret = string(b)
+		}
+	})
+	return ret
+}
+
+func TestOTLPOutput(t *testing.T) {
+	testCases := []struct {
+		name     string
+		protocol string
+		compress bool
+	}{
+		{"HTTP", model.OTLPProtocolHTTP, false},
+		{"HTTPCompress", model.OTLPProtocolHTTP, true},
+		{"GRPC", model.OTLPProtocolGRPC, false},
+		{"GRPCCompress", model.OTLPProtocolGRPC, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			receiver := newOTLPReceiver(1)
+			defer receiver.Close()
+			recorder := new(countingRecorder)
+			out, err := newOTLPOutput("test", &model.OTLPSpec{
+				Endpoint:           receiver.URL,
+				Protocol:           tc.protocol,
+				Compress:           tc.compress,
+				Severity:           "WARN",
+				ResourceAttributes: map[string]string{"host.name": "localhost"},
+				Attributes:         map[string]string{"env": "test"},
+				BatchSize:          2,
+				BatchInterval:      60,
+			}, recorder)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			start := time.Unix(1500000000, 123)
+			// the first log message has no level and takes the severity of the spec
+			levels := []string{"", "ERROR", "DEBUG"}
+			for i, line := range []string{"first\n", "second\n", "third\n"} {
+				if _, err = out.WriteTimed([]byte(line), start.Add(time.Duration(i)*time.Second), levels[i]); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+			out.Close()
+			if receiver.err != nil {
+				t.Fatalf("invalid request: %s", receiver.err.Error())
+			}
+			resource := map[string]string{model.OTLPServiceNameAttribute: "test", "host.name": "localhost"}
+			if !reflect.DeepEqual(receiver.resource, resource) {
+				t.Fatalf("unexpected resource attributes: want %v; got %v", resource, receiver.resource)
+			}
+			records := receiver.getRecords()
+			if len(records) != 3 {
+				t.Fatalf("unexpected number of records: want 3; got %d", len(records))
+			}
+			severities := []uint64{13, 17, 5}
+			severityTexts := []string{"WARN", "ERROR", "DEBUG"}
+			for i, body := range []string{"first", "second", "third"} {
+				expected := otlpRecord{
+					time:         uint64(start.Add(time.Duration(i) * time.Second).UnixNano()),
+					severity:     severities[i],
+					severityText: severityTexts[i],
+					body:         body,
+					attributes:   map[string]string{"env": "test"},
+				}
+				if !reflect.DeepEqual(records[i], expected) {
+					t.Fatalf("unexpected record %d: want %+v; got %+v", i, expected, records[i])
+				}
+			}
+			if !reflect.DeepEqual(recorder.codes, []int{http.StatusOK, http.StatusOK}) || recorder.drops != 2 {
+				t.Fatalf("unexpected recorded requests: %v, %d dropped", recorder.codes, recorder.drops)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+// Recorder receives the events that happen inside an output, usually to reflect them in a LogTaskStatus. The
+// methods of a Recorder may be called from goroutines other than the one writing to the output.
+type Recorder interface {
+	// RecordRotation is called every time a log file is rotated.
+	RecordRotation()
+
+	// RecordReconnect is called every time the connection to a server is redialed after it was lost or could not be
+	// made, whether the dial succeeds or not.
+	RecordReconnect()
+
+	// RecordRequest is called after every HTTP request with the status code of the response, or 0 if there is no
+	// response, and the time taken by the request.
+	RecordRequest(code int, latency time.Duration)
+
+	// RecordDrop is called with the number of log messages that are dropped after they have been written to an
+	// output that sends them later, such as in batches.
+	RecordDrop(count int)
+
+	// RecordFileWrite is called after every log message written to one of the files of an output with a FilesSpec
+	// with the path to the file and the size of the log message.
+	RecordFileWrite(path string, size int)
+
+	// RecordFileRemoval is called every time one of the files of an output with a FilesSpec is removed.
+	RecordFileRemoval(path string)
+
+	// RecordBuffer is called after every log message written to the buffer of an output with a BufferSpec with the
+	// size of the log message as it is buffered.
+	RecordBuffer(size int)
+
+	// RecordFlush is called every time the buffer of an output with a BufferSpec is flushed with the size of the log
+	// messages taken out of the buffer, whether they could be written or not.
+	RecordFlush(size int)
+
+	// RecordSync is called every time the log file of an output with a BufferSpec is synced to the disk.
+	RecordSync()
+}
+
+// New creates the output defined by the given LogTaskSpec for the LogTask of the given name. The log messages are
+// buffered according to the BufferSpec, if any, after they are encoded.
+func New(name string, spec *model.LogTaskSpec, recorder Recorder) (io.WriteCloser, error) {
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+	out, err := newOutput(name, spec, recorder)
+	if err == nil && spec.Buffer != nil {
+		out = newBufferedOutput(out, spec.Buffer, recorder)
+	}
+	if err != nil || len(spec.Encoding) == 0 || spec.Encoding == model.EncodingRaw {
+		return out, err
+	}
+	return newContainerOutput(out, spec.Encoding, spec.Container), nil
+}
+
+// newOutput creates the output of the kind given by the LogTaskSpec, which writes the log messages as they are.
+func newOutput(name string, spec *model.LogTaskSpec, recorder Recorder) (io.WriteCloser, error) {
+	switch spec.OutputKind {
+	case model.OutputKindStdErr:
+		return nopCloser{os.Stderr}, nil
+	case model.OutputKindStdOut:
+		return nopCloser{os.Stdout}, nil
+	case model.OutputKindFile:
+		path := spec.Filepath
+		if spec.Container != nil && len(spec.Container.LogRoot) > 0 {
+			path = containerLogPath(name, spec.Encoding, spec.Container)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create log directory: %s", err.Error())
+			}
+		}
+		if spec.Files != nil {
+			return newMultiFileOutput(path, spec.Files, spec.Rotation, recorder)
+		}
+		return newFileOutput(path, spec.Rotation, recorder)
+	case model.OutputKindSyslog:
+		return newSyslogOutput(spec.Syslog, recorder)
+	case model.OutputKindNetwork:
+		return newNetworkOutput(spec.Network, recorder)
+	case model.OutputKindHTTP:
+		return newHTTPOutput(spec.HTTP, recorder)
+	case model.OutputKindForward:
+		return newForwardOutput(name, spec.Forward, recorder)
+	case model.OutputKindOTLP:
+		return newOTLPOutput(name, spec.OTLP, recorder)
+	default:
+		return nil, fmt.Errorf("unsupported output kind: %s", spec.OutputKind)
+	}
+}
+
+// nopCloser wraps a io.Writer that should not be closed by LogTap, such as STDOUT.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) RecordRotation() {}
+
+func (nopRecorder) RecordReconnect() {}
+
+func (nopRecorder) RecordRequest(int, time.Duration) {}
+
+func (nopRecorder) RecordDrop(int) {}
+
+func (nopRecorder) RecordFileWrite(string, int) {}
+
+func (nopRecorder) RecordFileRemoval(string) {}
+
+func (nopRecorder) RecordBuffer(int) {}
+
+func (nopRecorder) RecordFlush(int) {}
+
+func (nopRecorder) RecordSync() {}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The Protocol Buffers wire types used by OTLP.
+const (
+	protobufVarint  = 0
+	protobufFixed64 = 1
+	protobufBytes   = 2
+	protobufFixed32 = 5
+)
+
+var errInvalidProtobuf = errors.New("invalid protobuf message")
+
+// protobufEncoder appends Protocol Buffers fields to a byte slice; it only covers the wire types used by OTLP. The
+// embedded messages are written as a header followed by their fields, so their sizes must be known beforehand.
+type protobufEncoder struct {
+	buf []byte
+}
+
+func (e *protobufEncoder) reset() {
+	e.buf = e.buf[:0]
+}
+
+func (e *protobufEncoder) writeTag(field, wireType int) {
+	e.buf = appendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *protobufEncoder) writeVarint(field int, v uint64) {
+	e.writeTag(field, protobufVarint)
+	e.buf = appendUvarint(e.buf, v)
+}
+
+func (e *protobufEncoder) writeFixed64(field int, v uint64) {
+	e.writeTag(field, protobufFixed64)
+	var scratch [8]byte
+	binary.LittleEndian.PutUint64(scratch[:], v)
+	e.buf = append(e.buf, scratch[:]...)
+}
+
+func (e *protobufEncoder) writeString(field int, s string) {
+	e.writeMessageHeader(field, len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *protobufEncoder) writeBytes(field int, b []byte) {
+	e.writeMessageHeader(field, len(b))
+	e.buf = append(e.buf, b...)
+}
+
+// writeMessageHeader writes the tag and the size of a length-delimited field of the given size.
+func (e *protobufEncoder) writeMessageHeader(field, size int) {
+	e.writeTag(field, protobufBytes)
+	e.buf = appendUvarint(e.buf, uint64(size))
+}
+
+// appendUvarint appends v encoded as a varint to buf.
+func appendUvarint(buf []byte, v uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(buf, scratch[:binary.PutUvarint(scratch[:], v)]...)
+}
+
+// protobufFieldSize returns the size of a length-delimited field whose content has the given size.
+func protobufFieldSize(field, size int) int {
+	var scratch [2 * binary.MaxVarintLen64]byte
+	header := appendUvarint(scratch[:0], uint64(field)<<3|protobufBytes)
+	header = appendUvarint(header, uint64(size))
+	return len(header) + size
+}
+
+// decodeProtobuf calls fn with every field of a Protocol Buffers message, passing the value of the varint and
+// fixed fields as v and the content of the length-delimited fields as b.
+func decodeProtobuf(data []byte, fn func(field, wireType int, v uint64, b []byte)) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errInvalidProtobuf
+		}
+		data = data[n:]
+		field, wireType := int(tag>>3), int(tag&7)
+		var v uint64
+		var b []byte
+		switch wireType {
+		case protobufVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errInvalidProtobuf
+			}
+			data = data[n:]
+		case protobufFixed64:
+			if len(data) < 8 {
+				return errInvalidProtobuf
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case protobufFixed32:
+			if len(data) < 4 {
+				return errInvalidProtobuf
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case protobufBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errInvalidProtobuf
+			}
+			b, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return errInvalidProtobuf
+		}
+		fn(field, wireType, v, b)
+	}
+	return nil
+}
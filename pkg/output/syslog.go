@@ -0,0 +1,141 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+const (
+	defaultSyslogFacility = "user"
+	defaultSyslogSeverity = "info"
+	defaultSyslogAppName  = "logtap"
+
+	rfc3164TimestampFormat = time.Stamp
+	rfc5424TimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// syslogLevelSeverities maps the levels of the log messages to the names of the syslog severities.
+var syslogLevelSeverities = map[string]string{
+	"TRACE": "debug",
+	"DEBUG": "debug",
+	"INFO":  "info",
+	"WARN":  "warning",
+	"ERROR": "err",
+	"FATAL": "crit",
+}
+
+// syslogOutput sends every log message written to it to a syslog server as a single syslog message whose severity
+// is mapped from the level of the log message; the log messages are dropped while the connection is down,
+// including before the server first accepts it.
+type syslogOutput struct {
+	spec       *model.SyslogSpec
+	priority   string
+	priorities map[string]string
+	hostname   string
+	appName    string
+	procID     string
+	conn       *reconnectingConn
+	buffer     bytes.Buffer
+}
+
+func newSyslogOutput(spec *model.SyslogSpec, recorder Recorder) (*syslogOutput, error) {
+	ret := &syslogOutput{
+		spec:       spec,
+		priorities: make(map[string]string, len(syslogLevelSeverities)),
+		hostname:   spec.Hostname,
+		appName:    spec.AppName,
+		procID:     strconv.Itoa(os.Getpid()),
+	}
+	var network string
+	switch spec.Transport {
+	case model.SyslogTransportUDP:
+		network = "udp"
+	case model.SyslogTransportTCP, model.SyslogTransportTCPOctetCounting:
+		network = "tcp"
+	case model.SyslogTransportUnixgram:
+		network = "unixgram"
+	default:
+		return nil, fmt.Errorf("unsupported syslog transport: %s", spec.Transport)
+	}
+	facility := getSyslogCode(model.SyslogFacilities, spec.Facility, defaultSyslogFacility)
+	severity := getSyslogCode(model.SyslogSeverities, spec.Severity, defaultSyslogSeverity)
+	ret.priority = "<" + strconv.Itoa(facility*8+severity) + ">"
+	for level, name := range syslogLevelSeverities {
+		severity = getSyslogCode(model.SyslogSeverities, name, defaultSyslogSeverity)
+		ret.priorities[level] = "<" + strconv.Itoa(facility*8+severity) + ">"
+	}
+	if len(ret.hostname) == 0 {
+		if hostname, err := os.Hostname(); err == nil && len(hostname) > 0 {
+			ret.hostname = hostname
+		} else {
+			ret.hostname = "-"
+		}
+	}
+	if len(ret.appName) == 0 {
+		ret.appName = defaultSyslogAppName
+	}
+	ret.conn = newReconnectingConn(network, spec.Address, 0, 0, recorder)
+	return ret, nil
+}
+
+// Write sends p as a log message without a level sent at the current time.
+func (s *syslogOutput) Write(p []byte) (int, error) {
+	return s.WriteTimed(p, time.Now(), "")
+}
+
+// WriteTimed sends p, without the trailing newline, as the body of a syslog message stamped with the given time
+// whose severity is mapped from the given level, or is that of the spec if the level is empty. It returns the size
+// of p rather than that of the syslog message, even if p is dropped.
+func (s *syslogOutput) WriteTimed(p []byte, t time.Time, level string) (int, error) {
+	priority, ok := s.priorities[level]
+	if !ok {
+		priority = s.priority
+	}
+	s.encode(bytes.TrimSuffix(p, []byte("\n")), t, priority)
+	return len(p), s.conn.write(s.buffer.Bytes())
+}
+
+func (s *syslogOutput) Close() error {
+	return s.conn.close()
+}
+
+// encode writes the framed syslog message with the given body, time and priority to the buffer.
+func (s *syslogOutput) encode(body []byte, t time.Time, priority string) {
+	s.buffer.Reset()
+	var header string
+	switch s.spec.Format {
+	case model.SyslogFormatRFC3164:
+		header = priority + t.Format(rfc3164TimestampFormat) + " " + s.hostname + " " +
+			s.appName + "[" + s.procID + "]: "
+	default:
+		header = priority + "1 " + t.Format(rfc5424TimestampFormat) + " " + s.hostname + " " +
+			s.appName + " " + s.procID + " - - "
+	}
+	if s.spec.Transport == model.SyslogTransportTCPOctetCounting {
+		s.buffer.WriteString(strconv.Itoa(len(header) + len(body)))
+		s.buffer.WriteByte(' ')
+	}
+	s.buffer.WriteString(header)
+	s.buffer.Write(body)
+	if s.spec.Transport == model.SyslogTransportTCP {
+		s.buffer.WriteByte('\n')
+	}
+}
+
+// getSyslogCode returns the numerical code of the named facility or severity, or that of def if name is empty.
+func getSyslogCode(names []string, name, def string) int {
+	if len(name) == 0 {
+		name = def
+	}
+	for i := range names {
+		if names[i] == name {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/lichuan0620/logtap/pkg/model/v1alpha1"
+)
+
+func TestSyslogOutput_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer conn.Close()
+	out, err := newSyslogOutput(&model.SyslogSpec{
+		Address:   conn.LocalAddr().String(),
+		Transport: model.SyslogTransportUDP,
+		Format:    model.SyslogFormatRFC3164,
+		Facility:  "local0",
+		Severity:  "warning",
+		Hostname:  "host",
+	}, nopRecorder{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer out.Close()
+	// the log message without a level takes the severity of the spec, and the others are mapped from their levels
+	for level, priority := range map[string]string{"": "132", "ERROR": "131", "DEBUG": "135"} {
+		if _, err = out.WriteTimed([]byte("hello\n"), time.Now(), level); err != nil {
+			t.Fatal(err.Error())
+		}
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		pattern := regexp.MustCompile(
+			`^<` + priority + `>[A-Z][a-z]{2} [ 0-9]\d \d{2}:\d{2}:\d{2} host logtap\[\d+\]: hello$`)
+		if !pattern.Match(buf[:n]) {
+			t.Fatalf("unexpected syslog message at level %q: %q", level, buf[:n])
+		}
+	}
+}
+
+func TestSyslogOutput_TCPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer listener.Close()
+	messages := make(chan string)
+	go func() {
+		// the first connection is closed right away to force a reconnection
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+			if err != nil {
+				return
+			}
+			message := make([]byte, size)
+			if _, err = io.ReadFull(reader, message); err != nil {
+				return
+			}
+			messages <- string(message)
+		}
+	}()
+	recorder := new(countingRecorder)
+	out, err := newSyslogOutput(&model.SyslogSpec{
+		Address:   listener.Addr().String(),
+		Transport: model.SyslogTransportTCPOctetCounting,
+		Format:    model.SyslogFormatRFC5424,
+		Hostname:  "host",
+		AppName:   "app",
+	}, recorder)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer out.Close()
+	pattern := regexp.MustCompile(`^<14>1 \d{4}-\d{2}-\d{2}T\S+ host app \d+ - - message \d$`)
+	for i := 0; i < 3; i++ {
+		// writes to a connection closed by the peer may succeed until the reset is received
+		body := fmt.Sprintf("message %d", i)
+		for {
+			if _, err = out.Write([]byte(body + "\n")); err != nil && err != ErrDropped {
+				t.Fatal(err.Error())
+			}
+			select {
+			case message := <-messages:
+				if !pattern.MatchString(message) {
+					t.Fatalf("unexpected syslog message: %q", message)
+				}
+			case <-time.After(100 * time.Millisecond):
+				continue
+			}
+			break
+		}
+	}
+	if recorder.reconnects != 1 {
+		t.Fatalf("unexpected number of reconnects: want 1; got %d", recorder.reconnects)
+	}
+}
@@ -0,0 +1,3 @@
+// Package receive implements a Receiver that measures the end-to-end latency and the throughput of the log messages
+// carrying latency markers, along with the listeners that feed it from a sink.
+package receive
@@ -0,0 +1,118 @@
+package receive
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/httputil"
+	"github.com/lichuan0620/logtap/pkg/metrics"
+)
+
+const (
+	// ReportPath is the path under which the Report of a Receiver is served in JSON.
+	ReportPath = "/report"
+
+	// MetricsPath is the path under which the metrics of a Receiver are served in the Prometheus text format.
+	MetricsPath = "/metrics"
+)
+
+type reportHandler struct {
+	receiver Receiver
+}
+
+// NewHandler returns a http.Handler that serves the Report of the given Receiver under ReportPath and its metrics
+// under MetricsPath.
+func NewHandler(receiver Receiver) http.Handler {
+	h := &reportHandler{
+		receiver: receiver,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ReportPath, h.serveReport)
+	mux.HandleFunc(MetricsPath, h.serveMetrics)
+	return mux
+}
+
+func (h *reportHandler) serveReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
+		return
+	}
+	httputil.WriteGetResponse(w, h.receiver.Report(time.Now()), nil)
+}
+
+func (h *reportHandler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := writeMetrics(w, h.receiver.Report(time.Now())); err != nil {
+		log.Printf("failed to write metrics: %s", err.Error())
+	}
+}
+
+// writeMetrics writes the metric families of the given Report; every sample but the number of unrecognized lines
+// is labelled by the task name.
+func writeMetrics(w io.Writer, report *Report) error {
+	labels := make([][]metrics.Label, len(report.Tasks))
+	for i, task := range report.Tasks {
+		labels[i] = []metrics.Label{{Name: "task", Value: task.Name}}
+	}
+	e := metrics.NewEncoder(w)
+
+	e.Family("logtap_received_logs_total", metrics.TypeCounter, "Number of log messages received.")
+	for i, task := range report.Tasks {
+		e.Sample("logtap_received_logs_total", labels[i], float64(task.Received))
+	}
+	e.Family("logtap_received_bytes_total", metrics.TypeCounter, "Size in bytes of the log messages received.")
+	for i, task := range report.Tasks {
+		e.Sample("logtap_received_bytes_total", labels[i], float64(task.ReceivedBytes))
+	}
+	e.Family("logtap_unmarked_logs_total", metrics.TypeCounter,
+		"Number of log messages received without a latency marker.")
+	for i, task := range report.Tasks {
+		e.Sample("logtap_unmarked_logs_total", labels[i], float64(task.Unmarked))
+	}
+	e.Family("logtap_received_logs_per_second", metrics.TypeGauge,
+		"Number of log messages received per second over the last 10 seconds.")
+	for i, task := range report.Tasks {
+		e.Sample("logtap_received_logs_per_second", labels[i], task.LogsPerSecond)
+	}
+	e.Family("logtap_received_bytes_per_second", metrics.TypeGauge,
+		"Size in bytes of the log messages received per second over the last 10 seconds.")
+	for i, task := range report.Tasks {
+		e.Sample("logtap_received_bytes_per_second", labels[i], task.BytesPerSecond)
+	}
+	e.Family("logtap_end_to_end_latency_seconds", metrics.TypeHistogram,
+		"Time taken by a log message to reach the receiver since it was created.")
+	for i, task := range report.Tasks {
+		e.Histogram("logtap_end_to_end_latency_seconds", labels[i], task.Histogram)
+	}
+	e.Family("logtap_end_to_end_latency_quantile_seconds", metrics.TypeGauge,
+		"Estimated quantile of the time taken by a log message to reach the receiver; quantile 1 is the maximum.")
+	for i, task := range report.Tasks {
+		quantileLabels := withLabel(labels[i], "quantile")
+		for _, quantile := range []struct {
+			name  string
+			value float64
+		}{
+			{"0.5", task.Latency.P50},
+			{"0.9", task.Latency.P90},
+			{"0.99", task.Latency.P99},
+			{"1", task.Latency.Max},
+		} {
+			quantileLabels[1].Value = quantile.name
+			e.Sample("logtap_end_to_end_latency_quantile_seconds", quantileLabels, quantile.value)
+		}
+	}
+	e.Family("logtap_unrecognized_lines_total", metrics.TypeCounter, "Number of lines without a sequence number.")
+	e.Sample("logtap_unrecognized_lines_total", nil, float64(report.Unrecognized))
+	return e.Flush()
+}
+
+// withLabel returns a copy of the labels with an extra label of the given name, whose value is left to be set.
+func withLabel(labels []metrics.Label, name string) []metrics.Label {
+	return append(append(make([]metrics.Label, 0, len(labels)+1), labels...), metrics.Label{Name: name})
+}
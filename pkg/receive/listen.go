@@ -0,0 +1,248 @@
+package receive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/httputil"
+)
+
+const (
+	// maxDatagramSize is the largest UDP datagram accepted.
+	maxDatagramSize = 65535
+
+	// maxOctetCountSize is the largest number of digits accepted in the length of an octet-counted syslog frame.
+	maxOctetCountSize = 9
+
+	// tailInterval is the interval at which a tailed file is checked for new lines.
+	tailInterval = 100 * time.Millisecond
+)
+
+// ServeTCP accepts connections from the listener and adds the newline-delimited lines read from them to the
+// Receiver, until the listener is closed. If octetCounting is true, the frames starting with the length of the
+// message followed by a space and a syslog priority are read as octet-counted frames, as described in RFC 6587.
+func ServeTCP(listener net.Listener, receiver Receiver, octetCounting bool) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				frame, err := readFrame(reader, octetCounting)
+				if len(frame) > 0 {
+					receiver.Add(frame, time.Now())
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// ServeUDP reads datagrams from the connection and adds the lines they contain to the Receiver, until the
+// connection is closed.
+func ServeUDP(conn net.PacketConn, receiver Receiver) error {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		addLines(receiver, buf[:n], time.Now())
+	}
+}
+
+// NewIngestHandler returns a http.Handler that adds the lines in the body of the POST and PUT requests, which may
+// be gzip-compressed, to the Receiver.
+func NewIngestHandler(receiver Receiver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			httputil.WriteGetResponse(w, nil, httputil.NewMethodNotAllowedError())
+			return
+		}
+		received := time.Now()
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zipped, err := gzip.NewReader(r.Body)
+			if err != nil {
+				httputil.WriteGetResponse(w, nil, httputil.NewRequestError(err.Error()))
+				return
+			}
+			defer zipped.Close()
+			body = zipped
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			httputil.WriteGetResponse(w, nil, httputil.NewRequestError(err.Error()))
+			return
+		}
+		addLines(receiver, data, received)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// TailFile adds the lines appended to the file at the given path to the Receiver until stopCh is closed, starting
+// from the end of the file. The file is read from the start again if it is truncated, or if it is replaced by a new
+// one after being rotated. A file that does not exist yet is waited for.
+func TailFile(path string, receiver Receiver, stopCh <-chan struct{}) error {
+	var (
+		file    *os.File
+		reader  *bufio.Reader
+		pending []byte
+		offset  int64
+	)
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+	open := func(fromEnd bool) error {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			file = nil
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		offset, pending = 0, nil
+		if fromEnd {
+			if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+				return err
+			}
+		}
+		reader = bufio.NewReader(file)
+		return nil
+	}
+	if err := open(true); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(tailInterval)
+	defer ticker.Stop()
+	for {
+		if file != nil {
+			for {
+				line, err := reader.ReadBytes('\n')
+				offset += int64(len(line))
+				if err != nil {
+					pending = append(pending, line...)
+					break
+				}
+				line = append(pending, line...)
+				pending = nil
+				receiver.Add(bytes.TrimSuffix(line, []byte("\n")), time.Now())
+			}
+		}
+		select {
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+		}
+		current, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if file == nil {
+			if err = open(false); err != nil {
+				return err
+			}
+			continue
+		}
+		opened, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		switch {
+		case !os.SameFile(opened, current):
+			// drain the rotated file before reading the new one
+			for {
+				line, err := reader.ReadBytes('\n')
+				if len(line) > 0 {
+					pending = append(pending, line...)
+				}
+				if err != nil {
+					break
+				}
+				receiver.Add(bytes.TrimSuffix(pending, []byte("\n")), time.Now())
+				pending = nil
+			}
+			file.Close()
+			if err = open(false); err != nil {
+				return err
+			}
+		case current.Size() < offset:
+			if _, err = file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset, pending = 0, nil
+			reader.Reset(file)
+		}
+	}
+}
+
+// readFrame reads a frame from the reader without the trailing new line.
+func readFrame(reader *bufio.Reader, octetCounting bool) ([]byte, error) {
+	if octetCounting {
+		if size, header := peekOctetCount(reader); header > 0 {
+			reader.Discard(header)
+			frame := make([]byte, size)
+			_, err := io.ReadFull(reader, frame)
+			return bytes.TrimSuffix(frame, []byte("\n")), err
+		}
+	}
+	line, err := reader.ReadBytes('\n')
+	return bytes.TrimSuffix(line, []byte("\n")), err
+}
+
+// peekOctetCount returns the length of the message and the size of the header of the octet-counted syslog frame
+// at the head of the reader, or a zero header size if it does not start with one.
+func peekOctetCount(reader *bufio.Reader) (int, int) {
+	for i := 0; i <= maxOctetCountSize; i++ {
+		peeked, err := reader.Peek(i + 2)
+		if err != nil {
+			return 0, 0
+		}
+		if c := peeked[i]; c == ' ' && i > 0 {
+			if peeked[i+1] != '<' {
+				return 0, 0
+			}
+			size, _ := strconv.Atoi(string(peeked[:i]))
+			return size, i + 1
+		} else if c < '0' || c > '9' {
+			return 0, 0
+		}
+	}
+	return 0, 0
+}
+
+// addLines adds the non-empty lines in data to the Receiver.
+func addLines(receiver Receiver, data []byte, received time.Time) {
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data)
+		}
+		if line := bytes.TrimSuffix(data[:end], []byte("\r")); len(line) > 0 {
+			receiver.Add(line, received)
+		}
+		if end == len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+}
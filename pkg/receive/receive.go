@@ -0,0 +1,233 @@
+package receive
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lichuan0620/logtap/pkg/logger"
+	"github.com/lichuan0620/logtap/pkg/metrics"
+)
+
+const (
+	// minLatency is the upper bound in seconds of the smallest fine-grained latency bucket.
+	minLatency = 1e-6
+
+	// latencyGrowth is the ratio between the upper bounds of two adjacent fine-grained latency buckets, which
+	// bounds the relative error of the quantiles to 2%.
+	latencyGrowth = 1.02
+
+	// latencyBuckets is the number of fine-grained latency buckets, enough to reach more than an hour.
+	latencyBuckets = 1200
+
+	// rateWindow is the number of seconds over which the throughput is measured.
+	rateWindow = 10
+)
+
+// LatencyBuckets are the upper bounds in seconds of the histogram buckets used for end-to-end latencies, ranging
+// from 1 millisecond to 1 minute.
+var LatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Receiver keeps track of the latency and the throughput of the log messages received from a sink. It is safe for
+// concurrent use.
+type Receiver interface {
+	// Add processes a log line, without the trailing new line, received at the given time.
+	Add(line []byte, received time.Time)
+
+	// Report summarizes the log lines processed so far; the throughput is measured up to the given time.
+	Report(now time.Time) *Report
+}
+
+// Report describes the log messages received so far.
+type Report struct {
+	// Unrecognized is the number of lines that do not carry a sequence number.
+	Unrecognized int64 `json:"unrecognized"`
+
+	// Tasks are the reports of the tasks, sorted by name.
+	Tasks []TaskReport `json:"tasks"`
+}
+
+// TaskReport describes the log messages received from a single task.
+type TaskReport struct {
+	// Name is the name of the task.
+	Name string `json:"name"`
+
+	// Received is the number of log messages received from the task.
+	Received int64 `json:"received"`
+
+	// ReceivedBytes is the size in bytes of the log messages received from the task, without the new lines.
+	ReceivedBytes int64 `json:"receivedBytes"`
+
+	// Unmarked is the number of log messages received without a latency marker, which are not measured.
+	Unmarked int64 `json:"unmarked"`
+
+	// LogsPerSecond is the number of log messages received per second over the last 10 seconds or so.
+	LogsPerSecond float64 `json:"logsPerSecond"`
+
+	// BytesPerSecond is the size in bytes of log messages received per second over the last 10 seconds or so.
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+
+	// Latency summarizes the end-to-end latencies of the log messages with latency markers.
+	Latency LatencyReport `json:"latency"`
+
+	// Histogram is the histogram of the end-to-end latencies in seconds, with LatencyBuckets.
+	Histogram *metrics.Histogram `json:"-"`
+}
+
+// LatencyReport summarizes the end-to-end latencies, in seconds, of the log messages received from a task; the
+// quantiles are estimated within 2%. A log message that seems to have been received before it was sent, because
+// the clocks are not in sync, counts as having no latency.
+type LatencyReport struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+type taskState struct {
+	report    TaskReport
+	latencies []uint64
+	measured  uint64
+	first     int64
+	seconds   [rateWindow]int64
+	logs      [rateWindow]int64
+	bytes     [rateWindow]int64
+}
+
+type receiverImpl struct {
+	mutex        sync.Mutex
+	tasks        map[string]*taskState
+	unrecognized int64
+}
+
+// NewReceiver creates an empty Receiver.
+func NewReceiver() Receiver {
+	return &receiverImpl{
+		tasks: make(map[string]*taskState),
+	}
+}
+
+func (r *receiverImpl) Add(line []byte, received time.Time) {
+	record, ok := logger.ParseRecord(line)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !ok || record.Sequence < 1 {
+		r.unrecognized++
+		return
+	}
+	task, exist := r.tasks[record.Name]
+	if !exist {
+		task = &taskState{
+			report: TaskReport{
+				Name:      record.Name,
+				Histogram: metrics.NewHistogram(LatencyBuckets),
+			},
+			latencies: make([]uint64, latencyBuckets),
+			first:     received.Unix(),
+		}
+		r.tasks[record.Name] = task
+	}
+	task.report.Received++
+	task.report.ReceivedBytes += int64(len(line))
+	task.addRate(received.Unix(), int64(len(line)))
+	if record.Sent.IsZero() {
+		task.report.Unmarked++
+		return
+	}
+	latency := received.Sub(record.Sent).Seconds()
+	if latency < 0 {
+		latency = 0
+	}
+	task.report.Histogram.Observe(latency)
+	task.latencies[latencyIndex(latency)]++
+	task.measured++
+	if latency > task.report.Latency.Max {
+		task.report.Latency.Max = latency
+	}
+}
+
+func (r *receiverImpl) Report(now time.Time) *Report {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ret := &Report{
+		Unrecognized: r.unrecognized,
+		Tasks:        make([]TaskReport, 0, len(r.tasks)),
+	}
+	for _, task := range r.tasks {
+		report := task.report
+		report.Histogram = task.report.Histogram.DeepCopy()
+		report.LogsPerSecond, report.BytesPerSecond = task.rates(now.Unix())
+		report.Latency.P50 = task.quantile(0.5)
+		report.Latency.P90 = task.quantile(0.9)
+		report.Latency.P99 = task.quantile(0.99)
+		ret.Tasks = append(ret.Tasks, report)
+	}
+	sort.Slice(ret.Tasks, func(i, j int) bool {
+		return ret.Tasks[i].Name < ret.Tasks[j].Name
+	})
+	return ret
+}
+
+// addRate counts a log message of the given size received in the given second.
+func (t *taskState) addRate(second, size int64) {
+	i := second % rateWindow
+	if t.seconds[i] != second {
+		t.seconds[i], t.logs[i], t.bytes[i] = second, 0, 0
+	}
+	t.logs[i]++
+	t.bytes[i] += size
+}
+
+// rates returns the number and the size of the log messages received per second over the full seconds of the
+// window before the given one.
+func (t *taskState) rates(now int64) (float64, float64) {
+	seconds := now - t.first
+	if seconds > rateWindow-1 {
+		seconds = rateWindow - 1
+	}
+	if seconds < 1 {
+		return 0, 0
+	}
+	var logs, bytes int64
+	for i := range t.seconds {
+		if t.seconds[i] < now && t.seconds[i] >= now-seconds {
+			logs += t.logs[i]
+			bytes += t.bytes[i]
+		}
+	}
+	return float64(logs) / float64(seconds), float64(bytes) / float64(seconds)
+}
+
+// quantile estimates the given quantile of the latencies by the upper bound of the bucket it falls into.
+func (t *taskState) quantile(q float64) float64 {
+	if t.measured == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(t.measured)))
+	var cumulative uint64
+	for i, count := range t.latencies {
+		cumulative += count
+		if cumulative >= target {
+			return math.Min(latencyBound(i), t.report.Latency.Max)
+		}
+	}
+	return t.report.Latency.Max
+}
+
+// latencyIndex returns the index of the fine-grained bucket of the given latency in seconds.
+func latencyIndex(latency float64) int {
+	if latency <= minLatency {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(latency/minLatency) / math.Log(latencyGrowth)))
+	if i >= latencyBuckets {
+		return latencyBuckets - 1
+	}
+	return i
+}
+
+// latencyBound returns the upper bound in seconds of the fine-grained bucket of the given index.
+func latencyBound(i int) float64 {
+	return minLatency * math.Pow(latencyGrowth, float64(i))
+}
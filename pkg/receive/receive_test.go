@@ -0,0 +1,79 @@
+package receive
+
+import (
+	"bufio"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiver_Report(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	receiver := NewReceiver()
+	// 100 logs sent over 10 seconds with latencies of 1ms to 100ms
+	for i := 1; i <= 100; i++ {
+		sent := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		line := "INFO [test] seq=" + strconv.Itoa(i) + " sent=" + strconv.FormatInt(sent.UnixNano(), 10) + " hello"
+		receiver.Add([]byte(line), sent.Add(time.Duration(i)*time.Millisecond))
+	}
+	receiver.Add([]byte("INFO [test] seq=101 unmarked"), start.Add(10*time.Second))
+	// sent after being received, as if the clocks were not in sync
+	early := "INFO [test] seq=102 sent=" + strconv.FormatInt(start.Add(time.Minute).UnixNano(), 10) + " early"
+	receiver.Add([]byte(early), start.Add(10*time.Second))
+	receiver.Add([]byte("garbage"), start)
+
+	report := receiver.Report(start.Add(11 * time.Second))
+	if report.Unrecognized != 1 || len(report.Tasks) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	task := report.Tasks[0]
+	if task.Name != "test" || task.Received != 102 || task.Unmarked != 1 || task.Histogram.Count != 101 {
+		t.Fatalf("unexpected task report: %+v", task)
+	}
+	if task.LogsPerSecond < 9 || task.LogsPerSecond > 11 {
+		t.Fatalf("unexpected throughput: %f", task.LogsPerSecond)
+	}
+	for _, quantile := range []struct {
+		got, want float64
+	}{
+		{task.Latency.P50, 0.050},
+		{task.Latency.P90, 0.090},
+		{task.Latency.P99, 0.099},
+		{task.Latency.Max, 0.100},
+	} {
+		if math.Abs(quantile.got-quantile.want) > quantile.want*latencyGrowth-quantile.want {
+			t.Fatalf("unexpected latency report: %+v", task.Latency)
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	input := "12 <14>1 a b c\n" + "2026-01-01 INFO [test] seq=1 x\n" + "5 <1>x\n" + "42 INFO [test] seq=2\n"
+	for _, octetCounting := range []bool{true, false} {
+		reader := bufio.NewReader(strings.NewReader(input))
+		var frames []string
+		for {
+			frame, err := readFrame(reader, octetCounting)
+			if len(frame) > 0 {
+				frames = append(frames, string(frame))
+			}
+			if err != nil {
+				break
+			}
+		}
+		want := []string{"<14>1 a b c", "2026-01-01 INFO [test] seq=1 x", "<1>x", "42 INFO [test] seq=2"}
+		if !octetCounting {
+			want = []string{"12 <14>1 a b c", "2026-01-01 INFO [test] seq=1 x", "5 <1>x", "42 INFO [test] seq=2"}
+		}
+		if len(frames) != len(want) {
+			t.Fatalf("unexpected frames: %q", frames)
+		}
+		for i := range want {
+			if frames[i] != want[i] {
+				t.Fatalf("unexpected frames: want %q; got %q", want, frames)
+			}
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package verify implements Verifier, which checks the log messages collected from a sink against the tasks that
+// produced them and reports the missing, duplicated, out-of-order and corrupted ones.
+package verify
@@ -0,0 +1,171 @@
+package verify
+
+import (
+	"sort"
+
+	"github.com/lichuan0620/logtap/pkg/logger"
+)
+
+// chunkBits is the number of sequence numbers tracked by each chunk of a bitmap.
+const chunkBits = 1 << 16
+
+// Verifier keeps track of the log messages collected from a sink.
+type Verifier interface {
+	// Add processes a log line, without the trailing new line.
+	Add(line []byte)
+
+	// Report summarizes the processed log lines. The expected map holds the number of logs that each task has
+	// sent, usually the final SentCount of the task; if a task is not in the map, the largest sequence number
+	// received from it is used instead.
+	Report(expected map[string]int64) *Report
+}
+
+// Report describes the result of a verification.
+type Report struct {
+	// Unrecognized is the number of lines that do not carry a sequence number.
+	Unrecognized int64 `json:"unrecognized"`
+
+	// Tasks are the reports of the tasks, sorted by name.
+	Tasks []TaskReport `json:"tasks"`
+}
+
+// TaskReport describes the result of a verification for a single task.
+type TaskReport struct {
+	// Name is the name of the task.
+	Name string `json:"name"`
+
+	// Expected is the number of logs that the task has sent.
+	Expected int64 `json:"expected"`
+
+	// Received is the number of intact logs received from the task, including the duplicated ones.
+	Received int64 `json:"received"`
+
+	// Missing is the number of sequence numbers, up to Expected, that have never been received.
+	Missing int64 `json:"missing"`
+
+	// Duplicated is the number of logs whose sequence numbers have already been received.
+	Duplicated int64 `json:"duplicated"`
+
+	// OutOfOrder is the number of logs received after a log with a larger sequence number.
+	OutOfOrder int64 `json:"outOfOrder"`
+
+	// Corrupted is the number of logs whose checksums do not match their payloads.
+	Corrupted int64 `json:"corrupted"`
+
+	// Unexpected is the number of distinct sequence numbers received that are larger than Expected.
+	Unexpected int64 `json:"unexpected"`
+}
+
+// OK returns true if no log is missing, duplicated, corrupted or unexpected.
+func (r *TaskReport) OK() bool {
+	return r.Missing == 0 && r.Duplicated == 0 && r.Corrupted == 0 && r.Unexpected == 0
+}
+
+type taskState struct {
+	report TaskReport
+	seen   map[int64][]uint64
+	maxSeq int64
+}
+
+type verifierImpl struct {
+	tasks        map[string]*taskState
+	unrecognized int64
+}
+
+// NewVerifier creates an empty Verifier.
+func NewVerifier() Verifier {
+	return &verifierImpl{
+		tasks: make(map[string]*taskState),
+	}
+}
+
+func (v *verifierImpl) Add(line []byte) {
+	record, ok := logger.ParseRecord(line)
+	if !ok || record.Sequence < 1 {
+		v.unrecognized++
+		return
+	}
+	task, exist := v.tasks[record.Name]
+	if !exist {
+		task = &taskState{
+			report: TaskReport{Name: record.Name},
+			seen:   make(map[int64][]uint64),
+		}
+		v.tasks[record.Name] = task
+	}
+	task.add(record)
+}
+
+func (v *verifierImpl) Report(expected map[string]int64) *Report {
+	ret := &Report{
+		Unrecognized: v.unrecognized,
+		Tasks:        make([]TaskReport, 0, len(v.tasks)),
+	}
+	for name, count := range expected {
+		if _, exist := v.tasks[name]; !exist {
+			ret.Tasks = append(ret.Tasks, TaskReport{
+				Name:     name,
+				Expected: count,
+				Missing:  count,
+			})
+		}
+	}
+	for name, task := range v.tasks {
+		count, exist := expected[name]
+		if !exist {
+			count = task.maxSeq
+		}
+		ret.Tasks = append(ret.Tasks, task.summarize(count))
+	}
+	sort.Slice(ret.Tasks, func(i, j int) bool {
+		return ret.Tasks[i].Name < ret.Tasks[j].Name
+	})
+	return ret
+}
+
+func (t *taskState) add(record *logger.Record) {
+	if record.Corrupted {
+		t.report.Corrupted++
+		return
+	}
+	t.report.Received++
+	chunk, exist := t.seen[record.Sequence/chunkBits]
+	if !exist {
+		chunk = make([]uint64, chunkBits/64)
+		t.seen[record.Sequence/chunkBits] = chunk
+	}
+	bit := record.Sequence % chunkBits
+	if chunk[bit/64]&(1<<uint(bit%64)) != 0 {
+		t.report.Duplicated++
+		return
+	}
+	chunk[bit/64] |= 1 << uint(bit%64)
+	if record.Sequence < t.maxSeq {
+		t.report.OutOfOrder++
+	} else {
+		t.maxSeq = record.Sequence
+	}
+}
+
+// summarize returns the TaskReport given the number of logs the task has sent.
+func (t *taskState) summarize(expected int64) TaskReport {
+	ret := t.report
+	ret.Expected = expected
+	var received int64
+	for index, chunk := range t.seen {
+		for i, word := range chunk {
+			for b := uint(0); word != 0; b++ {
+				if word&1 != 0 {
+					if seq := index*chunkBits + int64(i)*64 + int64(b); seq <= expected {
+						received++
+					} else {
+						ret.Unexpected++
+					}
+				}
+				word >>= 1
+			}
+		}
+	}
+	ret.Missing = expected - received
+	return ret
+}
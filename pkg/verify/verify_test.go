@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lichuan0620/logtap/pkg/logger"
+)
+
+func TestVerifier_Report(t *testing.T) {
+	const count = 10
+	newExplicit := func(writer *bytes.Buffer) logger.Logger {
+		return logger.NewExplicitLogger(writer, "hello world", logger.Header{Name: "explicit", Checksum: true})
+	}
+	newRandom := func(writer *bytes.Buffer) logger.Logger {
+		return logger.NewRandomLogger(writer, 128, logger.Header{Name: "random", Checksum: true})
+	}
+	newJSON := func(writer *bytes.Buffer) logger.Logger {
+		return logger.NewJSONLogger(writer, "hello", 128, nil, logger.Header{Name: "json", Checksum: true})
+	}
+	for name, newLogger := range map[string]func(*bytes.Buffer) logger.Logger{
+		"Explicit": newExplicit,
+		"Random":   newRandom,
+		"JSON":     newJSON,
+	} {
+		t.Run(name, func(t *testing.T) {
+			writer := new(bytes.Buffer)
+			log := newLogger(writer)
+			lines := make([][]byte, 0, count)
+			for i := 0; i < count; i++ {
+				writer.Reset()
+				if _, _, err := log.Log(); err != nil {
+					t.Fatal(err.Error())
+				}
+				lines = append(lines, append([]byte(nil), bytes.TrimSuffix(writer.Bytes(), []byte("\n"))...))
+			}
+			// drop #3, duplicate #5, swap #7 and #8, and corrupt #9
+			corrupted := append([]byte(nil), lines[8]...)
+			corrupted[len(corrupted)-3] ^= 1
+			collected := [][]byte{
+				lines[0], lines[1], lines[3], lines[4], lines[4], lines[5],
+				lines[7], lines[6], corrupted, lines[9], []byte("garbage"),
+			}
+			verifier := NewVerifier()
+			for _, line := range collected {
+				verifier.Add(line)
+			}
+			report := verifier.Report(map[string]int64{"absent": 5})
+			if report.Unrecognized != 1 || len(report.Tasks) != 2 {
+				t.Fatalf("unexpected report: %+v", report)
+			}
+			absent, task := report.Tasks[0], report.Tasks[1]
+			if absent.Name != "absent" || absent.Missing != 5 {
+				t.Fatalf("unexpected report of an absent task: %+v", absent)
+			}
+			want := TaskReport{
+				Name:       task.Name,
+				Expected:   count,
+				Received:   9,
+				Missing:    2,
+				Duplicated: 1,
+				OutOfOrder: 1,
+				Corrupted:  1,
+			}
+			if task != want {
+				t.Fatalf("unexpected report: want %+v; got %+v", want, task)
+			}
+			if task.OK() {
+				t.Fatal("report with problems considered OK")
+			}
+		})
+	}
+}